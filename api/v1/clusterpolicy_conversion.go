@@ -0,0 +1,448 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	v2 "github.com/xilinx/fpga-operator/api/v2"
+)
+
+// ImagePullSecretsAnnotation keeps the per-component image pull secrets of v1,
+// which are merged into the global image pull secrets in v2
+const ImagePullSecretsAnnotation = "policy.xilinx.com/v1-image-pull-secrets"
+
+var _ conversion.Convertible = &ClusterPolicy{}
+
+// imagePullSecrets is the image pull secrets of each component in v1
+type imagePullSecrets struct {
+	NFD              []string   `json:"nfd,omitempty"`
+	NodeLabeller     []string   `json:"nodeLabeller,omitempty"`
+	ContainerRuntime []string   `json:"containerRuntime,omitempty"`
+	DevicePlugin     []string   `json:"devicePlugin,omitempty"`
+	OsDists          [][]string `json:"osDists,omitempty"`
+}
+
+// merge returns the image pull secrets of all components without duplicates
+func (s *imagePullSecrets) merge() []string {
+	var merged []string
+	seen := map[string]bool{}
+	lists := append([][]string{s.NFD, s.NodeLabeller, s.ContainerRuntime, s.DevicePlugin}, s.OsDists...)
+	for _, list := range lists {
+		for _, secret := range list {
+			if !seen[secret] {
+				seen[secret] = true
+				merged = append(merged, secret)
+			}
+		}
+	}
+	return merged
+}
+
+// ConvertTo converts this ClusterPolicy to the hub version (v2)
+func (src *ClusterPolicy) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v2.ClusterPolicy)
+	if !ok {
+		return fmt.Errorf("expected a v2 ClusterPolicy but got %T", dstRaw)
+	}
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec.Global.DefaultRuntime = v2.Runtime(src.Spec.Operator.DefaultRuntime)
+	dst.Spec.Global.AssetsConfigMap = src.Spec.Operator.AssetsConfigMap
+
+	nfd := &src.Spec.NFD
+	dst.Spec.NFD = v2.NFDSpec{
+		Enabled: copyBool(nfd.Enabled),
+		Image:   toComponentImage(nfd.Repository, nfd.Image, nfd.Tag, nfd.ImagePullPolicy),
+
+		ProgressDeadlineSeconds: copyInt32(nfd.ProgressDeadlineSeconds),
+	}
+
+	nls := &src.Spec.NodeLabeller
+	dst.Spec.NodeLabeller = v2.NodeLabellerSpec{
+		Enabled: copyBool(nls.Enabled),
+		Image:   toComponentImage(nls.Repository, nls.Image, nls.Tag, nls.ImagePullPolicy),
+
+		ProgressDeadlineSeconds: copyInt32(nls.ProgressDeadlineSeconds),
+
+		PodSchedulingSpec:      v2.PodSchedulingSpec(*nls.PodSchedulingSpec.DeepCopy()),
+		ContainerOverridesSpec: v2.ContainerOverridesSpec(*nls.ContainerOverridesSpec.DeepCopy()),
+	}
+
+	crs := &src.Spec.ContainerRuntime
+	dst.Spec.ContainerRuntime = v2.ContainerRuntimeSpec{
+		Enabled:      copyBool(crs.Enabled),
+		Image:        toComponentImage(crs.Repository, crs.Image, crs.Tag, crs.ImagePullPolicy),
+		RuntimeClass: crs.RuntimeClass,
+		SetAsDefault: copyBool(crs.SetAsDefault),
+		InstallDir:   crs.InstallDir,
+		Args:         copyStrings(crs.Args),
+
+		ProgressDeadlineSeconds: copyInt32(crs.ProgressDeadlineSeconds),
+
+		PodSchedulingSpec:      v2.PodSchedulingSpec(*crs.PodSchedulingSpec.DeepCopy()),
+		ContainerOverridesSpec: v2.ContainerOverridesSpec(*crs.ContainerOverridesSpec.DeepCopy()),
+	}
+	for _, env := range crs.Env {
+		dst.Spec.ContainerRuntime.Env = append(dst.Spec.ContainerRuntime.Env, *env.DeepCopy())
+	}
+
+	dps := &src.Spec.DevicePlugin
+	dst.Spec.DevicePlugin = v2.DevicePluginSpec{
+		Enabled: copyBool(dps.Enabled),
+		Image:   toComponentImage(dps.Repository, dps.Image, dps.Tag, dps.ImagePullPolicy),
+
+		ProgressDeadlineSeconds: copyInt32(dps.ProgressDeadlineSeconds),
+
+		PodSchedulingSpec:      v2.PodSchedulingSpec(*dps.PodSchedulingSpec.DeepCopy()),
+		ContainerOverridesSpec: v2.ContainerOverridesSpec(*dps.ContainerOverridesSpec.DeepCopy()),
+	}
+	for _, env := range dps.Env {
+		dst.Spec.DevicePlugin.Env = append(dst.Spec.DevicePlugin.Env, *env.DeepCopy())
+	}
+
+	secrets := imagePullSecrets{
+		NFD:              nfd.ImagePullSecrets,
+		NodeLabeller:     nls.ImagePullSecrets,
+		ContainerRuntime: crs.ImagePullSecrets,
+		DevicePlugin:     dps.ImagePullSecrets,
+	}
+	hrs := &src.Spec.HostSetup.Reporter
+	dst.Spec.HostSetup = v2.HostSetupSpec{
+		Enabled:                 copyBool(src.Spec.HostSetup.Enabled),
+		ProgressDeadlineSeconds: copyInt32(src.Spec.HostSetup.ProgressDeadlineSeconds),
+		Mode:                    v2.HostSetupMode(src.Spec.HostSetup.Mode),
+		Reporter: v2.HostSetupReporterSpec{
+			Enabled: copyBool(hrs.Enabled),
+			Image:   toComponentImage(hrs.Repository, hrs.Image, hrs.Tag, hrs.ImagePullPolicy),
+		},
+	}
+	if src.Spec.HostSetup.OsDists != nil {
+		dst.Spec.HostSetup.OsDists = []v2.OsDistSetupSpec{}
+	}
+	for _, osDist := range src.Spec.HostSetup.OsDists {
+		dstOsDist := v2.OsDistSetupSpec{
+			OsId:           osDist.OsId,
+			OsMajorVersion: osDist.OsMajorVersion,
+			Image:          toComponentImage(osDist.Repository, osDist.Image, osDist.Tag, osDist.ImagePullPolicy),
+			XRT: v2.XRTSpec{
+				Version:         osDist.Version,
+				XrmInstallation: copyBool(osDist.XrmInstallation),
+			},
+			ShellFlash: v2.ShellFlashSpec{Enabled: copyBool(osDist.ShellFlashEnabled)},
+
+			PodSchedulingSpec:      v2.PodSchedulingSpec(*osDist.PodSchedulingSpec.DeepCopy()),
+			ContainerOverridesSpec: v2.ContainerOverridesSpec(*osDist.ContainerOverridesSpec.DeepCopy()),
+			InitXrtXrm:             (*v2.ContainerOverridesSpec)(osDist.InitXrtXrm.DeepCopy()),
+			InitCardFlash:          (*v2.ContainerOverridesSpec)(osDist.InitCardFlash.DeepCopy()),
+		}
+		if osDist.Cards != nil {
+			dstOsDist.ShellFlash.Cards = []v2.CardSelector{}
+		}
+		for _, card := range osDist.Cards {
+			dstOsDist.ShellFlash.Cards = append(dstOsDist.ShellFlash.Cards, v2.CardSelector{Platform: card})
+		}
+		dst.Spec.HostSetup.OsDists = append(dst.Spec.HostSetup.OsDists, dstOsDist)
+		secrets.OsDists = append(secrets.OsDists, osDist.ImagePullSecrets)
+	}
+
+	// keep the per-component image pull secrets if they differ from the merged ones
+	dst.Spec.Global.ImagePullSecrets = secrets.merge()
+	if !equality.Semantic.DeepEqual(secrets, globalImagePullSecrets(dst.Spec.Global.ImagePullSecrets, len(secrets.OsDists))) {
+		data, err := json.Marshal(secrets)
+		if err != nil {
+			return err
+		}
+		if dst.ObjectMeta.Annotations == nil {
+			dst.ObjectMeta.Annotations = map[string]string{}
+		}
+		dst.ObjectMeta.Annotations[ImagePullSecretsAnnotation] = string(data)
+	}
+
+	dst.Spec.FPGANodes = v2.FPGANodeSpec{NodeSelector: copyStringMap(src.Spec.FPGANodes.NodeSelector)}
+	for _, device := range src.Spec.FPGANodes.PCIDevices {
+		dst.Spec.FPGANodes.PCIDevices = append(dst.Spec.FPGANodes.PCIDevices, v2.PCIDeviceSpec(device))
+	}
+
+	convertStatusTo(&src.Status, &dst.Status)
+	return nil
+}
+
+// ConvertFrom converts from the hub version (v2) to this version
+func (dst *ClusterPolicy) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v2.ClusterPolicy)
+	if !ok {
+		return fmt.Errorf("expected a v2 ClusterPolicy but got %T", srcRaw)
+	}
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec.Operator.DefaultRuntime = Runtime(src.Spec.Global.DefaultRuntime)
+	dst.Spec.Operator.AssetsConfigMap = src.Spec.Global.AssetsConfigMap
+
+	// restore the per-component image pull secrets, unless the global ones are changed in v2
+	secrets := globalImagePullSecrets(src.Spec.Global.ImagePullSecrets, len(src.Spec.HostSetup.OsDists))
+	if data, ok := dst.ObjectMeta.Annotations[ImagePullSecretsAnnotation]; ok {
+		delete(dst.ObjectMeta.Annotations, ImagePullSecretsAnnotation)
+		if len(dst.ObjectMeta.Annotations) == 0 {
+			dst.ObjectMeta.Annotations = nil
+		}
+
+		kept := imagePullSecrets{}
+		if err := json.Unmarshal([]byte(data), &kept); err == nil &&
+			len(kept.OsDists) == len(src.Spec.HostSetup.OsDists) &&
+			equality.Semantic.DeepEqual(kept.merge(), src.Spec.Global.ImagePullSecrets) {
+			secrets = kept
+		}
+	}
+
+	nfd := &src.Spec.NFD
+	dst.Spec.NFD = NFDSpec{
+		Enabled:          copyBool(nfd.Enabled),
+		Repository:       nfd.Image.Repository,
+		Image:            nfd.Image.Name,
+		Tag:              nfd.Image.Tag,
+		ImagePullPolicy:  nfd.Image.PullPolicy,
+		ImagePullSecrets: secrets.NFD,
+
+		ProgressDeadlineSeconds: copyInt32(nfd.ProgressDeadlineSeconds),
+	}
+
+	nls := &src.Spec.NodeLabeller
+	dst.Spec.NodeLabeller = NodeLabellerSpec{
+		Enabled:          copyBool(nls.Enabled),
+		Repository:       nls.Image.Repository,
+		Image:            nls.Image.Name,
+		Tag:              nls.Image.Tag,
+		ImagePullPolicy:  nls.Image.PullPolicy,
+		ImagePullSecrets: secrets.NodeLabeller,
+
+		ProgressDeadlineSeconds: copyInt32(nls.ProgressDeadlineSeconds),
+
+		PodSchedulingSpec:      PodSchedulingSpec(*nls.PodSchedulingSpec.DeepCopy()),
+		ContainerOverridesSpec: ContainerOverridesSpec(*nls.ContainerOverridesSpec.DeepCopy()),
+	}
+
+	crs := &src.Spec.ContainerRuntime
+	dst.Spec.ContainerRuntime = ContainerRuntimeSpec{
+		Enabled:          copyBool(crs.Enabled),
+		RuntimeClass:     crs.RuntimeClass,
+		SetAsDefault:     copyBool(crs.SetAsDefault),
+		Repository:       crs.Image.Repository,
+		Image:            crs.Image.Name,
+		Tag:              crs.Image.Tag,
+		ImagePullPolicy:  crs.Image.PullPolicy,
+		ImagePullSecrets: secrets.ContainerRuntime,
+		Args:             copyStrings(crs.Args),
+		InstallDir:       crs.InstallDir,
+
+		ProgressDeadlineSeconds: copyInt32(crs.ProgressDeadlineSeconds),
+
+		PodSchedulingSpec:      PodSchedulingSpec(*crs.PodSchedulingSpec.DeepCopy()),
+		ContainerOverridesSpec: ContainerOverridesSpec(*crs.ContainerOverridesSpec.DeepCopy()),
+	}
+	for _, env := range crs.Env {
+		dst.Spec.ContainerRuntime.Env = append(dst.Spec.ContainerRuntime.Env, *env.DeepCopy())
+	}
+
+	dps := &src.Spec.DevicePlugin
+	dst.Spec.DevicePlugin = DevicePluginSpec{
+		Enabled:          copyBool(dps.Enabled),
+		Repository:       dps.Image.Repository,
+		Image:            dps.Image.Name,
+		Tag:              dps.Image.Tag,
+		ImagePullPolicy:  dps.Image.PullPolicy,
+		ImagePullSecrets: secrets.DevicePlugin,
+
+		ProgressDeadlineSeconds: copyInt32(dps.ProgressDeadlineSeconds),
+
+		PodSchedulingSpec:      PodSchedulingSpec(*dps.PodSchedulingSpec.DeepCopy()),
+		ContainerOverridesSpec: ContainerOverridesSpec(*dps.ContainerOverridesSpec.DeepCopy()),
+	}
+	for _, env := range dps.Env {
+		dst.Spec.DevicePlugin.Env = append(dst.Spec.DevicePlugin.Env, *env.DeepCopy())
+	}
+
+	hrs := &src.Spec.HostSetup.Reporter
+	dst.Spec.HostSetup = HostSetupSpec{
+		Enabled:                 copyBool(src.Spec.HostSetup.Enabled),
+		ProgressDeadlineSeconds: copyInt32(src.Spec.HostSetup.ProgressDeadlineSeconds),
+		Mode:                    HostSetupMode(src.Spec.HostSetup.Mode),
+		Reporter: HostSetupReporterSpec{
+			Enabled:         copyBool(hrs.Enabled),
+			Repository:      hrs.Image.Repository,
+			Image:           hrs.Image.Name,
+			Tag:             hrs.Image.Tag,
+			ImagePullPolicy: hrs.Image.PullPolicy,
+		},
+	}
+	if src.Spec.HostSetup.OsDists != nil {
+		dst.Spec.HostSetup.OsDists = []OsDistSetupSpec{}
+	}
+	for i, osDist := range src.Spec.HostSetup.OsDists {
+		dstOsDist := OsDistSetupSpec{
+			OsId:              osDist.OsId,
+			OsMajorVersion:    osDist.OsMajorVersion,
+			Version:           osDist.XRT.Version,
+			XrmInstallation:   copyBool(osDist.XRT.XrmInstallation),
+			ShellFlashEnabled: copyBool(osDist.ShellFlash.Enabled),
+			Repository:        osDist.Image.Repository,
+			Image:             osDist.Image.Name,
+			Tag:               osDist.Image.Tag,
+			ImagePullPolicy:   osDist.Image.PullPolicy,
+			ImagePullSecrets:  secrets.OsDists[i],
+
+			PodSchedulingSpec:      PodSchedulingSpec(*osDist.PodSchedulingSpec.DeepCopy()),
+			ContainerOverridesSpec: ContainerOverridesSpec(*osDist.ContainerOverridesSpec.DeepCopy()),
+			InitXrtXrm:             (*ContainerOverridesSpec)(osDist.InitXrtXrm.DeepCopy()),
+			InitCardFlash:          (*ContainerOverridesSpec)(osDist.InitCardFlash.DeepCopy()),
+		}
+		if osDist.ShellFlash.Cards != nil {
+			dstOsDist.Cards = []string{}
+		}
+		for _, card := range osDist.ShellFlash.Cards {
+			dstOsDist.Cards = append(dstOsDist.Cards, card.Platform)
+		}
+		dst.Spec.HostSetup.OsDists = append(dst.Spec.HostSetup.OsDists, dstOsDist)
+	}
+
+	dst.Spec.FPGANodes = FPGANodeSpec{NodeSelector: copyStringMap(src.Spec.FPGANodes.NodeSelector)}
+	for _, device := range src.Spec.FPGANodes.PCIDevices {
+		dst.Spec.FPGANodes.PCIDevices = append(dst.Spec.FPGANodes.PCIDevices, PCIDeviceSpec(device))
+	}
+
+	convertStatusFrom(&src.Status, &dst.Status)
+	return nil
+}
+
+// globalImagePullSecrets returns the image pull secrets of each component as per the global ones in v2
+func globalImagePullSecrets(global []string, osDists int) imagePullSecrets {
+	secrets := imagePullSecrets{
+		NFD:              copyStrings(global),
+		NodeLabeller:     copyStrings(global),
+		ContainerRuntime: copyStrings(global),
+		DevicePlugin:     copyStrings(global),
+	}
+	for i := 0; i < osDists; i++ {
+		secrets.OsDists = append(secrets.OsDists, copyStrings(global))
+	}
+	return secrets
+}
+
+func toComponentImage(repo string, image string, tag string, pullPolicy string) v2.ComponentImageSpec {
+	return v2.ComponentImageSpec{
+		Repository: repo,
+		Name:       image,
+		Tag:        tag,
+		PullPolicy: pullPolicy,
+	}
+}
+
+func convertStatusTo(src *ClusterPolicyStatus, dst *v2.ClusterPolicyStatus) {
+	dst.State = v2.State(src.State)
+	dst.Namespace = src.Namespace
+	for _, condition := range src.Conditions {
+		dst.Conditions = append(dst.Conditions, *condition.DeepCopy())
+	}
+	for _, component := range src.Components {
+		dstComponent := v2.ComponentStatus{
+			Name:    component.Name,
+			State:   v2.State(component.State),
+			Reason:  component.Reason,
+			Message: component.Message,
+
+			LastTransitionTime: component.LastTransitionTime.DeepCopy(),
+		}
+		for _, ds := range component.DaemonSets {
+			dstComponent.DaemonSets = append(dstComponent.DaemonSets, v2.DaemonSetStatus(*ds.DeepCopy()))
+		}
+		for _, deployment := range component.Deployments {
+			dstComponent.Deployments = append(dstComponent.Deployments, v2.DeploymentStatus(deployment))
+		}
+		dstComponent.SkippedObjects = append(dstComponent.SkippedObjects, component.SkippedObjects...)
+		dst.Components = append(dst.Components, dstComponent)
+	}
+	for _, node := range src.HostSetupNodes {
+		dst.HostSetupNodes = append(dst.HostSetupNodes, v2.HostSetupNodeStatus(*node.DeepCopy()))
+	}
+}
+
+func convertStatusFrom(src *v2.ClusterPolicyStatus, dst *ClusterPolicyStatus) {
+	dst.State = State(src.State)
+	dst.Namespace = src.Namespace
+	for _, condition := range src.Conditions {
+		dst.Conditions = append(dst.Conditions, *condition.DeepCopy())
+	}
+	for _, component := range src.Components {
+		dstComponent := ComponentStatus{
+			Name:    component.Name,
+			State:   State(component.State),
+			Reason:  component.Reason,
+			Message: component.Message,
+
+			LastTransitionTime: component.LastTransitionTime.DeepCopy(),
+		}
+		for _, ds := range component.DaemonSets {
+			dstComponent.DaemonSets = append(dstComponent.DaemonSets, DaemonSetStatus(*ds.DeepCopy()))
+		}
+		for _, deployment := range component.Deployments {
+			dstComponent.Deployments = append(dstComponent.Deployments, DeploymentStatus(deployment))
+		}
+		dstComponent.SkippedObjects = append(dstComponent.SkippedObjects, component.SkippedObjects...)
+		dst.Components = append(dst.Components, dstComponent)
+	}
+	for _, node := range src.HostSetupNodes {
+		dst.HostSetupNodes = append(dst.HostSetupNodes, HostSetupNodeStatus(*node.DeepCopy()))
+	}
+}
+
+func copyBool(b *bool) *bool {
+	if b == nil {
+		return nil
+	}
+	value := *b
+	return &value
+}
+
+func copyInt32(n *int32) *int32 {
+	if n == nil {
+		return nil
+	}
+	value := *n
+	return &value
+}
+
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
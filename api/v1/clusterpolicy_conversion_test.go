@@ -0,0 +1,208 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v2 "github.com/xilinx/fpga-operator/api/v2"
+)
+
+func newFullClusterPolicy() *ClusterPolicy {
+	cp := newClusterPolicy("fpga-clusterpolicy")
+	cp.Spec.SetDefaults()
+	cp.ObjectMeta.Annotations = map[string]string{"example.com/owner": "team"}
+	cp.Spec.Operator.AssetsConfigMap = "fpga-operator-assets"
+	cp.Spec.NFD.Enabled = new(bool)
+	cp.Spec.NodeLabeller.Enabled = new(bool)
+	*cp.Spec.NodeLabeller.Enabled = true
+	cp.Spec.NodeLabeller.Tolerations = []corev1.Toleration{{Operator: corev1.TolerationOpExists}}
+	cp.Spec.ContainerRuntime.Args = []string{"--debug"}
+	cp.Spec.ContainerRuntime.Env = []corev1.EnvVar{{Name: "XILINX_VISIBLE_DEVICES", Value: "all"}}
+	cp.Spec.DevicePlugin.Env = []corev1.EnvVar{{Name: "U30NameConvention", Value: "CommonName"}}
+	cp.Spec.DevicePlugin.Tolerations = []corev1.Toleration{
+		{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "fpga", Effect: corev1.TaintEffectNoSchedule},
+	}
+	cp.Spec.DevicePlugin.PodAnnotations = map[string]string{"example.com/scrape": "true"}
+	*cp.Spec.HostSetup.ProgressDeadlineSeconds = 1800
+	*cp.Spec.HostSetup.Reporter.Enabled = true
+	cp.Spec.HostSetup.Mode = HostSetupJob
+	cp.Spec.HostSetup.OsDists[0].NodeSelector = map[string]string{"example.com/pool": "fpga"}
+	cp.Spec.HostSetup.OsDists[0].PriorityClassName = "system-node-critical"
+	cp.Spec.DevicePlugin.Resources = &corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Mi")},
+	}
+	cp.Spec.HostSetup.OsDists[0].InitXrtXrm = &ContainerOverridesSpec{
+		Resources: &corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+		},
+	}
+	cp.Status = ClusterPolicyStatus{
+		State:     NotReady,
+		Namespace: "xilinx-system",
+		Conditions: []metav1.Condition{
+			{Type: ConditionReady, Status: metav1.ConditionFalse, Reason: ReasonStatesNotReady, Message: "States not ready: state-host-setup"},
+		},
+		Components: []ComponentStatus{
+			{
+				Name:               "state-host-setup",
+				State:              NotReady,
+				Reason:             ReasonDaemonSetsNotReady,
+				LastTransitionTime: &metav1.Time{Time: time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC)},
+				DaemonSets: []DaemonSetStatus{
+					{Name: "host-setup-ubuntu18-daemonset", NodeSelector: map[string]string{"os": "ubuntu18"}, DesiredNumberScheduled: 2,
+						UpdatedNumberScheduled: 1, NumberAvailable: 1},
+				},
+			},
+			{
+				Name:           "state-device-plugin",
+				State:          NoMatchingNodes,
+				Reason:         ReasonNoMatchingNodes,
+				SkippedObjects: []string{"ServiceMonitor/device-plugin"},
+			},
+			{
+				Name:        "state-nfd",
+				State:       NotReady,
+				Reason:      ReasonDaemonSetsNotReady,
+				Deployments: []DeploymentStatus{{Name: "nfd-master", Replicas: 1, UnavailableReplicas: 1}},
+			},
+		},
+		HostSetupNodes: []HostSetupNodeStatus{
+			{
+				Node:            "fpga-node",
+				XRTVersion:      "2023.1",
+				XRMInstalled:    true,
+				Shells:          map[string]string{"0000:3b:00": "xilinx_u200_gen3x16_xdma_base_2"},
+				LastSetupTime:   &metav1.Time{Time: time.Date(2023, 3, 1, 9, 30, 0, 0, time.UTC)},
+				LastSetupResult: "Succeeded",
+			},
+		},
+	}
+	return cp
+}
+
+func TestConversionRoundTrip(t *testing.T) {
+	testCases := []struct {
+		description string
+		update      func(cp *ClusterPolicy)
+		global      []string
+		annotated   bool
+	}{
+		{
+			"same image pull secrets",
+			func(cp *ClusterPolicy) {
+				cp.Spec.NFD.ImagePullSecrets = []string{"registry-secret"}
+				cp.Spec.NodeLabeller.ImagePullSecrets = []string{"registry-secret"}
+				cp.Spec.ContainerRuntime.ImagePullSecrets = []string{"registry-secret"}
+				cp.Spec.DevicePlugin.ImagePullSecrets = []string{"registry-secret"}
+				cp.Spec.HostSetup.OsDists[0].ImagePullSecrets = []string{"registry-secret"}
+				cp.Spec.HostSetup.OsDists[1].ImagePullSecrets = []string{"registry-secret"}
+			},
+			[]string{"registry-secret"},
+			false,
+		},
+		{
+			"per-component image pull secrets",
+			func(cp *ClusterPolicy) {
+				cp.Spec.DevicePlugin.ImagePullSecrets = []string{"device-plugin-secret"}
+				cp.Spec.HostSetup.OsDists[1].ImagePullSecrets = []string{"host-setup-secret", "device-plugin-secret"}
+			},
+			[]string{"device-plugin-secret", "host-setup-secret"},
+			true,
+		},
+		{
+			"no cards and no host setup",
+			func(cp *ClusterPolicy) {
+				cp.Spec.HostSetup.OsDists[0].Cards = nil
+				cp.Spec.HostSetup.Enabled = new(bool)
+			},
+			nil,
+			false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			src := newFullClusterPolicy()
+			tc.update(src)
+
+			hub := &v2.ClusterPolicy{}
+			require.NoError(t, src.DeepCopy().ConvertTo(hub), "failed to convert to v2")
+			require.Equal(t, tc.global, hub.Spec.Global.ImagePullSecrets, "unexpected global image pull secrets")
+			_, annotated := hub.ObjectMeta.Annotations[ImagePullSecretsAnnotation]
+			require.Equal(t, tc.annotated, annotated, "unexpected image pull secrets annotation")
+
+			// v1 -> v2 -> v1
+			dst := &ClusterPolicy{}
+			require.NoError(t, dst.ConvertFrom(hub.DeepCopy()), "failed to convert from v2")
+			require.Equal(t, src, dst, "v1 is changed by round-trip conversion")
+
+			// v2 -> v1 -> v2
+			hubDst := &v2.ClusterPolicy{}
+			require.NoError(t, dst.ConvertTo(hubDst), "failed to convert to v2")
+			require.Equal(t, hub, hubDst, "v2 is changed by round-trip conversion")
+		})
+	}
+}
+
+func TestConversionFromHub(t *testing.T) {
+	hub := &v2.ClusterPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "fpga-clusterpolicy"},
+		Spec: v2.ClusterPolicySpec{
+			Global: v2.GlobalSpec{DefaultRuntime: v2.Docker, ImagePullSecrets: []string{"registry-secret"}},
+			DevicePlugin: v2.DevicePluginSpec{
+				Image: v2.ComponentImageSpec{Repository: "public.ecr.aws/xilinx_dcg", Name: "k8s-device-plugin", Tag: "1.2.0"},
+			},
+			HostSetup: v2.HostSetupSpec{
+				OsDists: []v2.OsDistSetupSpec{
+					{
+						OsId:           "ubuntu",
+						OsMajorVersion: "20",
+						XRT:            v2.XRTSpec{Version: "2023.1"},
+						ShellFlash:     v2.ShellFlashSpec{Cards: []v2.CardSelector{{Platform: "alveo-u200"}}},
+					},
+				},
+			},
+		},
+	}
+
+	cp := &ClusterPolicy{}
+	require.NoError(t, cp.ConvertFrom(hub), "failed to convert from v2")
+	require.Equal(t, Docker, cp.Spec.Operator.DefaultRuntime)
+	require.Equal(t, "public.ecr.aws/xilinx_dcg/k8s-device-plugin:1.2.0",
+		ImagePath(cp.Spec.DevicePlugin.Repository, cp.Spec.DevicePlugin.Image, cp.Spec.DevicePlugin.Tag))
+	require.Equal(t, "2023.1", cp.Spec.HostSetup.OsDists[0].Version)
+	require.Equal(t, []string{"alveo-u200"}, cp.Spec.HostSetup.OsDists[0].Cards)
+
+	// global image pull secrets are used by every component in v1
+	require.Equal(t, []string{"registry-secret"}, cp.Spec.ContainerRuntime.ImagePullSecrets)
+	require.Equal(t, []string{"registry-secret"}, cp.Spec.DevicePlugin.ImagePullSecrets)
+	require.Equal(t, []string{"registry-secret"}, cp.Spec.HostSetup.OsDists[0].ImagePullSecrets)
+
+	// stale per-component image pull secrets are ignored once global ones are changed in v2
+	hub.ObjectMeta.Annotations = map[string]string{ImagePullSecretsAnnotation: `{"devicePlugin":["old-secret"],"osDists":[null]}`}
+	cp = &ClusterPolicy{}
+	require.NoError(t, cp.ConvertFrom(hub), "failed to convert from v2")
+	require.Equal(t, []string{"registry-secret"}, cp.Spec.DevicePlugin.ImagePullSecrets)
+	require.Nil(t, cp.ObjectMeta.Annotations, "image pull secrets annotation is kept in v1")
+}
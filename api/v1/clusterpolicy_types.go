@@ -37,6 +37,66 @@ const (
 // Runtime defines container runtime type
 type Runtime string
 
+const (
+	// HostSetupDaemonSet runs host-setup in the init containers of a DaemonSet per OS distribution
+	HostSetupDaemonSet HostSetupMode = "daemonset"
+	// HostSetupJob runs host-setup in a Job per node, re-run once the setup desired on the node changes
+	HostSetupJob HostSetupMode = "job"
+)
+
+// HostSetupMode defines how host-setup is run on the nodes
+type HostSetupMode string
+
+const (
+	// DefaultRepository is the default image repository of all components
+	DefaultRepository = "public.ecr.aws/xilinx_dcg"
+	// DefaultContainerRuntimeImage is the default image of Xilinx Container Toolkit
+	DefaultContainerRuntimeImage = "xilinx-container-runtime"
+	// DefaultContainerRuntimeTag is the default image tag of Xilinx Container Toolkit
+	DefaultContainerRuntimeTag = "latest"
+	// DefaultDevicePluginImage is the default image of device-plugin
+	DefaultDevicePluginImage = "k8s-device-plugin"
+	// DefaultDevicePluginTag is the default image tag of device-plugin
+	DefaultDevicePluginTag = "1.2.0"
+	// DefaultNFDRepository is the default image repository of Node Feature Discovery
+	DefaultNFDRepository = "registry.k8s.io/nfd"
+	// DefaultNFDImage is the default image of Node Feature Discovery
+	DefaultNFDImage = "node-feature-discovery"
+	// DefaultNFDTag is the default image tag of Node Feature Discovery
+	DefaultNFDTag = "v0.12.0"
+	// DefaultNodeLabellerImage is the default image of node labeller, which is the operator image.
+	// Its tag is the version of the operator
+	DefaultNodeLabellerImage = "fpga-operator"
+	// DefaultHostSetupImage is the default image of host-setup
+	DefaultHostSetupImage = "host-setup"
+	// DefaultHostSetupVersion is the default version to be setup on the host
+	DefaultHostSetupVersion = "2023.1"
+	// DefaultImagePullPolicy is the default image pull policy of all components
+	DefaultImagePullPolicy = "IfNotPresent"
+	// DefaultRuntimeClass is the default name of RuntimeClass
+	DefaultRuntimeClass = "xilinx"
+	// DefaultXCRInstallDir is the default install directory of Xilinx Container Toolkit on the host
+	DefaultXCRInstallDir = "/usr/bin"
+	// DefaultFPGAVendor is the default PCI vendor ID of FPGA
+	DefaultFPGAVendor = "10ee"
+	// DefaultFPGAClass is the default PCI device class of FPGA, i.e. processing accelerators
+	DefaultFPGAClass = "1200"
+	// DefaultProgressDeadlineSeconds is the default seconds for the DaemonSets of a state to roll out
+	DefaultProgressDeadlineSeconds int32 = 600
+)
+
+// OperatorVersion is the version of the operator, set at build time with
+// -ldflags "-X github.com/xilinx/fpga-operator/api/v1.OperatorVersion=<version>"
+var OperatorVersion = "latest"
+
+// defaultHostSetupTags is the default host-setup image tag of each OS distribution
+var defaultHostSetupTags = map[string]string{
+	"ubuntu18": "ubuntu18.04",
+	"ubuntu20": "ubuntu20.04",
+	"ubuntu22": "ubuntu22.04",
+	"centos7":  "centos7.9",
+}
+
 func (r Runtime) String() string {
 	switch r {
 	case Docker:
@@ -52,6 +112,148 @@ type OperatorSpec struct {
 	// +kubebuilder:validation:Enum=docker;containerd
 	// +kubebuilder:default=containerd
 	DefaultRuntime Runtime `json:"defaultRuntime"`
+
+	// Optional: name of the ConfigMap in the operator namespace overriding the asset files of the states.
+	// The keys are in the format of <state>.<file>, eg. state-device-plugin.0100_device_plugin.yaml
+	// +kubebuilder:validation:Optional
+	AssetsConfigMap string `json:"assetsConfigMap,omitempty"`
+}
+
+// PCIDeviceSpec matches a PCI device labeled by NFD, eg. feature.node.kubernetes.io/pci-1200_10ee.present
+type PCIDeviceSpec struct {
+	// PCI vendor ID, eg. 10ee
+	// +kubebuilder:validation:Pattern=`^[0-9a-f]{4}$`
+	Vendor string `json:"vendor"`
+
+	// Optional: PCI device class, eg. 1200
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[0-9a-f]{4}$`
+	Class string `json:"class,omitempty"`
+
+	// Optional: PCI device ID, only matched if NFD is configured to label the device ID
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[0-9a-f]{4}$`
+	Device string `json:"device,omitempty"`
+}
+
+// FPGANodeSpec defines how the nodes with FPGA(s) are detected
+type FPGANodeSpec struct {
+	// Optional: PCI devices of FPGA, a node with any of the devices is an FPGA node
+	// +kubebuilder:validation:Optional
+	PCIDevices []PCIDeviceSpec `json:"pciDevices,omitempty"`
+
+	// Optional: Labels of the FPGA nodes, used instead of pciDevices if set
+	// +kubebuilder:validation:Optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+}
+
+// PodSchedulingSpec defines the scheduling and extra metadata of the pods of a component
+type PodSchedulingSpec struct {
+	// Optional: Node selector merged into the node selector of the pods
+	// +kubebuilder:validation:Optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Optional: Affinity of the pods
+	// +kubebuilder:validation:Optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// Optional: Tolerations appended to the tolerations of the pods
+	// +kubebuilder:validation:Optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Optional: Priority class of the pods
+	// +kubebuilder:validation:Optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// Optional: Extra labels of the pods
+	// +kubebuilder:validation:Optional
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+
+	// Optional: Extra annotations of the pods
+	// +kubebuilder:validation:Optional
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+}
+
+// ContainerOverridesSpec defines the resources and security context overrides of a container
+type ContainerOverridesSpec struct {
+	// Optional: Resource requests and limits of the container
+	// +kubebuilder:validation:Optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Optional: Security context merged into the security context of the container
+	// +kubebuilder:validation:Optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+}
+
+// NFDSpec defines the properties of Node Feature Discovery deployed by the operator
+type NFDSpec struct {
+	// Enabled indicates if deployment of NFD worker and master through operator is enabled
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// NFD image repo
+	// +kubebuilder:validation:Optional
+	Repository string `json:"repository,omitempty"`
+
+	// NFD image name
+	// +kubebuilder:validation:Pattern=[a-zA-Z0-9\-]+
+	Image string `json:"image,omitempty"`
+
+	// NFD image tag
+	// +kubebuilder:validation:Optional
+	Tag string `json:"tag,omitempty"`
+
+	// Image pull policy
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
+	ImagePullPolicy string `json:"imagePullPolicy,omitempty"`
+
+	// Image pull secrets
+	// +kubebuilder:validation:Optional
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+
+	// Optional: seconds for the DaemonSets to roll out before the state is reported as degraded
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+}
+
+// NodeLabellerSpec defines the properties of the built-in node labeller, which labels the nodes
+// with OS and FPGA information when Node Feature Discovery is not deployed
+type NodeLabellerSpec struct {
+	// Enabled indicates if deployment of node labeller through operator is enabled
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// node labeller image repo
+	// +kubebuilder:validation:Optional
+	Repository string `json:"repository,omitempty"`
+
+	// node labeller image name
+	// +kubebuilder:validation:Pattern=[a-zA-Z0-9\-]+
+	Image string `json:"image,omitempty"`
+
+	// node labeller image tag
+	// +kubebuilder:validation:Optional
+	Tag string `json:"tag,omitempty"`
+
+	// Image pull policy
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
+	ImagePullPolicy string `json:"imagePullPolicy,omitempty"`
+
+	// Image pull secrets
+	// +kubebuilder:validation:Optional
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+
+	// Optional: seconds for the DaemonSets to roll out before the state is reported as degraded
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
+	// Scheduling and extra metadata of the pods
+	PodSchedulingSpec `json:",inline"`
+
+	// Resources and security context of the main container
+	ContainerOverridesSpec `json:",inline"`
 }
 
 type ContainerRuntimeSpec struct {
@@ -78,12 +280,18 @@ type ContainerRuntimeSpec struct {
 
 	// Image pull policy
 	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
 	ImagePullPolicy string `json:"imagePullPolicy,omitempty"`
 
 	// Image pull secrets
 	// +kubebuilder:validation:Optional
 	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
 
+	// Optional: seconds for the DaemonSets to roll out before the state is reported as degraded
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
 	// Optional: List of arguments
 	Args []string `json:"args,omitempty"`
 
@@ -100,6 +308,12 @@ type ContainerRuntimeSpec struct {
 
 	// // Docker or Containerd socket file
 	// RuntimeSocket string `json:"runtimeSocket,omitempty"`
+
+	// Scheduling and extra metadata of the pods
+	PodSchedulingSpec `json:",inline"`
+
+	// Resources and security context of the main container
+	ContainerOverridesSpec `json:",inline"`
 }
 
 type DevicePluginSpec struct {
@@ -120,14 +334,26 @@ type DevicePluginSpec struct {
 
 	// Image pull policy
 	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
 	ImagePullPolicy string `json:"imagePullPolicy,omitempty"`
 
 	// Image pull secrets
 	// +kubebuilder:validation:Optional
 	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
 
+	// Optional: seconds for the DaemonSets to roll out before the state is reported as degraded
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
 	// Optional: List of environment variables
 	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Scheduling and extra metadata of the pods
+	PodSchedulingSpec `json:",inline"`
+
+	// Resources and security context of the main container
+	ContainerOverridesSpec `json:",inline"`
 }
 
 type OsDistSetupSpec struct {
@@ -165,11 +391,26 @@ type OsDistSetupSpec struct {
 
 	// Image pull policy
 	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
 	ImagePullPolicy string `json:"imagePullPolicy,omitempty"`
 
 	// Image pull secrets
 	// +kubebuilder:validation:Optional
 	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+
+	// Scheduling and extra metadata of the pods
+	PodSchedulingSpec `json:",inline"`
+
+	// Resources and security context of the main container
+	ContainerOverridesSpec `json:",inline"`
+
+	// Optional: Resources and security context of the init-xrt-xrm init container
+	// +kubebuilder:validation:Optional
+	InitXrtXrm *ContainerOverridesSpec `json:"initXrtXrm,omitempty"`
+
+	// Optional: Resources and security context of the init-card-flash init container
+	// +kubebuilder:validation:Optional
+	InitCardFlash *ContainerOverridesSpec `json:"initCardFlash,omitempty"`
 }
 
 type HostSetupSpec struct {
@@ -178,6 +419,44 @@ type HostSetupSpec struct {
 
 	// Setup per os distributions, eg. ubuntu18, ubuntu20
 	OsDists []OsDistSetupSpec `json:"osDists"`
+
+	// Optional: seconds for the DaemonSets to roll out before the state is reported as degraded
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
+	// Optional: run host-setup in a DaemonSet per OS distribution, or in a Job per node
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=daemonset;job
+	Mode HostSetupMode `json:"mode,omitempty"`
+
+	// Optional: sidecar reporting the setup of each node in the node annotations
+	// +kubebuilder:validation:Optional
+	Reporter HostSetupReporterSpec `json:"reporter,omitempty"`
+}
+
+// HostSetupReporterSpec defines the sidecar of host-setup, which reports the XRT and XRM installed,
+// the shells flashed and the result of the last setup in the annotations of its node
+type HostSetupReporterSpec struct {
+	// Enabled indicates if the reporter is deployed with host-setup
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// reporter image repo
+	// +kubebuilder:validation:Optional
+	Repository string `json:"repository,omitempty"`
+
+	// reporter image name
+	// +kubebuilder:validation:Pattern=[a-zA-Z0-9\-]+
+	Image string `json:"image,omitempty"`
+
+	// reporter image tag
+	// +kubebuilder:validation:Optional
+	Tag string `json:"tag,omitempty"`
+
+	// Image pull policy
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
+	ImagePullPolicy string `json:"imagePullPolicy,omitempty"`
 }
 
 // ClusterPolicySpec defines the desired state of ClusterPolicy
@@ -188,6 +467,14 @@ type ClusterPolicySpec struct {
 	// Operator component spec
 	Operator OperatorSpec `json:"operator"`
 
+	// NFD component spec
+	// +kubebuilder:validation:Optional
+	NFD NFDSpec `json:"nfd,omitempty"`
+
+	// NodeLabeller component spec
+	// +kubebuilder:validation:Optional
+	NodeLabeller NodeLabellerSpec `json:"nodeLabeller,omitempty"`
+
 	// ContainerRuntime component spec
 	ContainerRuntime ContainerRuntimeSpec `json:"containerRuntime"`
 
@@ -196,6 +483,10 @@ type ClusterPolicySpec struct {
 
 	// HostSetup component spec
 	HostSetup HostSetupSpec `json:"hostSetup"`
+
+	// Detection of the nodes with FPGA(s)
+	// +kubebuilder:validation:Optional
+	FPGANodes FPGANodeSpec `json:"fpgaNodes,omitempty"`
 }
 
 // State indicates state of GPU operator components
@@ -210,8 +501,131 @@ const (
 	NotReady State = "notReady"
 	// Disabled indicates if the state is disabled
 	Disabled State = "disabled"
+	// NoMatchingNodes indicates no node matches the DaemonSets of the state
+	NoMatchingNodes State = "noMatchingNodes"
+)
+
+const (
+	// ConditionReady indicates all enabled states of ClusterPolicy are ready
+	ConditionReady = "Ready"
+	// ConditionProgressing indicates some states are being rolled out
+	ConditionProgressing = "Progressing"
+	// ConditionDegraded indicates the operator failed to reconcile some states
+	ConditionDegraded = "Degraded"
+	// ConditionNodeFeatureDiscoveryMissing indicates no node is labeled by Node Feature Discovery
+	ConditionNodeFeatureDiscoveryMissing = "NodeFeatureDiscoveryMissing"
+)
+
+const (
+	// ReasonAllStatesReady is used when all states are ready
+	ReasonAllStatesReady = "AllStatesReady"
+	// ReasonStatesNotReady is used when some states are waiting for their resources to become ready
+	ReasonStatesNotReady = "StatesNotReady"
+	// ReasonReconcileFailed is used when the operator failed to deploy resources of a state
+	ReasonReconcileFailed = "ReconcileFailed"
+	// ReasonAssetsLoadFailed is used when the operator failed to load the asset files of the states
+	ReasonAssetsLoadFailed = "AssetsLoadFailed"
+	// ReasonStateReady is used when all resources of a state are ready
+	ReasonStateReady = "Ready"
+	// ReasonStateDisabled is used when a state is disabled in ClusterPolicy
+	ReasonStateDisabled = "Disabled"
+	// ReasonDaemonSetsNotReady is used when some DaemonSets of a state are not rolled out to all their nodes
+	ReasonDaemonSetsNotReady = "DaemonSetsNotReady"
+	// ReasonJobsNotComplete is used when some Jobs of a state are not complete on their nodes
+	ReasonJobsNotComplete = "JobsNotComplete"
+	// ReasonProgressDeadlineExceeded is used when DaemonSets of a state are not rolled out within the progress deadline
+	ReasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+	// ReasonNoMatchingNodes is used when no node matches the DaemonSets of a state
+	ReasonNoMatchingNodes = "NoMatchingNodes"
+	// ReasonNoMatchingSpec is used when DaemonSets of a state have no spec in ClusterPolicy
+	ReasonNoMatchingSpec = "NoMatchingSpec"
+	// ReasonDuplicateClusterPolicy is used when ClusterPolicy is ignored as another one is active
+	ReasonDuplicateClusterPolicy = "DuplicateClusterPolicy"
+	// ReasonNoNodeFeatureLabels is used when no node has the labels of Node Feature Discovery
+	ReasonNoNodeFeatureLabels = "NoNodeFeatureLabels"
+	// ReasonNodeFeatureLabelsFound is used when some nodes have the labels of Node Feature Discovery
+	ReasonNodeFeatureLabelsFound = "NodeFeatureLabelsFound"
+	// ReasonNodeLabellerEnabled is used when the built-in node labeller is deployed instead of Node Feature Discovery
+	ReasonNodeLabellerEnabled = "NodeLabellerEnabled"
 )
 
+// DaemonSetStatus indicates rollout status of a DaemonSet deployed by the operator
+type DaemonSetStatus struct {
+	// Name of the DaemonSet
+	Name string `json:"name"`
+	// NodeSelector identifies the set of nodes the DaemonSet is deployed on
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// DesiredNumberScheduled is the number of nodes that should be running the DaemonSet pod
+	DesiredNumberScheduled int32 `json:"desiredNumberScheduled"`
+	// NumberReady is the number of nodes running a ready DaemonSet pod
+	NumberReady int32 `json:"numberReady"`
+	// NumberUnavailable is the number of nodes without an available DaemonSet pod
+	NumberUnavailable int32 `json:"numberUnavailable"`
+	// UpdatedNumberScheduled is the number of nodes running the updated DaemonSet pod
+	// +optional
+	UpdatedNumberScheduled int32 `json:"updatedNumberScheduled,omitempty"`
+	// NumberAvailable is the number of nodes running an available DaemonSet pod
+	// +optional
+	NumberAvailable int32 `json:"numberAvailable,omitempty"`
+}
+
+// DeploymentStatus indicates rollout status of a Deployment deployed by the operator
+type DeploymentStatus struct {
+	// Name of the Deployment
+	Name string `json:"name"`
+	// Replicas is the number of pods targeted by the Deployment
+	Replicas int32 `json:"replicas"`
+	// UnavailableReplicas is the number of pods of the Deployment not available
+	// +optional
+	UnavailableReplicas int32 `json:"unavailableReplicas,omitempty"`
+}
+
+// ComponentStatus indicates status of a single state, eg. state-device-plugin
+type ComponentStatus struct {
+	// Name of the state
+	Name string `json:"name"`
+	// +kubebuilder:validation:Enum=ignored;ready;notReady;disabled;noMatchingNodes
+	// State indicates status of the state
+	State State `json:"state"`
+	// Reason is a brief CamelCase reason for the state
+	Reason string `json:"reason,omitempty"`
+	// Message is a human readable description of the state
+	Message string `json:"message,omitempty"`
+	// LastTransitionTime is the last time the state changed
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+	// DaemonSets indicates rollout status of DaemonSets deployed for the state
+	DaemonSets []DaemonSetStatus `json:"daemonSets,omitempty"`
+	// Deployments indicates rollout status of Deployments deployed for the state
+	// +optional
+	Deployments []DeploymentStatus `json:"deployments,omitempty"`
+	// SkippedObjects are the objects of the state not created as their kinds are not installed,
+	// in the format of <kind>/<name>, eg. ServiceMonitor/device-plugin
+	// +optional
+	SkippedObjects []string `json:"skippedObjects,omitempty"`
+}
+
+// HostSetupNodeStatus indicates the setup of a node reported by host-setup in the node annotations
+type HostSetupNodeStatus struct {
+	// Node is the name of the node
+	Node string `json:"node"`
+	// XRTVersion is the version of XRT installed by host-setup
+	// +optional
+	XRTVersion string `json:"xrtVersion,omitempty"`
+	// XRMInstalled indicates if XRM is installed by host-setup
+	// +optional
+	XRMInstalled bool `json:"xrmInstalled,omitempty"`
+	// Shells are the shells flashed on the FPGA cards, keyed by the PCI address of the card, eg. 0000:3b:00
+	// +optional
+	Shells map[string]string `json:"shells,omitempty"`
+	// LastSetupTime is the time the last setup completed
+	// +optional
+	LastSetupTime *metav1.Time `json:"lastSetupTime,omitempty"`
+	// LastSetupResult is the result of the last setup, Succeeded or Failed
+	// +optional
+	LastSetupResult string `json:"lastSetupResult,omitempty"`
+}
+
 // ClusterPolicyStatus defines the observed state of ClusterPolicy
 type ClusterPolicyStatus struct {
 	// +kubebuilder:validation:Enum=ignored;ready;notReady;disabled
@@ -219,6 +633,21 @@ type ClusterPolicyStatus struct {
 	State State `json:"state"`
 	// Namespace indicates a namespace in which the operator is installed
 	Namespace string `json:"namespace,omitempty"`
+	// Conditions indicates the latest available observations of ClusterPolicy
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Components indicates status of each state of ClusterPolicy
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Components []ComponentStatus `json:"components,omitempty"`
+	// HostSetupNodes indicates the setup of each node reported by host-setup
+	// +optional
+	// +listType=map
+	// +listMapKey=node
+	HostSetupNodes []HostSetupNodeStatus `json:"hostSetupNodes,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -253,6 +682,27 @@ func (p *ClusterPolicy) SetStatus(s State, ns string) {
 	p.Status.Namespace = ns
 }
 
+func (nfd *NFDSpec) IsEnabled() bool {
+	if nfd.Enabled == nil {
+		return false
+	}
+	return *nfd.Enabled
+}
+
+func (nls *NodeLabellerSpec) IsEnabled() bool {
+	if nls.Enabled == nil {
+		return false
+	}
+	return *nls.Enabled
+}
+
+func (hrs *HostSetupReporterSpec) IsEnabled() bool {
+	if hrs.Enabled == nil {
+		return false
+	}
+	return *hrs.Enabled
+}
+
 func (crs *ContainerRuntimeSpec) IsEnabled() bool {
 	if crs.Enabled == nil {
 		return true
@@ -274,6 +724,167 @@ func (hss *HostSetupSpec) IsEnabled() bool {
 	return *hss.Enabled
 }
 
+// IsJobMode returns true if host-setup is run in a Job per node instead of DaemonSets
+func (hss *HostSetupSpec) IsJobMode() bool {
+	return hss.Mode == HostSetupJob
+}
+
+// SetDefaults materializes the defaults of all components in ClusterPolicy spec
+func (s *ClusterPolicySpec) SetDefaults() {
+	if s.Operator.DefaultRuntime == "" {
+		s.Operator.DefaultRuntime = Containerd
+	}
+	s.NFD.SetDefaults()
+	s.NodeLabeller.SetDefaults()
+	s.ContainerRuntime.SetDefaults()
+	s.DevicePlugin.SetDefaults()
+	s.HostSetup.SetDefaults()
+	s.FPGANodes.SetDefaults()
+}
+
+// SetDefaults materializes the default PCI device of FPGA if the FPGA nodes are not selected by labels
+func (fns *FPGANodeSpec) SetDefaults() {
+	if len(fns.PCIDevices) == 0 && len(fns.NodeSelector) == 0 {
+		fns.PCIDevices = []PCIDeviceSpec{{Vendor: DefaultFPGAVendor, Class: DefaultFPGAClass}}
+	}
+}
+
+// SetDefaults materializes the defaults of Node Feature Discovery, whose image is not in the default repository
+func (nfd *NFDSpec) SetDefaults() {
+	nfd.Enabled = defaultBool(nfd.Enabled, false)
+	nfd.ProgressDeadlineSeconds = defaultInt32(nfd.ProgressDeadlineSeconds, DefaultProgressDeadlineSeconds)
+	if nfd.Image == "" {
+		nfd.Image = DefaultNFDImage
+	}
+	if nfd.Image == DefaultNFDImage {
+		if nfd.Repository == "" {
+			nfd.Repository = DefaultNFDRepository
+		}
+		if nfd.Tag == "" {
+			nfd.Tag = DefaultNFDTag
+		}
+	}
+	if nfd.ImagePullPolicy == "" {
+		nfd.ImagePullPolicy = DefaultImagePullPolicy
+	}
+}
+
+// SetDefaults materializes the defaults of node labeller
+func (nls *NodeLabellerSpec) SetDefaults() {
+	nls.Enabled = defaultBool(nls.Enabled, false)
+	nls.ProgressDeadlineSeconds = defaultInt32(nls.ProgressDeadlineSeconds, DefaultProgressDeadlineSeconds)
+	// the tag of the operator image is not materialized, so node labeller is upgraded along with the operator
+	defaultImage(&nls.Repository, &nls.Image, &nls.Tag, DefaultNodeLabellerImage, "")
+	if nls.ImagePullPolicy == "" {
+		nls.ImagePullPolicy = DefaultImagePullPolicy
+	}
+}
+
+// SetDefaults materializes the defaults of Xilinx Container Toolkit
+func (crs *ContainerRuntimeSpec) SetDefaults() {
+	crs.Enabled = defaultBool(crs.Enabled, true)
+	crs.SetAsDefault = defaultBool(crs.SetAsDefault, false)
+	crs.ProgressDeadlineSeconds = defaultInt32(crs.ProgressDeadlineSeconds, DefaultProgressDeadlineSeconds)
+	if crs.RuntimeClass == "" {
+		crs.RuntimeClass = DefaultRuntimeClass
+	}
+	if crs.InstallDir == "" {
+		crs.InstallDir = DefaultXCRInstallDir
+	}
+	defaultImage(&crs.Repository, &crs.Image, &crs.Tag, DefaultContainerRuntimeImage, DefaultContainerRuntimeTag)
+	if crs.ImagePullPolicy == "" {
+		crs.ImagePullPolicy = DefaultImagePullPolicy
+	}
+}
+
+// SetDefaults materializes the defaults of device-plugin
+func (dps *DevicePluginSpec) SetDefaults() {
+	dps.Enabled = defaultBool(dps.Enabled, true)
+	dps.ProgressDeadlineSeconds = defaultInt32(dps.ProgressDeadlineSeconds, DefaultProgressDeadlineSeconds)
+	defaultImage(&dps.Repository, &dps.Image, &dps.Tag, DefaultDevicePluginImage, DefaultDevicePluginTag)
+	if dps.ImagePullPolicy == "" {
+		dps.ImagePullPolicy = DefaultImagePullPolicy
+	}
+}
+
+// SetDefaults materializes the defaults of host-setup for each OS distribution
+func (hss *HostSetupSpec) SetDefaults() {
+	hss.Enabled = defaultBool(hss.Enabled, true)
+	hss.ProgressDeadlineSeconds = defaultInt32(hss.ProgressDeadlineSeconds, DefaultProgressDeadlineSeconds)
+	if hss.Mode == "" {
+		hss.Mode = HostSetupDaemonSet
+	}
+	// the reporter is a command in the operator image, as the node labeller, whose tag is not materialized
+	hss.Reporter.Enabled = defaultBool(hss.Reporter.Enabled, false)
+	defaultImage(&hss.Reporter.Repository, &hss.Reporter.Image, &hss.Reporter.Tag, DefaultNodeLabellerImage, "")
+	if hss.Reporter.ImagePullPolicy == "" {
+		hss.Reporter.ImagePullPolicy = DefaultImagePullPolicy
+	}
+	for i := range hss.OsDists {
+		osDist := &hss.OsDists[i]
+		if osDist.Version == "" {
+			osDist.Version = DefaultHostSetupVersion
+		}
+		osDist.XrmInstallation = defaultBool(osDist.XrmInstallation, true)
+		osDist.ShellFlashEnabled = defaultBool(osDist.ShellFlashEnabled, true)
+		if osDist.Cards == nil {
+			osDist.Cards = []string{}
+		}
+		tag := defaultHostSetupTags[strings.ToLower(osDist.OsId+osDist.OsMajorVersion)]
+		defaultImage(&osDist.Repository, &osDist.Image, &osDist.Tag, DefaultHostSetupImage, tag)
+		if osDist.ImagePullPolicy == "" {
+			osDist.ImagePullPolicy = DefaultImagePullPolicy
+		}
+	}
+}
+
+// defaultBool returns a pointer to the default value if the flag is not set
+func defaultBool(flag *bool, value bool) *bool {
+	if flag != nil {
+		return flag
+	}
+	return &value
+}
+
+// defaultInt32 returns a pointer to the default value if the number is not set
+func defaultInt32(number *int32, value int32) *int32 {
+	if number != nil {
+		return number
+	}
+	return &value
+}
+
+// defaultImage sets the default image, and the default repo and tag if the default image is used
+func defaultImage(repo *string, image *string, tag *string, defaultImage string, defaultTag string) {
+	if *image == "" {
+		*image = defaultImage
+	}
+	if *image != defaultImage {
+		return
+	}
+	if *repo == "" {
+		*repo = DefaultRepository
+	}
+	if *tag == "" {
+		*tag = defaultTag
+	}
+}
+
+// operatorImageTag returns the tag of a component run from the operator image, eg. node labeller or the
+// host-setup reporter, which is the version of the operator if not set, so the component never skews from it
+func operatorImageTag(image string, tag string) string {
+	if image == DefaultNodeLabellerImage && tag == "" {
+		return OperatorVersion
+	}
+	return tag
+}
+
+// OperatorImagePath returns the path of the image of a component run from the operator image, eg. node labeller
+// or the host-setup reporter
+func OperatorImagePath(repo string, image string, tag string) string {
+	return ImagePath(repo, image, operatorImageTag(image, tag))
+}
+
 func ImagePath(repo string, image string, tag string) string {
 	var imagePath string
 	if repo == "" && tag == "" {
@@ -0,0 +1,352 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// log is for logging in this package.
+var clusterpolicylog = logf.Log.WithName("clusterpolicy-resource")
+
+var (
+	// registry host with optional port, followed by path components
+	repositoryRegexp = regexp.MustCompile(`^[a-zA-Z0-9]+([._-][a-zA-Z0-9]+)*(:[0-9]+)?(/[a-z0-9]+([._-][a-z0-9]+)*)*$`)
+	imageRegexp      = regexp.MustCompile(`^[a-z0-9]+([._-][a-z0-9]+)*$`)
+	tagRegexp        = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127}$`)
+	digestRegexp     = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// HostSetupPlatforms is the platform matrix supported by host setup,
+// which maps each OS distribution to the versions supported on the OS distribution,
+// and each version to the card platforms supported by the version on the OS distribution
+// +kubebuilder:object:generate=false
+type HostSetupPlatforms map[string]map[string]map[string]bool
+
+// ParseHostSetupPlatforms parses the platform matrix from the host setup spec file,
+// in which each line starts with "<card>_<version>_<os>-<release>:"
+func ParseHostSetupPlatforms(spec []byte) (HostSetupPlatforms, error) {
+	platforms := HostSetupPlatforms{}
+	scanner := bufio.NewScanner(bytes.NewReader(spec))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		comb := strings.SplitN(line, ":", 2)[0]
+		fields := strings.Split(comb, "_")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid host setup spec entry %q", comb)
+		}
+		card, version := fields[0], fields[1]
+		osId, release, ok := strings.Cut(fields[2], "-")
+		if !ok || osId == "" || release == "" {
+			return nil, fmt.Errorf("invalid host setup spec entry %q", comb)
+		}
+		osMajorVersion := strings.SplitN(release, ".", 2)[0]
+		osDist := osDistKey(osId, osMajorVersion)
+		if platforms[osDist] == nil {
+			platforms[osDist] = map[string]map[string]bool{}
+		}
+		if platforms[osDist][version] == nil {
+			platforms[osDist][version] = map[string]bool{}
+		}
+		platforms[osDist][version][card] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return platforms, nil
+}
+
+// osDistKey returns the key of the OS distribution in the platform matrix
+func osDistKey(osId, osMajorVersion string) string {
+	return strings.ToLower(osId + osMajorVersion)
+}
+
+// versions returns the sorted list of versions supported on the OS distribution
+func (p HostSetupPlatforms) versions(osDist string) []string {
+	versions := []string{}
+	for version := range p[osDist] {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// cards returns the sorted list of cards supported by the version on the OS distribution
+func (p HostSetupPlatforms) cards(osDist, version string) []string {
+	cards := []string{}
+	for card := range p[osDist][version] {
+		cards = append(cards, card)
+	}
+	sort.Strings(cards)
+	return cards
+}
+
+// ClusterPolicyValidator validates ClusterPolicy on creation and update
+// +kubebuilder:object:generate=false
+type ClusterPolicyValidator struct {
+	Client    client.Reader
+	Platforms HostSetupPlatforms
+}
+
+// ClusterPolicyDefaulter materializes the defaults of ClusterPolicy on creation and update
+// +kubebuilder:object:generate=false
+type ClusterPolicyDefaulter struct{}
+
+//+kubebuilder:webhook:path=/mutate-policy-xilinx-com-v1-clusterpolicy,mutating=true,failurePolicy=fail,sideEffects=None,groups=policy.xilinx.com,resources=clusterpolicies,verbs=create;update,versions=v1,name=mclusterpolicy.kb.io,admissionReviewVersions=v1
+
+var _ webhook.CustomDefaulter = &ClusterPolicyDefaulter{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type
+func (d *ClusterPolicyDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	cp, ok := obj.(*ClusterPolicy)
+	if !ok {
+		return fmt.Errorf("expected a ClusterPolicy but got %T", obj)
+	}
+	clusterpolicylog.Info("default", "name", cp.Name)
+
+	cp.Spec.SetDefaults()
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-policy-xilinx-com-v1-clusterpolicy,mutating=false,failurePolicy=fail,sideEffects=None,groups=policy.xilinx.com,resources=clusterpolicies,verbs=create;update,versions=v1,name=vclusterpolicy.kb.io,admissionReviewVersions=v1
+
+var _ webhook.CustomValidator = &ClusterPolicyValidator{}
+
+// SetupWebhookWithManager registers the ClusterPolicy webhooks with the manager
+func (v *ClusterPolicyValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&ClusterPolicy{}).
+		WithDefaulter(&ClusterPolicyDefaulter{}).
+		WithValidator(v).
+		Complete()
+}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *ClusterPolicyValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	cp, ok := obj.(*ClusterPolicy)
+	if !ok {
+		return fmt.Errorf("expected a ClusterPolicy but got %T", obj)
+	}
+	clusterpolicylog.Info("validate create", "name", cp.Name)
+
+	allErrs := v.validateSpec(&cp.Spec)
+
+	// ClusterPolicy is a singleton
+	list := &ClusterPolicyList{}
+	if err := v.Client.List(ctx, list); err != nil {
+		return apierrors.NewInternalError(err)
+	}
+	for _, item := range list.Items {
+		if item.Name != cp.Name && item.DeletionTimestamp.IsZero() {
+			allErrs = append(allErrs, field.Forbidden(field.NewPath("metadata", "name"),
+				fmt.Sprintf("ClusterPolicy %s already exists, only one ClusterPolicy is allowed", item.Name)))
+			break
+		}
+	}
+
+	return toInvalidError(cp, allErrs)
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *ClusterPolicyValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	cp, ok := newObj.(*ClusterPolicy)
+	if !ok {
+		return fmt.Errorf("expected a ClusterPolicy but got %T", newObj)
+	}
+	clusterpolicylog.Info("validate update", "name", cp.Name)
+
+	// skip validation of the spec when only metadata or status is updated, e.g. on deletion
+	if old, ok := oldObj.(*ClusterPolicy); ok && old.Generation == cp.Generation {
+		return nil
+	}
+
+	return toInvalidError(cp, v.validateSpec(&cp.Spec))
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (v *ClusterPolicyValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// toInvalidError converts field errors to an Invalid error of ClusterPolicy
+func toInvalidError(cp *ClusterPolicy, allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(GroupVersion.WithKind("ClusterPolicy").GroupKind(), cp.Name, allErrs)
+}
+
+// validateSpec validates the FPGA node labels, images of all components and the host setup of each OS distribution
+func (v *ClusterPolicyValidator) validateSpec(spec *ClusterPolicySpec) field.ErrorList {
+	allErrs := field.ErrorList{}
+	specPath := field.NewPath("spec")
+
+	allErrs = append(allErrs, metav1validation.ValidateLabels(spec.FPGANodes.NodeSelector,
+		specPath.Child("fpgaNodes", "nodeSelector"))...)
+
+	if spec.NFD.IsEnabled() {
+		allErrs = append(allErrs, validateImage(specPath.Child("nfd"), spec.NFD.Repository, spec.NFD.Image, spec.NFD.Tag)...)
+	}
+
+	if spec.NodeLabeller.IsEnabled() {
+		allErrs = append(allErrs, validateImage(specPath.Child("nodeLabeller"),
+			spec.NodeLabeller.Repository, spec.NodeLabeller.Image,
+			operatorImageTag(spec.NodeLabeller.Image, spec.NodeLabeller.Tag))...)
+	}
+
+	if spec.ContainerRuntime.IsEnabled() {
+		allErrs = append(allErrs, validateImage(specPath.Child("containerRuntime"),
+			spec.ContainerRuntime.Repository, spec.ContainerRuntime.Image, spec.ContainerRuntime.Tag)...)
+		allErrs = append(allErrs, validatePrivileged(specPath.Child("containerRuntime"),
+			&spec.ContainerRuntime.ContainerOverridesSpec)...)
+	}
+
+	if spec.DevicePlugin.IsEnabled() {
+		allErrs = append(allErrs, validateImage(specPath.Child("devicePlugin"),
+			spec.DevicePlugin.Repository, spec.DevicePlugin.Image, spec.DevicePlugin.Tag)...)
+	}
+
+	if !spec.HostSetup.IsEnabled() {
+		return allErrs
+	}
+	osDists := map[string]bool{}
+	for i, osDist := range spec.HostSetup.OsDists {
+		osDistPath := specPath.Child("hostSetup", "osDists").Index(i)
+
+		key := strings.ToLower(osDist.OsId + "/" + osDist.OsMajorVersion)
+		if osDists[key] {
+			allErrs = append(allErrs, field.Duplicate(osDistPath,
+				fmt.Sprintf("osId: %s, osMajorVersion: %s", osDist.OsId, osDist.OsMajorVersion)))
+		}
+		osDists[key] = true
+
+		allErrs = append(allErrs, validateImage(osDistPath, osDist.Repository, osDist.Image, osDist.Tag)...)
+		allErrs = append(allErrs, v.validatePlatforms(osDistPath, &osDist)...)
+		allErrs = append(allErrs, validatePrivileged(osDistPath, &osDist.ContainerOverridesSpec)...)
+		allErrs = append(allErrs, validatePrivileged(osDistPath.Child("initXrtXrm"), osDist.InitXrtXrm)...)
+		allErrs = append(allErrs, validatePrivileged(osDistPath.Child("initCardFlash"), osDist.InitCardFlash)...)
+	}
+
+	return allErrs
+}
+
+// validatePlatforms validates the version and cards are supported by host setup on the OS distribution
+func (v *ClusterPolicyValidator) validatePlatforms(path *field.Path, osDist *OsDistSetupSpec) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if v.Platforms == nil || osDist.Version == "" {
+		return allErrs
+	}
+
+	// an OS distribution unknown to host setup is set up by a custom image with its own platforms
+	key := osDistKey(osDist.OsId, osDist.OsMajorVersion)
+	if _, ok := v.Platforms[key]; !ok {
+		return allErrs
+	}
+
+	if _, ok := v.Platforms[key][osDist.Version]; !ok {
+		allErrs = append(allErrs, field.NotSupported(path.Child("version"), osDist.Version, v.Platforms.versions(key)))
+		return allErrs
+	}
+
+	for i, card := range osDist.Cards {
+		if !v.Platforms[key][osDist.Version][card] {
+			allErrs = append(allErrs, field.NotSupported(path.Child("cards").Index(i), card,
+				v.Platforms.cards(key, osDist.Version)))
+		}
+	}
+	return allErrs
+}
+
+// validatePrivileged validates the security context doesn't drop the privilege required by the container
+func validatePrivileged(path *field.Path, overrides *ContainerOverridesSpec) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if overrides == nil || overrides.SecurityContext == nil {
+		return allErrs
+	}
+	if privileged := overrides.SecurityContext.Privileged; privileged != nil && !*privileged {
+		allErrs = append(allErrs, field.Forbidden(path.Child("securityContext", "privileged"),
+			"the container requires privileged mode"))
+	}
+	return allErrs
+}
+
+// validateImage validates the image reference built by ImagePath
+func validateImage(path *field.Path, repo string, image string, tag string) field.ErrorList {
+	allErrs := field.ErrorList{}
+	if image == "" {
+		allErrs = append(allErrs, field.Required(path.Child("image"), "image is required"))
+		return allErrs
+	}
+
+	// image is the full reference if neither repository nor tag is set
+	if repo == "" && tag == "" {
+		if !isImageReference(image) {
+			allErrs = append(allErrs, field.Invalid(path.Child("image"), image, "invalid image reference"))
+		}
+		return allErrs
+	}
+
+	if !imageRegexp.MatchString(image) {
+		allErrs = append(allErrs, field.Invalid(path.Child("image"), image, "invalid image name"))
+	}
+	if repo == "" {
+		allErrs = append(allErrs, field.Required(path.Child("repository"), "repository is required when tag is set"))
+	} else if !repositoryRegexp.MatchString(repo) {
+		allErrs = append(allErrs, field.Invalid(path.Child("repository"), repo, "invalid image repository"))
+	}
+	if tag == "" {
+		allErrs = append(allErrs, field.Required(path.Child("tag"), "tag is required when repository is set"))
+	} else if !tagRegexp.MatchString(tag) && !digestRegexp.MatchString(tag) {
+		allErrs = append(allErrs, field.Invalid(path.Child("tag"), tag, "invalid image tag or digest"))
+	}
+	return allErrs
+}
+
+// isImageReference checks the image is a valid reference with optional repository, tag or digest
+func isImageReference(image string) bool {
+	name := image
+	if i := strings.Index(image, "@"); i >= 0 {
+		name = image[:i]
+		if !digestRegexp.MatchString(image[i+1:]) {
+			return false
+		}
+	} else if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		name = image[:i]
+		if !tagRegexp.MatchString(image[i+1:]) {
+			return false
+		}
+	}
+	return repositoryRegexp.MatchString(name)
+}
@@ -0,0 +1,372 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const hostSetupSpecPath = "../../hostSetup/conf/spec.txt"
+
+func newValidator(t *testing.T, objs ...runtime.Object) *ClusterPolicyValidator {
+	spec, err := os.ReadFile(hostSetupSpecPath)
+	require.NoError(t, err, "failed to read host setup spec")
+	platforms, err := ParseHostSetupPlatforms(spec)
+	require.NoError(t, err, "failed to parse host setup spec")
+
+	s := runtime.NewScheme()
+	require.NoError(t, AddToScheme(s))
+	return &ClusterPolicyValidator{
+		Client:    fake.NewClientBuilder().WithScheme(s).WithRuntimeObjects(objs...).Build(),
+		Platforms: platforms,
+	}
+}
+
+func newClusterPolicy(name string) *ClusterPolicy {
+	return &ClusterPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: ClusterPolicySpec{
+			ContainerRuntime: ContainerRuntimeSpec{
+				Repository: "public.ecr.aws/xilinx_dcg",
+				Image:      "xilinx-container-runtime",
+				Tag:        "latest",
+			},
+			DevicePlugin: DevicePluginSpec{
+				Repository: "public.ecr.aws/xilinx_dcg",
+				Image:      "k8s-device-plugin",
+				Tag:        "1.2.0",
+			},
+			HostSetup: HostSetupSpec{
+				OsDists: []OsDistSetupSpec{
+					{
+						OsId:           "ubuntu",
+						OsMajorVersion: "18",
+						Version:        "2023.1",
+						Cards:          []string{"alveo-u200", "alveo-u50"},
+						Repository:     "public.ecr.aws/xilinx_dcg",
+						Image:          "host-setup",
+						Tag:            "ubuntu18.04",
+					},
+					{
+						OsId:           "ubuntu",
+						OsMajorVersion: "20",
+						Version:        "2022.1",
+						Repository:     "public.ecr.aws/xilinx_dcg",
+						Image:          "host-setup",
+						Tag:            "ubuntu20.04",
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestParseHostSetupPlatforms(t *testing.T) {
+	v := newValidator(t)
+	require.True(t, v.Platforms["ubuntu22"]["2023.1"]["alveo-u55c"], "alveo-u55c is not supported by 2023.1 on ubuntu22")
+	require.True(t, v.Platforms["centos7"]["2019.1"]["alveo-u280"], "alveo-u280 is not supported by 2019.1 on centos7")
+	require.False(t, v.Platforms["centos7"]["2019.1"]["alveo-u200"], "alveo-u200 is supported by 2019.1 on centos7")
+	require.True(t, v.Platforms["ubuntu18"]["2021.1"]["alveo-u280"], "alveo-u280 is not supported by 2021.1 on ubuntu18")
+	require.False(t, v.Platforms["ubuntu20"]["2021.1"]["alveo-u280"], "alveo-u280 is supported by 2021.1 on ubuntu20")
+	require.NotContains(t, v.Platforms["ubuntu22"], "2022.1", "2022.1 is supported on ubuntu22")
+
+	_, err := ParseHostSetupPlatforms([]byte("alveo-u200:XRT_PACKAGE=xrt.deb;\n"))
+	require.Error(t, err, "malformed host setup spec is parsed")
+	_, err = ParseHostSetupPlatforms([]byte("alveo-u200_2023.1_ubuntu:XRT_PACKAGE=xrt.deb;\n"))
+	require.Error(t, err, "host setup spec without OS release is parsed")
+}
+
+func TestValidateCreate(t *testing.T) {
+	testCases := []struct {
+		description string
+		update      func(cp *ClusterPolicy)
+		errField    string
+	}{
+		{
+			"valid",
+			func(cp *ClusterPolicy) {},
+			"",
+		},
+		{
+			"duplicate osDists",
+			func(cp *ClusterPolicy) {
+				cp.Spec.HostSetup.OsDists[1].OsMajorVersion = "18"
+			},
+			"spec.hostSetup.osDists[1]",
+		},
+		{
+			"image without repository",
+			func(cp *ClusterPolicy) {
+				cp.Spec.DevicePlugin.Repository = ""
+			},
+			"spec.devicePlugin.repository",
+		},
+		{
+			"missing image",
+			func(cp *ClusterPolicy) {
+				cp.Spec.ContainerRuntime.Image = ""
+			},
+			"spec.containerRuntime.image",
+		},
+		{
+			"malformed node labeller image",
+			func(cp *ClusterPolicy) {
+				cp.Spec.NodeLabeller.Enabled = new(bool)
+				*cp.Spec.NodeLabeller.Enabled = true
+				cp.Spec.NodeLabeller.Image = "fpga-operator"
+				cp.Spec.NodeLabeller.Tag = "v1:0"
+			},
+			"spec.nodeLabeller.tag",
+		},
+		{
+			"node labeller of the operator version",
+			func(cp *ClusterPolicy) {
+				cp.Spec.NodeLabeller.Enabled = new(bool)
+				*cp.Spec.NodeLabeller.Enabled = true
+				cp.Spec.NodeLabeller.Repository = DefaultRepository
+				cp.Spec.NodeLabeller.Image = DefaultNodeLabellerImage
+				cp.Spec.NodeLabeller.Tag = ""
+			},
+			"",
+		},
+		{
+			"missing image of disabled component",
+			func(cp *ClusterPolicy) {
+				cp.Spec.ContainerRuntime.Enabled = new(bool)
+				cp.Spec.ContainerRuntime.Image = ""
+			},
+			"",
+		},
+		{
+			"full image reference",
+			func(cp *ClusterPolicy) {
+				cp.Spec.DevicePlugin.Repository = ""
+				cp.Spec.DevicePlugin.Image = "registry.local:5000/xilinx/k8s-device-plugin:1.2.0"
+				cp.Spec.DevicePlugin.Tag = ""
+			},
+			"",
+		},
+		{
+			"malformed full image reference",
+			func(cp *ClusterPolicy) {
+				cp.Spec.DevicePlugin.Repository = ""
+				cp.Spec.DevicePlugin.Image = "registry.local/k8s-device-plugin:"
+				cp.Spec.DevicePlugin.Tag = ""
+			},
+			"spec.devicePlugin.image",
+		},
+		{
+			"malformed tag",
+			func(cp *ClusterPolicy) {
+				cp.Spec.HostSetup.OsDists[0].Tag = "ubuntu:18.04"
+			},
+			"spec.hostSetup.osDists[0].tag",
+		},
+		{
+			"image digest",
+			func(cp *ClusterPolicy) {
+				cp.Spec.DevicePlugin.Tag = "sha256:" + strings.Repeat("a", 64)
+			},
+			"",
+		},
+		{
+			"unsupported version",
+			func(cp *ClusterPolicy) {
+				cp.Spec.HostSetup.OsDists[0].Version = "2018.3"
+			},
+			"spec.hostSetup.osDists[0].version",
+		},
+		{
+			"unsupported card",
+			func(cp *ClusterPolicy) {
+				cp.Spec.HostSetup.OsDists[0].Cards = []string{"alveo-u200", "alveo-u30"}
+			},
+			"spec.hostSetup.osDists[0].cards[1]",
+		},
+		{
+			"card unsupported by version",
+			func(cp *ClusterPolicy) {
+				cp.Spec.HostSetup.OsDists[0].Version = "2019.1"
+				cp.Spec.HostSetup.OsDists[0].Cards = []string{"alveo-u280", "alveo-u200"}
+			},
+			"spec.hostSetup.osDists[0].cards[1]",
+		},
+		{
+			"version unsupported on OS",
+			func(cp *ClusterPolicy) {
+				cp.Spec.HostSetup.OsDists[1].Version = "2019.1"
+			},
+			"spec.hostSetup.osDists[1].version",
+		},
+		{
+			"card unsupported on OS",
+			func(cp *ClusterPolicy) {
+				cp.Spec.HostSetup.OsDists[1].Version = "2021.1"
+				cp.Spec.HostSetup.OsDists[1].Cards = []string{"alveo-u280"}
+			},
+			"spec.hostSetup.osDists[1].cards[0]",
+		},
+		{
+			"OS unknown to host setup",
+			func(cp *ClusterPolicy) {
+				cp.Spec.HostSetup.OsDists[1].OsId = "rhel"
+				cp.Spec.HostSetup.OsDists[1].OsMajorVersion = "8"
+				cp.Spec.HostSetup.OsDists[1].Version = "2023.1"
+				cp.Spec.HostSetup.OsDists[1].Cards = []string{"alveo-u30"}
+			},
+			"",
+		},
+		{
+			"unprivileged init container",
+			func(cp *ClusterPolicy) {
+				cp.Spec.HostSetup.OsDists[0].InitCardFlash = &ContainerOverridesSpec{
+					SecurityContext: &corev1.SecurityContext{Privileged: new(bool)},
+				}
+			},
+			"spec.hostSetup.osDists[0].initCardFlash.securityContext.privileged",
+		},
+		{
+			"invalid FPGA node label",
+			func(cp *ClusterPolicy) {
+				cp.Spec.FPGANodes.NodeSelector = map[string]string{"example.com/fpga node": "true"}
+			},
+			"spec.fpgaNodes.nodeSelector",
+		},
+		{
+			"unprivileged device plugin",
+			func(cp *ClusterPolicy) {
+				cp.Spec.DevicePlugin.SecurityContext = &corev1.SecurityContext{Privileged: new(bool)}
+			},
+			"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			cp := newClusterPolicy("fpga-clusterpolicy")
+			tc.update(cp)
+
+			err := newValidator(t).ValidateCreate(context.TODO(), cp)
+			if tc.errField == "" {
+				require.NoError(t, err, "valid ClusterPolicy is rejected")
+				return
+			}
+			require.Error(t, err, "invalid ClusterPolicy is accepted")
+			require.Contains(t, err.Error(), tc.errField, "unexpected error")
+		})
+	}
+}
+
+func TestValidateCreateSingleton(t *testing.T) {
+	v := newValidator(t, newClusterPolicy("fpga-clusterpolicy"))
+
+	err := v.ValidateCreate(context.TODO(), newClusterPolicy("another-clusterpolicy"))
+	require.Error(t, err, "second ClusterPolicy is accepted")
+	require.Contains(t, err.Error(), "fpga-clusterpolicy already exists", "unexpected error")
+}
+
+func TestValidateUpdate(t *testing.T) {
+	v := newValidator(t)
+	old := newClusterPolicy("fpga-clusterpolicy")
+	old.Generation = 1
+
+	cp := old.DeepCopy()
+	cp.Generation = 2
+	cp.Spec.HostSetup.OsDists[0].Version = "2018.3"
+	require.Error(t, v.ValidateUpdate(context.TODO(), old, cp), "unsupported version is accepted")
+
+	// metadata update of an existing ClusterPolicy is always accepted
+	old.Spec.HostSetup.OsDists[0].Version = "2018.3"
+	cp = old.DeepCopy()
+	cp.Finalizers = []string{"policy.xilinx.com/finalizer"}
+	require.NoError(t, v.ValidateUpdate(context.TODO(), old, cp), "metadata update is rejected")
+}
+
+func TestDefault(t *testing.T) {
+	cp := &ClusterPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "fpga-clusterpolicy"},
+		Spec: ClusterPolicySpec{
+			DevicePlugin: DevicePluginSpec{
+				Image: "registry.local/k8s-device-plugin:1.0.0",
+			},
+			HostSetup: HostSetupSpec{
+				OsDists: []OsDistSetupSpec{
+					{OsId: "ubuntu", OsMajorVersion: "20"},
+					{OsId: "rhel", OsMajorVersion: "8", Tag: "rhel8", XrmInstallation: new(bool)},
+				},
+			},
+		},
+	}
+	require.NoError(t, (&ClusterPolicyDefaulter{}).Default(context.TODO(), cp))
+
+	spec := cp.Spec
+	require.Equal(t, Containerd, spec.Operator.DefaultRuntime)
+
+	require.False(t, *spec.NFD.Enabled)
+	require.Equal(t, "registry.k8s.io/nfd/node-feature-discovery:v0.12.0",
+		ImagePath(spec.NFD.Repository, spec.NFD.Image, spec.NFD.Tag))
+
+	require.False(t, *spec.NodeLabeller.Enabled)
+	// the tag of the operator image is the version of the operator, which is not materialized
+	require.Empty(t, spec.NodeLabeller.Tag)
+	require.Equal(t, "public.ecr.aws/xilinx_dcg/fpga-operator:"+OperatorVersion,
+		OperatorImagePath(spec.NodeLabeller.Repository, spec.NodeLabeller.Image, spec.NodeLabeller.Tag))
+	require.Empty(t, spec.HostSetup.Reporter.Tag)
+	require.Equal(t, "public.ecr.aws/xilinx_dcg/fpga-operator:"+OperatorVersion,
+		OperatorImagePath(spec.HostSetup.Reporter.Repository, spec.HostSetup.Reporter.Image, spec.HostSetup.Reporter.Tag))
+
+	require.True(t, *spec.ContainerRuntime.Enabled)
+	require.False(t, *spec.ContainerRuntime.SetAsDefault)
+	require.Equal(t, DefaultRuntimeClass, spec.ContainerRuntime.RuntimeClass)
+	require.Equal(t, DefaultXCRInstallDir, spec.ContainerRuntime.InstallDir)
+	require.Equal(t, "public.ecr.aws/xilinx_dcg/xilinx-container-runtime:latest",
+		ImagePath(spec.ContainerRuntime.Repository, spec.ContainerRuntime.Image, spec.ContainerRuntime.Tag))
+	require.Equal(t, DefaultImagePullPolicy, spec.ContainerRuntime.ImagePullPolicy)
+
+	// custom image is kept as it is
+	require.True(t, *spec.DevicePlugin.Enabled)
+	require.Equal(t, "registry.local/k8s-device-plugin:1.0.0",
+		ImagePath(spec.DevicePlugin.Repository, spec.DevicePlugin.Image, spec.DevicePlugin.Tag))
+
+	require.True(t, *spec.HostSetup.Enabled)
+	require.Equal(t, DefaultProgressDeadlineSeconds, *spec.HostSetup.ProgressDeadlineSeconds)
+	require.Equal(t, HostSetupDaemonSet, spec.HostSetup.Mode)
+	ubuntu := spec.HostSetup.OsDists[0]
+	require.Equal(t, DefaultHostSetupVersion, ubuntu.Version)
+	require.True(t, *ubuntu.XrmInstallation)
+	require.True(t, *ubuntu.ShellFlashEnabled)
+	require.NotNil(t, ubuntu.Cards)
+	require.Equal(t, "public.ecr.aws/xilinx_dcg/host-setup:ubuntu20.04",
+		ImagePath(ubuntu.Repository, ubuntu.Image, ubuntu.Tag))
+
+	rhel := spec.HostSetup.OsDists[1]
+	require.False(t, *rhel.XrmInstallation)
+	require.Equal(t, "public.ecr.aws/xilinx_dcg/host-setup:rhel8",
+		ImagePath(rhel.Repository, rhel.Image, rhel.Tag))
+
+	// defaulted ClusterPolicy is valid
+	require.NoError(t, newValidator(t).ValidateCreate(context.TODO(), cp))
+}
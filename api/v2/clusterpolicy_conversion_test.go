@@ -0,0 +1,78 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2_test
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/xilinx/fpga-operator/api/v1"
+	v2 "github.com/xilinx/fpga-operator/api/v2"
+)
+
+// blank assignment to verify that v2 ClusterPolicy is the conversion hub of v1
+var (
+	_ conversion.Hub         = &v2.ClusterPolicy{}
+	_ conversion.Convertible = &v1.ClusterPolicy{}
+)
+
+// requireJSONEqual fails if the objects are serialized differently, eg. an empty list and nil are equal
+func requireJSONEqual(t *testing.T, expected, actual interface{}, msg string) {
+	expectedJSON, err := json.Marshal(expected)
+	require.NoError(t, err)
+	actualJSON, err := json.Marshal(actual)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expectedJSON), string(actualJSON), msg)
+}
+
+// readSample reads the sample ClusterPolicy of the version, failing on the fields unknown to the API
+func readSample(t *testing.T, version string, cp interface{}) {
+	data, err := os.ReadFile("../../config/samples/policy_" + version + "_clusterpolicy.yaml")
+	require.NoError(t, err)
+	require.NoError(t, yaml.UnmarshalStrict(data, cp), "sample does not match the %s API", version)
+}
+
+func TestSampleRoundTrip(t *testing.T) {
+	hub := &v2.ClusterPolicy{}
+	readSample(t, "v2", hub)
+
+	// v2 -> v1 -> v2
+	spoke := &v1.ClusterPolicy{}
+	require.NoError(t, spoke.ConvertFrom(hub.DeepCopy()), "failed to convert from v2")
+	dst := &v2.ClusterPolicy{}
+	require.NoError(t, spoke.ConvertTo(dst), "failed to convert to v2")
+	// the TypeMeta is set by the conversion webhook, not by ConvertTo
+	dst.TypeMeta = hub.TypeMeta
+	requireJSONEqual(t, hub, dst, "v2 is changed by round-trip conversion")
+}
+
+func TestSamplesEquivalent(t *testing.T) {
+	hub := &v2.ClusterPolicy{}
+	readSample(t, "v2", hub)
+	spoke := &v1.ClusterPolicy{}
+	readSample(t, "v1", spoke)
+
+	// the samples of both versions describe the same ClusterPolicy
+	converted := &v2.ClusterPolicy{}
+	require.NoError(t, spoke.ConvertTo(converted), "failed to convert to v2")
+	requireJSONEqual(t, hub.Spec, converted.Spec, "samples of v1 and v2 differ")
+}
@@ -0,0 +1,505 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Docker runtime
+	Docker Runtime = "docker"
+	// Containerd runtime
+	Containerd Runtime = "containerd"
+)
+
+// Runtime defines container runtime type
+type Runtime string
+
+const (
+	// HostSetupDaemonSet runs host-setup in the init containers of a DaemonSet per OS distribution
+	HostSetupDaemonSet HostSetupMode = "daemonset"
+	// HostSetupJob runs host-setup in a Job per node, re-run once the setup desired on the node changes
+	HostSetupJob HostSetupMode = "job"
+)
+
+// HostSetupMode defines how host-setup is run on the nodes
+type HostSetupMode string
+
+// GlobalSpec defines the settings shared by all components
+type GlobalSpec struct {
+	// Container runtime used in case it is not detected
+	// +kubebuilder:validation:Enum=docker;containerd
+	// +kubebuilder:default=containerd
+	DefaultRuntime Runtime `json:"defaultRuntime"`
+
+	// Image pull secrets used by all components
+	// +kubebuilder:validation:Optional
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+
+	// Name of the ConfigMap in the operator namespace overriding the asset files of the states
+	// +kubebuilder:validation:Optional
+	AssetsConfigMap string `json:"assetsConfigMap,omitempty"`
+}
+
+// ComponentImageSpec defines the image of a component
+type ComponentImageSpec struct {
+	// Image repo
+	// +kubebuilder:validation:Optional
+	Repository string `json:"repository,omitempty"`
+
+	// Image name
+	// +kubebuilder:validation:Pattern=[a-zA-Z0-9\-]+
+	Name string `json:"name,omitempty"`
+
+	// Image tag or digest
+	// +kubebuilder:validation:Optional
+	Tag string `json:"tag,omitempty"`
+
+	// Image pull policy
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Always;Never;IfNotPresent
+	PullPolicy string `json:"pullPolicy,omitempty"`
+}
+
+// PCIDeviceSpec matches a PCI device labeled by NFD, eg. feature.node.kubernetes.io/pci-1200_10ee.present
+type PCIDeviceSpec struct {
+	// PCI vendor ID, eg. 10ee
+	// +kubebuilder:validation:Pattern=`^[0-9a-f]{4}$`
+	Vendor string `json:"vendor"`
+
+	// Optional: PCI device class, eg. 1200
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[0-9a-f]{4}$`
+	Class string `json:"class,omitempty"`
+
+	// Optional: PCI device ID, only matched if NFD is configured to label the device ID
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^[0-9a-f]{4}$`
+	Device string `json:"device,omitempty"`
+}
+
+// FPGANodeSpec defines how the nodes with FPGA(s) are detected
+type FPGANodeSpec struct {
+	// Optional: PCI devices of FPGA, a node with any of the devices is an FPGA node
+	// +kubebuilder:validation:Optional
+	PCIDevices []PCIDeviceSpec `json:"pciDevices,omitempty"`
+
+	// Optional: Labels of the FPGA nodes, used instead of pciDevices if set
+	// +kubebuilder:validation:Optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+}
+
+// PodSchedulingSpec defines the scheduling and extra metadata of the pods of a component
+type PodSchedulingSpec struct {
+	// Optional: Node selector merged into the node selector of the pods
+	// +kubebuilder:validation:Optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// Optional: Affinity of the pods
+	// +kubebuilder:validation:Optional
+	Affinity *corev1.Affinity `json:"affinity,omitempty"`
+
+	// Optional: Tolerations appended to the tolerations of the pods
+	// +kubebuilder:validation:Optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// Optional: Priority class of the pods
+	// +kubebuilder:validation:Optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// Optional: Extra labels of the pods
+	// +kubebuilder:validation:Optional
+	PodLabels map[string]string `json:"podLabels,omitempty"`
+
+	// Optional: Extra annotations of the pods
+	// +kubebuilder:validation:Optional
+	PodAnnotations map[string]string `json:"podAnnotations,omitempty"`
+}
+
+// ContainerOverridesSpec defines the resources and security context overrides of a container
+type ContainerOverridesSpec struct {
+	// Optional: Resource requests and limits of the container
+	// +kubebuilder:validation:Optional
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+
+	// Optional: Security context merged into the security context of the container
+	// +kubebuilder:validation:Optional
+	SecurityContext *corev1.SecurityContext `json:"securityContext,omitempty"`
+}
+
+// NFDSpec defines Node Feature Discovery deployed by the operator
+type NFDSpec struct {
+	// Enabled indicates if deployment of NFD worker and master through operator is enabled
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// NFD image
+	// +kubebuilder:validation:Optional
+	Image ComponentImageSpec `json:"image,omitempty"`
+
+	// Optional: seconds for the DaemonSets to roll out before the state is reported as degraded
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+}
+
+// NodeLabellerSpec defines the node labeller publishing the OS and FPGA labels of the nodes
+type NodeLabellerSpec struct {
+	// Enabled indicates if deployment of node labeller through operator is enabled
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// node labeller image
+	// +kubebuilder:validation:Optional
+	Image ComponentImageSpec `json:"image,omitempty"`
+
+	// Optional: seconds for the DaemonSets to roll out before the state is reported as degraded
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
+	// Scheduling and extra metadata of the pods
+	PodSchedulingSpec `json:",inline"`
+
+	// Resources and security context of the main container
+	ContainerOverridesSpec `json:",inline"`
+}
+
+type ContainerRuntimeSpec struct {
+	// Enabled indicates if deployment of Xilinx Container Toolkit through operator is enabled
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Xilinx Container Toolkit image
+	// +kubebuilder:validation:Optional
+	Image ComponentImageSpec `json:"image,omitempty"`
+
+	// Optional: seconds for the DaemonSets to roll out before the state is reported as degraded
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
+	// +kubebuilder:default=xilinx
+	RuntimeClass string `json:"runtimeClass,omitempty"`
+
+	// set as default
+	SetAsDefault *bool `json:"setAsDefault,omitempty"`
+
+	// XCR install directory on the host
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default=/usr/bin
+	InstallDir string `json:"installDir,omitempty"`
+
+	// Optional: List of arguments
+	Args []string `json:"args,omitempty"`
+
+	// Optional: List of environment variables
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Scheduling and extra metadata of the pods
+	PodSchedulingSpec `json:",inline"`
+
+	// Resources and security context of the main container
+	ContainerOverridesSpec `json:",inline"`
+}
+
+type DevicePluginSpec struct {
+	// Enabled indicates if deployment of device-plugin through operator is enabled
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// device-plugin image
+	// +kubebuilder:validation:Optional
+	Image ComponentImageSpec `json:"image,omitempty"`
+
+	// Optional: seconds for the DaemonSets to roll out before the state is reported as degraded
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
+	// Optional: List of environment variables
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Scheduling and extra metadata of the pods
+	PodSchedulingSpec `json:",inline"`
+
+	// Resources and security context of the main container
+	ContainerOverridesSpec `json:",inline"`
+}
+
+// XRTSpec defines the XRT setup on the host
+type XRTSpec struct {
+	// The version to be setup
+	Version string `json:"version,omitempty"`
+
+	// XRM installation enabled
+	XrmInstallation *bool `json:"xrmInstallation,omitempty"`
+}
+
+// CardSelector selects the cards to be flashed on the host
+type CardSelector struct {
+	// Platform of the cards, eg. alveo-u200
+	// +kubebuilder:validation:Pattern=`^alveo-[a-z0-9]+$`
+	Platform string `json:"platform"`
+}
+
+// ShellFlashSpec defines the shell flash of the cards on the host
+type ShellFlashSpec struct {
+	// shell flash enabled
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Cards to be flashed, empty to flash all cards
+	// +kubebuilder:validation:Optional
+	Cards []CardSelector `json:"cards,omitempty"`
+}
+
+type OsDistSetupSpec struct {
+	// OS distribution, eg. ubuntu, centos
+	// +kubebuilder:validation:Enum=ubuntu;centos;amzn;rhel
+	OsId string `json:"osId"`
+
+	// OS major version, eg. 18, 20
+	OsMajorVersion string `json:"osMajorVersion"`
+
+	// host-setup image
+	// +kubebuilder:validation:Optional
+	Image ComponentImageSpec `json:"image,omitempty"`
+
+	// XRT setup
+	// +kubebuilder:validation:Optional
+	XRT XRTSpec `json:"xrt,omitempty"`
+
+	// Shell flash of the cards
+	// +kubebuilder:validation:Optional
+	ShellFlash ShellFlashSpec `json:"shellFlash,omitempty"`
+
+	// Scheduling and extra metadata of the pods
+	PodSchedulingSpec `json:",inline"`
+
+	// Resources and security context of the main container
+	ContainerOverridesSpec `json:",inline"`
+
+	// Optional: Resources and security context of the init-xrt-xrm init container
+	// +kubebuilder:validation:Optional
+	InitXrtXrm *ContainerOverridesSpec `json:"initXrtXrm,omitempty"`
+
+	// Optional: Resources and security context of the init-card-flash init container
+	// +kubebuilder:validation:Optional
+	InitCardFlash *ContainerOverridesSpec `json:"initCardFlash,omitempty"`
+}
+
+type HostSetupSpec struct {
+	// Enabled indicates if deployment of host-setup through operator is enabled
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// Setup per os distributions, eg. ubuntu18, ubuntu20
+	OsDists []OsDistSetupSpec `json:"osDists"`
+
+	// Optional: seconds for the DaemonSets to roll out before the state is reported as degraded
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Minimum=1
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
+	// Optional: run host-setup in a DaemonSet per OS distribution, or in a Job per node
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=daemonset;job
+	Mode HostSetupMode `json:"mode,omitempty"`
+
+	// Optional: sidecar reporting the setup of each node in the node annotations
+	// +kubebuilder:validation:Optional
+	Reporter HostSetupReporterSpec `json:"reporter,omitempty"`
+}
+
+// HostSetupReporterSpec defines the sidecar of host-setup, which reports the XRT and XRM installed,
+// the shells flashed and the result of the last setup in the annotations of its node
+type HostSetupReporterSpec struct {
+	// Enabled indicates if the reporter is deployed with host-setup
+	Enabled *bool `json:"enabled,omitempty"`
+
+	// reporter image
+	// +kubebuilder:validation:Optional
+	Image ComponentImageSpec `json:"image,omitempty"`
+}
+
+// ClusterPolicySpec defines the desired state of ClusterPolicy
+type ClusterPolicySpec struct {
+	// Global settings shared by all components
+	Global GlobalSpec `json:"global"`
+
+	// NFD component spec
+	// +kubebuilder:validation:Optional
+	NFD NFDSpec `json:"nfd,omitempty"`
+
+	// NodeLabeller component spec
+	// +kubebuilder:validation:Optional
+	NodeLabeller NodeLabellerSpec `json:"nodeLabeller,omitempty"`
+
+	// ContainerRuntime component spec
+	ContainerRuntime ContainerRuntimeSpec `json:"containerRuntime"`
+
+	// DevicePlugin component spec
+	DevicePlugin DevicePluginSpec `json:"devicePlugin"`
+
+	// HostSetup component spec
+	HostSetup HostSetupSpec `json:"hostSetup"`
+
+	// Detection of the nodes with FPGA(s)
+	// +kubebuilder:validation:Optional
+	FPGANodes FPGANodeSpec `json:"fpgaNodes,omitempty"`
+}
+
+// State indicates state of FPGA operator components
+type State string
+
+const (
+	// Ignored indicates duplicate ClusterPolicy instances and rest are ignored.
+	Ignored State = "ignored"
+	// Ready indicates all components of ClusterPolicy are ready
+	Ready State = "ready"
+	// NotReady indicates some/all components of ClusterPolicy are not ready
+	NotReady State = "notReady"
+	// Disabled indicates if the state is disabled
+	Disabled State = "disabled"
+	// NoMatchingNodes indicates no node matches the DaemonSets of the state
+	NoMatchingNodes State = "noMatchingNodes"
+)
+
+// DaemonSetStatus indicates rollout status of a DaemonSet deployed by the operator
+type DaemonSetStatus struct {
+	// Name of the DaemonSet
+	Name string `json:"name"`
+	// NodeSelector identifies the set of nodes the DaemonSet is deployed on
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// DesiredNumberScheduled is the number of nodes that should be running the DaemonSet pod
+	DesiredNumberScheduled int32 `json:"desiredNumberScheduled"`
+	// NumberReady is the number of nodes running a ready DaemonSet pod
+	NumberReady int32 `json:"numberReady"`
+	// NumberUnavailable is the number of nodes without an available DaemonSet pod
+	NumberUnavailable int32 `json:"numberUnavailable"`
+	// UpdatedNumberScheduled is the number of nodes running the updated DaemonSet pod
+	// +optional
+	UpdatedNumberScheduled int32 `json:"updatedNumberScheduled,omitempty"`
+	// NumberAvailable is the number of nodes running an available DaemonSet pod
+	// +optional
+	NumberAvailable int32 `json:"numberAvailable,omitempty"`
+}
+
+// DeploymentStatus indicates rollout status of a Deployment deployed by the operator
+type DeploymentStatus struct {
+	// Name of the Deployment
+	Name string `json:"name"`
+	// Replicas is the number of pods targeted by the Deployment
+	Replicas int32 `json:"replicas"`
+	// UnavailableReplicas is the number of pods of the Deployment not available
+	// +optional
+	UnavailableReplicas int32 `json:"unavailableReplicas,omitempty"`
+}
+
+// ComponentStatus indicates status of a single state, eg. state-device-plugin
+type ComponentStatus struct {
+	// Name of the state
+	Name string `json:"name"`
+	// +kubebuilder:validation:Enum=ignored;ready;notReady;disabled;noMatchingNodes
+	// State indicates status of the state
+	State State `json:"state"`
+	// Reason is a brief CamelCase reason for the state
+	Reason string `json:"reason,omitempty"`
+	// Message is a human readable description of the state
+	Message string `json:"message,omitempty"`
+	// LastTransitionTime is the last time the state changed
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+	// DaemonSets indicates rollout status of DaemonSets deployed for the state
+	DaemonSets []DaemonSetStatus `json:"daemonSets,omitempty"`
+	// Deployments indicates rollout status of Deployments deployed for the state
+	// +optional
+	Deployments []DeploymentStatus `json:"deployments,omitempty"`
+	// SkippedObjects are the objects of the state not created as their kinds are not installed,
+	// in the format of <kind>/<name>, eg. ServiceMonitor/device-plugin
+	// +optional
+	SkippedObjects []string `json:"skippedObjects,omitempty"`
+}
+
+// HostSetupNodeStatus indicates the setup of a node reported by host-setup in the node annotations
+type HostSetupNodeStatus struct {
+	// Node is the name of the node
+	Node string `json:"node"`
+	// XRTVersion is the version of XRT installed by host-setup
+	// +optional
+	XRTVersion string `json:"xrtVersion,omitempty"`
+	// XRMInstalled indicates if XRM is installed by host-setup
+	// +optional
+	XRMInstalled bool `json:"xrmInstalled,omitempty"`
+	// Shells are the shells flashed on the FPGA cards, keyed by the PCI address of the card, eg. 0000:3b:00
+	// +optional
+	Shells map[string]string `json:"shells,omitempty"`
+	// LastSetupTime is the time the last setup completed
+	// +optional
+	LastSetupTime *metav1.Time `json:"lastSetupTime,omitempty"`
+	// LastSetupResult is the result of the last setup, Succeeded or Failed
+	// +optional
+	LastSetupResult string `json:"lastSetupResult,omitempty"`
+}
+
+// ClusterPolicyStatus defines the observed state of ClusterPolicy
+type ClusterPolicyStatus struct {
+	// +kubebuilder:validation:Enum=ignored;ready;notReady;disabled
+	// State indicates status of ClusterPolicy
+	State State `json:"state"`
+	// Namespace indicates a namespace in which the operator is installed
+	Namespace string `json:"namespace,omitempty"`
+	// Conditions indicates the latest available observations of ClusterPolicy
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Components indicates status of each state of ClusterPolicy
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Components []ComponentStatus `json:"components,omitempty"`
+	// HostSetupNodes indicates the setup of each node reported by host-setup
+	// +optional
+	// +listType=map
+	// +listMapKey=node
+	HostSetupNodes []HostSetupNodeStatus `json:"hostSetupNodes,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:storageversion
+
+// ClusterPolicy is the Schema for the clusterpolicies API
+type ClusterPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterPolicySpec   `json:"spec,omitempty"`
+	Status ClusterPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterPolicyList contains a list of ClusterPolicy
+type ClusterPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterPolicy{}, &ClusterPolicyList{})
+}
@@ -0,0 +1,100 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/bombsimon/logrusr/v3"
+	"github.com/go-logr/logr"
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/kubernetes"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	"github.com/xilinx/fpga-operator/labeller"
+)
+
+func main() {
+	var hostRoot string
+	var interval time.Duration
+	var oneshot bool
+	devices := labeller.PCIDevices{}
+	flag.StringVar(&hostRoot, "host-root", "/host", "The directory the host file system is mounted at.")
+	flag.DurationVar(&interval, "interval", time.Minute, "The interval between two reports of the node.")
+	flag.BoolVar(&oneshot, "oneshot", false, "Report the node once and exit.")
+	flag.Var(&devices, "pci-device", "PCI devices of FPGA in the format of <vendor>[:<class>[:<device>]], "+
+		"separated by comma or repeated. (default 10ee:1200)")
+	flag.Parse()
+
+	logger := logrusr.New(logrus.New()).WithName("host-setup-reporter")
+
+	if len(devices) == 0 {
+		devices = labeller.PCIDevices{{Vendor: "10ee", Class: "1200"}}
+	}
+
+	nodeName := os.Getenv("NODE_NAME")
+	if nodeName == "" {
+		logger.Error(nil, "NODE_NAME environment variable not set")
+		os.Exit(1)
+	}
+
+	clientset, err := kubernetes.NewForConfig(ctrl.GetConfigOrDie())
+	if err != nil {
+		logger.Error(err, "unable to create Kubernetes client")
+		os.Exit(1)
+	}
+
+	l := labeller.New(hostRoot, devices)
+	ctx := ctrl.SetupSignalHandler()
+	logger.Info("Reporting host setup", "node", nodeName)
+	for {
+		if err := report(ctx, l, clientset, nodeName, logger); err != nil {
+			logger.Error(err, "unable to report host setup", "node", nodeName)
+			if oneshot {
+				os.Exit(1)
+			}
+		}
+		if oneshot {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// report reads the setup of the node, and updates the node annotations if they are changed
+func report(ctx context.Context, l *labeller.Labeller, clientset kubernetes.Interface, nodeName string, logger logr.Logger) error {
+	annotations, err := l.SetupAnnotations()
+	if err != nil {
+		return err
+	}
+	updated, err := labeller.UpdateNodeAnnotations(ctx, clientset, nodeName, annotations)
+	if err != nil {
+		return err
+	}
+	if updated {
+		logger.Info("Node annotations updated", "node", nodeName, "annotations", annotations)
+	}
+	return nil
+}
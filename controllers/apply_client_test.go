@@ -0,0 +1,147 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// applyClient emulates server-side apply by a single field manager on the fake client, which handles an apply
+// patch as a strategic merge patch: the fields applied before and no longer applied are removed, the lists
+// applied are replaced, a dry-run apply returns the result without writing it, and an apply changing nothing
+// makes no write
+type applyClient struct {
+	client.Client
+
+	mu sync.Mutex
+	// applied is the last configuration applied to each object
+	applied map[string]map[string]interface{}
+}
+
+func newApplyClient(c client.Client) *applyClient {
+	return &applyClient{Client: c, applied: map[string]map[string]interface{}{}}
+}
+
+func (c *applyClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if patch.Type() != types.ApplyPatchType {
+		return c.Client.Patch(ctx, obj, patch, opts...)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	options := &client.PatchOptions{}
+	options.ApplyOptions(opts)
+	dryRun := len(options.DryRun) > 0
+
+	desired, err := toUnstructured(obj)
+	if err != nil {
+		return err
+	}
+	// the status and the metadata set by the server are not applied
+	delete(desired, "status")
+	metadata, _ := desired["metadata"].(map[string]interface{})
+	for key := range metadata {
+		switch key {
+		case "name", "namespace", "labels", "annotations", "ownerReferences":
+		default:
+			delete(metadata, key)
+		}
+	}
+
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	key := gvk.String() + "/" + obj.GetNamespace() + "/" + obj.GetName()
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(gvk)
+	err = c.Client.Get(ctx, client.ObjectKeyFromObject(obj), live)
+	if errors.IsNotFound(err) {
+		if dryRun {
+			return nil
+		}
+		if err := c.Client.Create(ctx, obj); err != nil {
+			return err
+		}
+		c.applied[key] = desired
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	result := live.DeepCopy().Object
+	pruneApplied(result, c.applied[key], desired)
+	mergeApplied(result, desired)
+	if !dryRun {
+		c.applied[key] = desired
+		before, _ := json.Marshal(live.Object)
+		after, _ := json.Marshal(result)
+		if string(before) != string(after) {
+			updated := &unstructured.Unstructured{Object: result}
+			if err := c.Client.Update(ctx, updated); err != nil {
+				return err
+			}
+			result = updated.Object
+		}
+	}
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		u.Object = result
+		return nil
+	}
+	value := reflect.ValueOf(obj).Elem()
+	value.Set(reflect.Zero(value.Type()))
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(result, obj)
+}
+
+// pruneApplied removes the fields of live which were in the previous configuration applied, but not in desired
+func pruneApplied(live, previous, desired map[string]interface{}) {
+	for key, value := range previous {
+		d, ok := desired[key]
+		if !ok {
+			delete(live, key)
+			continue
+		}
+		p, isMap := value.(map[string]interface{})
+		dm, desiredMap := d.(map[string]interface{})
+		lm, liveMap := live[key].(map[string]interface{})
+		if isMap && desiredMap && liveMap {
+			pruneApplied(lm, p, dm)
+		}
+	}
+}
+
+// mergeApplied sets the fields of desired in live, the maps are merged and the other values replaced
+func mergeApplied(live, desired map[string]interface{}) {
+	for key, value := range desired {
+		if value == nil {
+			continue
+		}
+		dm, desiredMap := value.(map[string]interface{})
+		lm, liveMap := live[key].(map[string]interface{})
+		if desiredMap && liveMap {
+			mergeApplied(lm, dm)
+			continue
+		}
+		live[key] = runtime.DeepCopyJSONValue(value)
+	}
+}
@@ -18,40 +18,71 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"io/fs"
+	"strings"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	"github.com/go-logr/logr"
 	policyv1 "github.com/xilinx/fpga-operator/api/v1"
+	"github.com/xilinx/fpga-operator/labeller"
 )
 
 const (
 	minDelayCR   = 100 * time.Millisecond
 	maxDelayCR   = 30 * time.Second
 	requeueDealy = 10 * time.Second
+	// maxConcurrentReconciles is 1, as there is one active ClusterPolicy, whose reconciliations are serialized
+	// by the workqueue anyway, and the duplicates are only marked ignored
+	maxConcurrentReconciles = 1
+
+	// ClusterPolicyFinalizer is the finalizer to teardown components before ClusterPolicy is deleted
+	ClusterPolicyFinalizer = "policy.xilinx.com/finalizer"
+
+	nfdLabelOSReleasePrefix = "feature.node.kubernetes.io/system-os_release"
 )
 
 // blank assignment to verify that ReconcileClusterPolicy implements reconcile.Reconciler
 var _ reconcile.Reconciler = &ClusterPolicyReconciler{}
-var clusterPolicyCtrl ClusterPolicyController
 
 // ClusterPolicyReconciler reconciles a ClusterPolicy object
 type ClusterPolicyReconciler struct {
 	client.Client
-	Log    logr.Logger
-	Scheme *runtime.Scheme
+	Log      logr.Logger
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// Assets are the embedded asset files in a directory per state
+	Assets fs.FS
+	// AssetsDir is the directory of the asset files overriding the embedded ones, empty if not set
+	AssetsDir string
+	// Clientset reads the logs of failing pods, which the controller-runtime client does not support
+	Clientset kubernetes.Interface
+
+	// ctrl holds the states shared by the reconciliations
+	ctrl ClusterPolicyController
 }
 
 //+kubebuilder:rbac:groups=policy.xilinx.com,resources=clusterpolicies,verbs=get;list;watch;create;update;patch;delete
@@ -60,8 +91,13 @@ type ClusterPolicyReconciler struct {
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles;clusterrolebindings;roles;rolebindings,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=namespaces;serviceaccounts;pods;services;services/finalizers;endpoints,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=persistentvolumeclaims;events;configmaps;secrets;nodes,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=pods/log,verbs=get
 //+kubebuilder:rbac:groups=apps,resources=deployments;daemonsets;replicasets;statefulsets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=node.k8s.io,resources=runtimeclasses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=nfd.k8s-sigs.io,resources=nodefeatures;nodefeaturerules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -77,14 +113,24 @@ func (r *ClusterPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	_ = context.Background()
 	_ = r.Log.WithValues("Reconciling ClusterPolicy", req.NamespacedName)
 
+	start := time.Now()
+	defer func() {
+		if metrics := r.ctrl.metrics(); metrics != nil {
+			metrics.reconciliationTotal.Inc()
+			metrics.reconciliationDuration.Observe(time.Since(start).Seconds())
+		}
+	}()
+
 	// fetch the ClusterPolicy instance
 	instance := &policyv1.ClusterPolicy{}
 	err := r.Client.Get(context.TODO(), req.NamespacedName, instance)
 	if err != nil {
-		// clusterPolicyCtrl.operatorMetrics.reconciliationStatus.Set(reconciliationStatusClusterPolicyUnavailable)
+		if metrics := r.ctrl.metrics(); metrics != nil {
+			metrics.reconciliationStatus.Set(reconciliationStatusClusterPolicyUnavailable)
+		}
 		if errors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
-			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
+			// Owned objects are automatically garbage collected.
 			// Return and don't requeue
 			return reconcile.Result{}, nil
 		}
@@ -92,92 +138,389 @@ func (r *ClusterPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return reconcile.Result{}, err
 	}
 
-	// TODO: Handle deletion of the main ClusterPolicy and cycle to the next one.
+	// Handle deletion of ClusterPolicy
+	if !instance.ObjectMeta.DeletionTimestamp.IsZero() {
+		return r.finalize(instance)
+	}
+
 	// We already have a main Clusterpolicy
-	if clusterPolicyCtrl.singleton != nil && clusterPolicyCtrl.singleton.ObjectMeta.Name != instance.ObjectMeta.Name {
-		instance.SetStatus(policyv1.Ignored, clusterPolicyCtrl.operatorNamespace)
-		// spurious reconciliation
+	active, err := r.activeClusterPolicy()
+	if err != nil {
 		return ctrl.Result{}, err
 	}
+	if active != nil && active.ObjectMeta.Name != instance.ObjectMeta.Name {
+		r.Log.Info("Ignoring duplicate ClusterPolicy", "Name", instance.ObjectMeta.Name, "Active", active.ObjectMeta.Name)
+		return ctrl.Result{}, r.markIgnored(instance, active.ObjectMeta.Name)
+	}
 
-	err = clusterPolicyCtrl.init(r, instance)
+	// Add finalizer to the main ClusterPolicy to teardown the components on deletion
+	if !controllerutil.ContainsFinalizer(instance, ClusterPolicyFinalizer) {
+		controllerutil.AddFinalizer(instance, ClusterPolicyFinalizer)
+		err = r.Client.Update(context.TODO(), instance)
+		if err != nil {
+			r.Log.Error(err, "Failed to add finalizer to ClusterPolicy")
+			return ctrl.Result{}, err
+		}
+	}
+
+	n, err := r.newControlContext(instance)
 	if err != nil {
 		r.Log.Error(err, "Failed to initialize ClusterPolicy controller")
 
-		// if clusterPolicyCtrl.operatorMetrics != nil {
-		// 	clusterPolicyCtrl.operatorMetrics.reconciliationStatus.Set(reconciliationStatusClusterPolicyUnavailable)
-		// }
+		if metrics := r.ctrl.metrics(); metrics != nil {
+			metrics.reconciliationStatus.Set(reconciliationStatusClusterOperatorError)
+			metrics.reconciliationFailed.Inc()
+		}
+		_ = updateCRStatus(r, nil, req.NamespacedName, policyv1.NotReady, nil, err)
 		return ctrl.Result{}, err
 	}
 
 	// perform the oprator steps
 	overallStatus := policyv1.Ready
 	statesNotReady := []string{}
-	for {
-		status, statusError := clusterPolicyCtrl.step()
+	components := []policyv1.ComponentStatus{}
+	for idx, st := range n.states {
+		status, statusError := n.step(idx)
+		component := n.componentStatus(idx, status, statusError)
+		n.checkProgress(idx, &component, instance.Status.Components, time.Now())
+		components = append(components, component)
+		n.operatorMetrics.setStateStatus(st.name, status)
 		if statusError != nil {
-			updateCRState(r, req.NamespacedName, policyv1.NotReady)
+			n.operatorMetrics.reconciliationStatus.Set(reconciliationStatusClusterOperatorError)
+			n.operatorMetrics.reconciliationFailed.Inc()
+			recordEvent(*n, nil, corev1.EventTypeWarning, policyv1.ReasonReconcileFailed,
+				fmt.Sprintf("Failed to reconcile state %s: %v", st.name, statusError))
+			_ = updateCRStatus(r, n, req.NamespacedName, policyv1.NotReady, components, statusError)
 			return ctrl.Result{RequeueAfter: requeueDealy}, statusError
 		}
 		if status == policyv1.NotReady {
-			// if CR was previously set to ready(prior reboot etc), reset it to current state
-			if instance.Status.State == policyv1.Ready {
-				updateCRState(r, req.NamespacedName, policyv1.NotReady)
-			}
 			overallStatus = policyv1.NotReady
-			statesNotReady = append(statesNotReady, clusterPolicyCtrl.stateNames[clusterPolicyCtrl.idx-1])
+			statesNotReady = append(statesNotReady, st.name)
 		}
 		r.Log.Info("ClusterPolicy step completed",
-			"state", clusterPolicyCtrl.stateNames[clusterPolicyCtrl.idx-1],
+			"state", st.name,
 			"status", status)
-
-		if clusterPolicyCtrl.last() {
-			break
-		}
 	}
 
+	// update CR status with the overall state and status of each state
+	_ = updateCRStatus(r, n, req.NamespacedName, overallStatus, components, nil)
+
 	// if any state is not ready, requeue for reconfile after 5 seconds
 	if overallStatus != policyv1.Ready {
 
 		r.Log.Info("ClusterPolicy isn't ready", "states not ready", statesNotReady)
+		// the event is recorded once the states become not ready, not on every requeue
+		message := fmt.Sprintf("States not ready: %s", strings.Join(statesNotReady, ", "))
+		if !wasNotReady(&instance.Status, message) {
+			recordEvent(*n, nil, corev1.EventTypeWarning, EventReasonNotReady, message)
+		}
+		n.operatorMetrics.reconciliationStatus.Set(reconciliationStatusNotReady)
 
 		return ctrl.Result{RequeueAfter: requeueDealy}, nil
 	}
 
-	// Update CR state as ready as all states are complete
-	updateCRState(r, req.NamespacedName, policyv1.Ready)
+	n.operatorMetrics.reconciliationStatus.Set(reconciliationStatusSuccess)
+	n.operatorMetrics.reconciliationLastSuccess.Set(float64(time.Now().Unix()))
 	return ctrl.Result{}, nil
 }
 
-func updateCRState(r *ClusterPolicyReconciler, namespacedName types.NamespacedName, state policyv1.State) error {
-	// Fetch latest instance and update state to avoid version mismatch
+// finalize tears down the components deployed for the main ClusterPolicy in reverse state order,
+// and then promotes the next ClusterPolicy as the main one
+func (r *ClusterPolicyReconciler) finalize(instance *policyv1.ClusterPolicy) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(instance, ClusterPolicyFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	// only the main ClusterPolicy has components deployed
+	active, err := r.activeClusterPolicy()
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	isMain := active == nil || active.ObjectMeta.Name == instance.ObjectMeta.Name
+	if isMain {
+		n, err := r.newControlContext(instance)
+		if err != nil {
+			r.Log.Error(err, "Failed to initialize ClusterPolicy controller for teardown")
+			return ctrl.Result{}, err
+		}
+
+		r.Log.Info("Tearing down ClusterPolicy", "Name", instance.ObjectMeta.Name)
+		done, err := n.teardown()
+		if err != nil {
+			r.Log.Error(err, "Failed to teardown ClusterPolicy")
+			recordEvent(*n, nil, corev1.EventTypeWarning, EventReasonTeardown,
+				fmt.Sprintf("Failed to teardown ClusterPolicy: %v", err))
+			return ctrl.Result{}, err
+		}
+		if !done {
+			return ctrl.Result{RequeueAfter: requeueDealy}, nil
+		}
+	}
+
+	controllerutil.RemoveFinalizer(instance, ClusterPolicyFinalizer)
+	err = r.Client.Update(context.TODO(), instance)
+	if err != nil {
+		r.Log.Error(err, "Failed to remove finalizer from ClusterPolicy")
+		return ctrl.Result{}, err
+	}
+
+	if !isMain {
+		return ctrl.Result{}, nil
+	}
+	r.Log.Info("ClusterPolicy teardown completed", "Name", instance.ObjectMeta.Name)
+	return ctrl.Result{}, r.promoteNextClusterPolicy(instance.ObjectMeta.Name)
+}
+
+// activeClusterPolicy returns the main ClusterPolicy, which is the oldest one by creationTimestamp.
+// ClusterPolicies being deleted are skipped unless they still hold the finalizer for teardown.
+func (r *ClusterPolicyReconciler) activeClusterPolicy() (*policyv1.ClusterPolicy, error) {
+	list := &policyv1.ClusterPolicyList{}
+	err := r.Client.List(context.TODO(), list)
+	if err != nil {
+		r.Log.Error(err, "Failed to list ClusterPolicies")
+		return nil, err
+	}
+	return oldestClusterPolicy(list.Items), nil
+}
+
+// oldestClusterPolicy returns the oldest ClusterPolicy, with the name to break ties
+func oldestClusterPolicy(items []policyv1.ClusterPolicy) *policyv1.ClusterPolicy {
+	var oldest *policyv1.ClusterPolicy
+	for i := range items {
+		cp := &items[i]
+		if !cp.ObjectMeta.DeletionTimestamp.IsZero() && !controllerutil.ContainsFinalizer(cp, ClusterPolicyFinalizer) {
+			continue
+		}
+		if oldest == nil {
+			oldest = cp
+			continue
+		}
+		if cp.ObjectMeta.CreationTimestamp.Equal(&oldest.ObjectMeta.CreationTimestamp) {
+			if cp.ObjectMeta.Name < oldest.ObjectMeta.Name {
+				oldest = cp
+			}
+			continue
+		}
+		if cp.ObjectMeta.CreationTimestamp.Before(&oldest.ObjectMeta.CreationTimestamp) {
+			oldest = cp
+		}
+	}
+	return oldest
+}
+
+// markIgnored persists the ignored state of a duplicate ClusterPolicy, with a condition naming the active one
+func (r *ClusterPolicyReconciler) markIgnored(instance *policyv1.ClusterPolicy, active string) error {
+	status := instance.Status.DeepCopy()
+	status.State = policyv1.Ignored
+	status.Namespace = r.ctrl.namespace()
+	status.Components = nil
+
+	message := fmt.Sprintf("ClusterPolicy %s is already active, %s is ignored", active, instance.ObjectMeta.Name)
+	for _, conditionType := range []string{policyv1.ConditionReady, policyv1.ConditionProgressing, policyv1.ConditionDegraded} {
+		condition := metav1.Condition{
+			Type:               conditionType,
+			Status:             metav1.ConditionFalse,
+			Reason:             policyv1.ReasonDuplicateClusterPolicy,
+			ObservedGeneration: instance.Generation,
+		}
+		if conditionType == policyv1.ConditionReady {
+			condition.Message = message
+		}
+		meta.SetStatusCondition(&status.Conditions, condition)
+	}
+
+	if equality.Semantic.DeepEqual(&instance.Status, status) {
+		// status is unchanged
+		return nil
+	}
+	instance.Status = *status
+	err := r.Client.Status().Update(context.TODO(), instance)
+	if err != nil {
+		r.Log.Error(err, "Failed to update ClusterPolicy status")
+		return err
+	}
+	if r.Recorder != nil {
+		r.Recorder.Event(instance, corev1.EventTypeWarning, policyv1.ReasonDuplicateClusterPolicy, message)
+	}
+	return nil
+}
+
+// promoteNextClusterPolicy triggers reconciliation of the oldest ClusterPolicy left,
+// so it becomes the main ClusterPolicy
+func (r *ClusterPolicyReconciler) promoteNextClusterPolicy(deleted string) error {
+	list := &policyv1.ClusterPolicyList{}
+	err := r.Client.List(context.TODO(), list)
+	if err != nil {
+		r.Log.Error(err, "Failed to list ClusterPolicies")
+		return err
+	}
+
+	// the cache may still have the deleted ClusterPolicy
+	items := []policyv1.ClusterPolicy{}
+	for _, cp := range list.Items {
+		if cp.ObjectMeta.Name != deleted {
+			items = append(items, cp)
+		}
+	}
+	next := oldestClusterPolicy(items)
+	if next == nil || !next.ObjectMeta.DeletionTimestamp.IsZero() {
+		return nil
+	}
+
+	// updating the status triggers reconciliation of the ClusterPolicy
+	r.Log.Info("Promoting ClusterPolicy", "Name", next.ObjectMeta.Name)
+	next.SetStatus(policyv1.NotReady, r.ctrl.namespace())
+	err = r.Client.Status().Update(context.TODO(), next)
+	if err != nil {
+		r.Log.Error(err, "Failed to update ClusterPolicy status")
+		return err
+	}
+	return nil
+}
+
+// updateCRStatus updates state, conditions and per-state status of ClusterPolicy.
+// n is the context of the reconciliation, nil if the controller failed to initialize
+func updateCRStatus(r *ClusterPolicyReconciler, n *ControlContext, namespacedName types.NamespacedName, state policyv1.State,
+	components []policyv1.ComponentStatus, reconcileErr error) error {
+	// Fetch latest instance and update status to avoid version mismatch
 	instance := &policyv1.ClusterPolicy{}
 	err := r.Client.Get(context.TODO(), namespacedName, instance)
 	if err != nil {
 		r.Log.Error(err, "Failed to get ClusterPolicy instance for status update")
 		return err
 	}
-	if instance.Status.State == state {
-		// state is unchanged
+
+	status := instance.Status.DeepCopy()
+	status.State = state
+	status.Namespace = r.ctrl.namespace()
+	if components != nil {
+		status.Components = components
+	}
+	setStatusConditions(status, instance.Generation, reconcileErr)
+	nfdMissing := false
+	if n != nil {
+		spec := &instance.Spec
+		nfdMissing = setNFDCondition(status, instance.Generation, n.hasNFDLabels,
+			spec.NFD.IsEnabled(), spec.NodeLabeller.IsEnabled())
+		nodes, err := n.hostSetupNodeStatuses()
+		if err != nil {
+			r.Log.Error(err, "Failed to get the setup of nodes for status update")
+		} else {
+			status.HostSetupNodes = nodes
+		}
+	}
+
+	if equality.Semantic.DeepEqual(&instance.Status, status) {
+		// status is unchanged
 		return nil
 	}
-	// Update the CR state
-	instance.SetStatus(state, clusterPolicyCtrl.operatorNamespace)
+	// Update the CR status
+	instance.Status = *status
 	err = r.Client.Status().Update(context.TODO(), instance)
 	if err != nil {
 		r.Log.Error(err, "Failed to update ClusterPolicy status")
 		return err
 	}
+	if nfdMissing && r.Recorder != nil {
+		condition := meta.FindStatusCondition(status.Conditions, policyv1.ConditionNodeFeatureDiscoveryMissing)
+		r.Recorder.Event(instance, corev1.EventTypeWarning, condition.Reason, condition.Message)
+	}
 	return nil
 }
 
+// setNFDCondition sets NodeFeatureDiscoveryMissing condition, which is true if no node has NFD labels
+// and the node labeller is disabled. It returns true if the condition becomes true
+func setNFDCondition(status *policyv1.ClusterPolicyStatus, generation int64, hasNFDLabels, nfd, nodeLabeller bool) bool {
+	condition := metav1.Condition{
+		Type:               policyv1.ConditionNodeFeatureDiscoveryMissing,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+	}
+	switch {
+	case nodeLabeller:
+		condition.Reason = policyv1.ReasonNodeLabellerEnabled
+		condition.Message = "FPGA nodes are detected by the built-in node labeller"
+	case hasNFDLabels:
+		condition.Reason = policyv1.ReasonNodeFeatureLabelsFound
+	case nfd:
+		condition.Status, condition.Reason = metav1.ConditionTrue, policyv1.ReasonNoNodeFeatureLabels
+		condition.Message = "No node is labeled by Node Feature Discovery deployed by the operator yet"
+	default:
+		condition.Status, condition.Reason = metav1.ConditionTrue, policyv1.ReasonNoNodeFeatureLabels
+		condition.Message = "No node is labeled by Node Feature Discovery, FPGA nodes are not detected. " +
+			"Deploy Node Feature Discovery, or set nfd.enabled or nodeLabeller.enabled in ClusterPolicy"
+	}
+
+	wasMissing := meta.IsStatusConditionTrue(status.Conditions, condition.Type)
+	meta.SetStatusCondition(&status.Conditions, condition)
+	return condition.Status == metav1.ConditionTrue && !wasMissing
+}
+
+// wasNotReady returns true if the previous Ready condition reports the same states not ready
+func wasNotReady(status *policyv1.ClusterPolicyStatus, message string) bool {
+	ready := meta.FindStatusCondition(status.Conditions, policyv1.ConditionReady)
+	return ready != nil && ready.Status == metav1.ConditionFalse &&
+		ready.Reason == policyv1.ReasonStatesNotReady && ready.Message == message
+}
+
+// setStatusConditions sets Ready, Progressing and Degraded conditions as per the overall state
+func setStatusConditions(status *policyv1.ClusterPolicyStatus, generation int64, reconcileErr error) {
+	ready := metav1.Condition{Type: policyv1.ConditionReady, ObservedGeneration: generation}
+	progressing := metav1.Condition{Type: policyv1.ConditionProgressing, ObservedGeneration: generation}
+	degraded := metav1.Condition{Type: policyv1.ConditionDegraded, ObservedGeneration: generation}
+
+	statesNotReady := []string{}
+	statesExceeded := []string{}
+	for _, component := range status.Components {
+		if component.State == policyv1.NotReady {
+			statesNotReady = append(statesNotReady, component.Name)
+		}
+		if component.Reason == policyv1.ReasonProgressDeadlineExceeded {
+			statesExceeded = append(statesExceeded, fmt.Sprintf("%s: %s", component.Name, component.Message))
+		}
+	}
+
+	switch {
+	case reconcileErr != nil:
+		reason := policyv1.ReasonReconcileFailed
+		if isAssetsError(reconcileErr) {
+			reason = policyv1.ReasonAssetsLoadFailed
+		}
+		ready.Status, ready.Reason = metav1.ConditionFalse, reason
+		ready.Message = reconcileErr.Error()
+		progressing.Status, progressing.Reason = metav1.ConditionFalse, reason
+		degraded.Status, degraded.Reason = metav1.ConditionTrue, reason
+		degraded.Message = reconcileErr.Error()
+	case status.State == policyv1.Ready:
+		ready.Status, ready.Reason = metav1.ConditionTrue, policyv1.ReasonAllStatesReady
+		ready.Message = "All states are ready"
+		progressing.Status, progressing.Reason = metav1.ConditionFalse, policyv1.ReasonAllStatesReady
+		degraded.Status, degraded.Reason = metav1.ConditionFalse, policyv1.ReasonAllStatesReady
+	default:
+		message := fmt.Sprintf("States not ready: %s", strings.Join(statesNotReady, ", "))
+		ready.Status, ready.Reason = metav1.ConditionFalse, policyv1.ReasonStatesNotReady
+		ready.Message = message
+		progressing.Status, progressing.Reason = metav1.ConditionTrue, policyv1.ReasonStatesNotReady
+		progressing.Message = message
+		degraded.Status, degraded.Reason = metav1.ConditionFalse, policyv1.ReasonStatesNotReady
+		if len(statesExceeded) > 0 {
+			degraded.Status, degraded.Reason = metav1.ConditionTrue, policyv1.ReasonProgressDeadlineExceeded
+			degraded.Message = strings.Join(statesExceeded, "\n")
+		}
+	}
+
+	meta.SetStatusCondition(&status.Conditions, ready)
+	meta.SetStatusCondition(&status.Conditions, progressing)
+	meta.SetStatusCondition(&status.Conditions, degraded)
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ClusterPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	// create a new controller
 	c, err := controller.New("clusterpolicy-controller", mgr,
 		controller.Options{
 			Reconciler:              r,
-			MaxConcurrentReconciles: 1,
+			MaxConcurrentReconciles: maxConcurrentReconciles,
 			RateLimiter:             workqueue.NewItemExponentialFailureRateLimiter(minDelayCR, maxDelayCR),
 		},
 	)
@@ -200,5 +543,113 @@ func (r *ClusterPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		return err
 	}
 
+	// Watch for changes to secondary resource Jobs of host-setup and requeue the owner ClusterPolicy
+	err = c.Watch(&source.Kind{Type: &batchv1.Job{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &policyv1.ClusterPolicy{},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to nodes affecting the FPGA nodes, OS distributions or container runtime,
+	// and requeue the main ClusterPolicy
+	err = c.Watch(&source.Kind{Type: &corev1.Node{}},
+		handler.EnqueueRequestsFromMapFunc(r.mapToClusterPolicy), r.nodePredicate())
+	if err != nil {
+		return err
+	}
+
+	// Watch for changes to secondary resource Deployments and requeue the owner ClusterPolicy
+	err = c.Watch(&source.Kind{Type: &appsv1.Deployment{}}, &handler.EnqueueRequestForOwner{
+		IsController: true,
+		OwnerType:    &policyv1.ClusterPolicy{},
+	})
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// mapToClusterPolicy maps an object to the request of the main ClusterPolicy
+func (r *ClusterPolicyReconciler) mapToClusterPolicy(obj client.Object) []reconcile.Request {
+	active, err := r.activeClusterPolicy()
+	if err != nil || active == nil {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: active.ObjectMeta.Name}}}
+}
+
+// nodePredicate filters the node events, so the updates of unrelated labels, annotations and node status are ignored.
+// Nodes joining or leaving the cluster always change the OS distributions
+func (r *ClusterPolicyReconciler) nodePredicate() predicate.Predicate {
+	return predicate.Funcs{
+		CreateFunc: func(e event.CreateEvent) bool { return true },
+		DeleteFunc: func(e event.DeleteEvent) bool { return true },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldNode, ok := e.ObjectOld.(*corev1.Node)
+			if !ok {
+				return false
+			}
+			newNode, ok := e.ObjectNew.(*corev1.Node)
+			if !ok {
+				return false
+			}
+			return r.nodeChanged(oldNode, newNode)
+		},
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+	}
+}
+
+// nodeChanged returns true if the container runtime, the setup reported by host-setup,
+// or any label of FPGA nodes or OS release is changed
+func (r *ClusterPolicyReconciler) nodeChanged(oldNode, newNode *corev1.Node) bool {
+	if oldNode.Status.NodeInfo.ContainerRuntimeVersion != newNode.Status.NodeInfo.ContainerRuntimeVersion {
+		return true
+	}
+	for _, key := range changedLabels(oldNode.GetAnnotations(), newNode.GetAnnotations()) {
+		if strings.HasPrefix(key, labeller.SetupAnnotationPrefix) {
+			return true
+		}
+	}
+
+	changed := changedLabels(oldNode.GetLabels(), newNode.GetLabels())
+	for _, key := range changed {
+		if strings.HasPrefix(key, nfdLabelPCIPrefix) || strings.HasPrefix(key, nfdLabelOSReleasePrefix) ||
+			strings.HasPrefix(key, labeller.LabelPrefix) {
+			return true
+		}
+	}
+	if len(changed) == 0 {
+		return false
+	}
+
+	// FPGA nodes may be selected by arbitrary labels of the main ClusterPolicy
+	active, err := r.activeClusterPolicy()
+	if err != nil || active == nil {
+		return false
+	}
+	for _, key := range changed {
+		if _, ok := active.Spec.FPGANodes.NodeSelector[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// changedLabels returns the keys of the labels or annotations added, removed or updated
+func changedLabels(oldLabels, newLabels map[string]string) []string {
+	changed := []string{}
+	for key, value := range newLabels {
+		if oldValue, ok := oldLabels[key]; !ok || oldValue != value {
+			changed = append(changed, key)
+		}
+	}
+	for key := range oldLabels {
+		if _, ok := newLabels[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
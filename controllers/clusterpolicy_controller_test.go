@@ -0,0 +1,361 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	policyv1 "github.com/xilinx/fpga-operator/api/v1"
+	"github.com/xilinx/fpga-operator/labeller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestSetStatusConditions(t *testing.T) {
+	testCases := []struct {
+		description  string
+		state        policyv1.State
+		components   []policyv1.ComponentStatus
+		reconcileErr error
+		ready        metav1.ConditionStatus
+		progressing  metav1.ConditionStatus
+		degraded     metav1.ConditionStatus
+		readyMessage string
+	}{
+		{
+			"ready",
+			policyv1.Ready,
+			[]policyv1.ComponentStatus{
+				{Name: "state-container-runtime", State: policyv1.Ready},
+				{Name: "state-device-plugin", State: policyv1.Ready},
+			},
+			nil,
+			metav1.ConditionTrue,
+			metav1.ConditionFalse,
+			metav1.ConditionFalse,
+			"All states are ready",
+		},
+		{
+			"not ready",
+			policyv1.NotReady,
+			[]policyv1.ComponentStatus{
+				{Name: "state-container-runtime", State: policyv1.Ready},
+				{Name: "state-device-plugin", State: policyv1.NotReady},
+				{Name: "state-host-setup", State: policyv1.NotReady},
+			},
+			nil,
+			metav1.ConditionFalse,
+			metav1.ConditionTrue,
+			metav1.ConditionFalse,
+			"States not ready: state-device-plugin, state-host-setup",
+		},
+		{
+			"progress deadline exceeded",
+			policyv1.NotReady,
+			[]policyv1.ComponentStatus{
+				{Name: "state-container-runtime", State: policyv1.Ready},
+				{Name: "state-host-setup", State: policyv1.NotReady, Reason: policyv1.ReasonProgressDeadlineExceeded,
+					Message: "DaemonSets not ready within 10m0s: pod host-setup-x2k9p on node fpga-node: init container init-xrt-xrm CrashLoopBackOff"},
+			},
+			nil,
+			metav1.ConditionFalse,
+			metav1.ConditionTrue,
+			metav1.ConditionTrue,
+			"States not ready: state-host-setup",
+		},
+		{
+			"reconcile failed",
+			policyv1.NotReady,
+			[]policyv1.ComponentStatus{
+				{Name: "state-container-runtime", State: policyv1.NotReady},
+			},
+			fmt.Errorf("failed to create RuntimeClass"),
+			metav1.ConditionFalse,
+			metav1.ConditionFalse,
+			metav1.ConditionTrue,
+			"failed to create RuntimeClass",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			status := &policyv1.ClusterPolicyStatus{State: tc.state, Components: tc.components}
+			setStatusConditions(status, 2, tc.reconcileErr)
+
+			ready := meta.FindStatusCondition(status.Conditions, policyv1.ConditionReady)
+			require.NotNil(t, ready, "Ready condition not set")
+			require.Equal(t, tc.ready, ready.Status, "unexpected Ready condition status")
+			require.Equal(t, tc.readyMessage, ready.Message, "unexpected Ready condition message")
+			require.Equal(t, int64(2), ready.ObservedGeneration, "unexpected observed generation")
+
+			progressing := meta.FindStatusCondition(status.Conditions, policyv1.ConditionProgressing)
+			require.NotNil(t, progressing, "Progressing condition not set")
+			require.Equal(t, tc.progressing, progressing.Status, "unexpected Progressing condition status")
+
+			degraded := meta.FindStatusCondition(status.Conditions, policyv1.ConditionDegraded)
+			require.NotNil(t, degraded, "Degraded condition not set")
+			require.Equal(t, tc.degraded, degraded.Status, "unexpected Degraded condition status")
+		})
+	}
+}
+
+func TestWasNotReady(t *testing.T) {
+	notReady := metav1.Condition{Type: policyv1.ConditionReady, Status: metav1.ConditionFalse,
+		Reason: policyv1.ReasonStatesNotReady, Message: "States not ready: state-host-setup"}
+	failed := metav1.Condition{Type: policyv1.ConditionReady, Status: metav1.ConditionFalse,
+		Reason: policyv1.ReasonReconcileFailed, Message: "failed to create RuntimeClass"}
+	ready := metav1.Condition{Type: policyv1.ConditionReady, Status: metav1.ConditionTrue,
+		Reason: policyv1.ReasonAllStatesReady, Message: "All states are ready"}
+
+	testCases := []struct {
+		description string
+		conditions  []metav1.Condition
+		message     string
+		expected    bool
+	}{
+		{"no previous condition", nil, "States not ready: state-host-setup", false},
+		{"previously ready", []metav1.Condition{ready}, "States not ready: state-host-setup", false},
+		{"previously failed", []metav1.Condition{failed}, "States not ready: state-host-setup", false},
+		{"same states not ready", []metav1.Condition{notReady}, "States not ready: state-host-setup", true},
+		{"other states not ready", []metav1.Condition{notReady}, "States not ready: state-device-plugin, state-host-setup", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			status := &policyv1.ClusterPolicyStatus{Conditions: tc.conditions}
+			require.Equal(t, tc.expected, wasNotReady(status, tc.message))
+		})
+	}
+}
+
+func TestSetNFDCondition(t *testing.T) {
+	testCases := []struct {
+		description  string
+		hasNFDLabels bool
+		nfd          bool
+		nodeLabeller bool
+		expected     metav1.ConditionStatus
+		reason       string
+	}{
+		{"no NFD labels", false, false, false, metav1.ConditionTrue, policyv1.ReasonNoNodeFeatureLabels},
+		{"NFD labels found", true, false, false, metav1.ConditionFalse, policyv1.ReasonNodeFeatureLabelsFound},
+		{"NFD deployed by operator", false, true, false, metav1.ConditionTrue, policyv1.ReasonNoNodeFeatureLabels},
+		{"node labeller enabled", false, false, true, metav1.ConditionFalse, policyv1.ReasonNodeLabellerEnabled},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			status := &policyv1.ClusterPolicyStatus{}
+			changed := setNFDCondition(status, 2, tc.hasNFDLabels, tc.nfd, tc.nodeLabeller)
+			require.Equal(t, tc.expected == metav1.ConditionTrue, changed, "unexpected transition of condition")
+
+			condition := meta.FindStatusCondition(status.Conditions, policyv1.ConditionNodeFeatureDiscoveryMissing)
+			require.NotNil(t, condition, "NodeFeatureDiscoveryMissing condition not set")
+			require.Equal(t, tc.expected, condition.Status, "unexpected condition status")
+			require.Equal(t, tc.reason, condition.Reason, "unexpected condition reason")
+
+			changed = setNFDCondition(status, 2, tc.hasNFDLabels, tc.nfd, tc.nodeLabeller)
+			require.False(t, changed, "condition transition reported twice")
+		})
+	}
+}
+
+func TestNodePredicate(t *testing.T) {
+	cp := clusterPolicy.DeepCopy()
+	cp.ObjectMeta.ResourceVersion = ""
+	cp.Spec.FPGANodes.NodeSelector = map[string]string{"example.com/fpga": "true"}
+	r := &ClusterPolicyReconciler{
+		Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(cp).Build(),
+		Log:    ctrl.Log.WithName("test"),
+	}
+
+	newNode := func(labels map[string]string, runtimeVersion string) *corev1.Node {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Labels: labels}}
+		node.Status.NodeInfo.ContainerRuntimeVersion = runtimeVersion
+		return node
+	}
+	baseLabels := map[string]string{
+		"kubernetes.io/hostname": "node",
+		nfdLabelOSReleaseID:      "ubuntu",
+		nfdLabelOSVersionID:      "20.04",
+	}
+	withLabel := func(key, value string) map[string]string {
+		labels := map[string]string{}
+		for k, v := range baseLabels {
+			labels[k] = v
+		}
+		if value == "" {
+			delete(labels, key)
+		} else {
+			labels[key] = value
+		}
+		return labels
+	}
+
+	testCases := []struct {
+		description string
+		newNode     *corev1.Node
+		expected    bool
+	}{
+		{"unchanged", newNode(baseLabels, "containerd://1.6.8"), false},
+		{"unrelated label", newNode(withLabel("example.com/rack", "a1"), "containerd://1.6.8"), false},
+		{"OS upgraded", newNode(withLabel(nfdLabelOSVersionID, "22.04"), "containerd://1.6.8"), true},
+		{"OS label removed", newNode(withLabel(nfdLabelOSReleaseID, ""), "containerd://1.6.8"), true},
+		{"FPGA labeled by NFD", newNode(withLabel("feature.node.kubernetes.io/pci-1200_10ee.present", "true"), "containerd://1.6.8"), true},
+		{"FPGA labeled by node labeller", newNode(withLabel(labeller.LabelFPGAPresent, "true"), "containerd://1.6.8"), true},
+		{"FPGA node selector", newNode(withLabel("example.com/fpga", "true"), "containerd://1.6.8"), true},
+		{"container runtime upgraded", newNode(baseLabels, "containerd://1.7.0"), true},
+	}
+
+	p := r.nodePredicate()
+	oldNode := newNode(baseLabels, "containerd://1.6.8")
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			require.Equal(t, tc.expected, p.Update(event.UpdateEvent{ObjectOld: oldNode, ObjectNew: tc.newNode}),
+				"unexpected result of node update")
+		})
+	}
+
+	require.True(t, p.Create(event.CreateEvent{Object: oldNode}), "node joining the cluster is ignored")
+	require.True(t, p.Delete(event.DeleteEvent{Object: oldNode}), "node leaving the cluster is ignored")
+
+	requests := r.mapToClusterPolicy(oldNode)
+	require.Len(t, requests, 1, "node is not mapped to ClusterPolicy")
+	require.Equal(t, cp.ObjectMeta.Name, requests[0].Name, "node is not mapped to the main ClusterPolicy")
+}
+
+func TestOldestClusterPolicy(t *testing.T) {
+	now := metav1.Now()
+	later := metav1.NewTime(now.Add(time.Minute))
+
+	newClusterPolicy := func(name string, created metav1.Time, deleting bool, finalizer bool) policyv1.ClusterPolicy {
+		cp := policyv1.ClusterPolicy{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: created}}
+		if deleting {
+			cp.ObjectMeta.DeletionTimestamp = &later
+		}
+		if finalizer {
+			cp.ObjectMeta.Finalizers = []string{ClusterPolicyFinalizer}
+		}
+		return cp
+	}
+
+	testCases := []struct {
+		description string
+		items       []policyv1.ClusterPolicy
+		expected    string
+	}{
+		{
+			"no ClusterPolicy",
+			[]policyv1.ClusterPolicy{},
+			"",
+		},
+		{
+			"oldest ClusterPolicy",
+			[]policyv1.ClusterPolicy{
+				newClusterPolicy("cp-b", later, false, false),
+				newClusterPolicy("cp-a", now, false, false),
+			},
+			"cp-a",
+		},
+		{
+			"same creationTimestamp",
+			[]policyv1.ClusterPolicy{
+				newClusterPolicy("cp-b", now, false, false),
+				newClusterPolicy("cp-a", now, false, false),
+			},
+			"cp-a",
+		},
+		{
+			"oldest ClusterPolicy tearing down",
+			[]policyv1.ClusterPolicy{
+				newClusterPolicy("cp-b", later, false, false),
+				newClusterPolicy("cp-a", now, true, true),
+			},
+			"cp-a",
+		},
+		{
+			"oldest ClusterPolicy deleted",
+			[]policyv1.ClusterPolicy{
+				newClusterPolicy("cp-b", later, false, false),
+				newClusterPolicy("cp-a", now, true, false),
+			},
+			"cp-b",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			oldest := oldestClusterPolicy(tc.items)
+			if tc.expected == "" {
+				require.Nil(t, oldest, "unexpected ClusterPolicy")
+				return
+			}
+			require.NotNil(t, oldest, "ClusterPolicy not found")
+			require.Equal(t, tc.expected, oldest.ObjectMeta.Name, "unexpected active ClusterPolicy")
+		})
+	}
+}
+
+func TestIgnoredClusterPolicy(t *testing.T) {
+	drainEvents(eventRecorder)
+
+	duplicate := clusterPolicy.DeepCopy()
+	duplicate.ObjectMeta = metav1.ObjectMeta{
+		Name:              "duplicate-clusterpolicy",
+		CreationTimestamp: metav1.NewTime(clusterPolicy.ObjectMeta.CreationTimestamp.Add(time.Hour)),
+	}
+	err := clusterPolicyReconciler.Client.Create(context.TODO(), duplicate)
+	require.NoError(t, err, "failed to create duplicate ClusterPolicy")
+	defer func() {
+		_ = clusterPolicyReconciler.Client.Delete(context.TODO(), duplicate)
+	}()
+
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Name: duplicate.ObjectMeta.Name}}
+	_, err = clusterPolicyReconciler.Reconcile(context.TODO(), req)
+	require.NoError(t, err, "failed to reconcile duplicate ClusterPolicy")
+
+	cp := &policyv1.ClusterPolicy{}
+	err = clusterPolicyReconciler.Client.Get(context.TODO(), req.NamespacedName, cp)
+	require.NoError(t, err, "failed to get duplicate ClusterPolicy")
+	require.Equal(t, policyv1.Ignored, cp.Status.State, "unexpected state of duplicate ClusterPolicy")
+	require.Empty(t, cp.ObjectMeta.Finalizers, "unexpected finalizer on duplicate ClusterPolicy")
+
+	ready := meta.FindStatusCondition(cp.Status.Conditions, policyv1.ConditionReady)
+	require.NotNil(t, ready, "Ready condition not set")
+	require.Equal(t, policyv1.ReasonDuplicateClusterPolicy, ready.Reason, "unexpected Ready condition reason")
+	require.True(t, strings.Contains(ready.Message, clusterPolicy.ObjectMeta.Name), "active ClusterPolicy not named in condition")
+
+	events := drainEvents(eventRecorder)
+	require.Equal(t, 1, len(events), "unexpected # of events")
+	require.True(t, strings.HasPrefix(events[0], "Warning "+policyv1.ReasonDuplicateClusterPolicy), "unexpected event %s", events[0])
+
+	// status is not updated again on spurious reconciliation
+	_, err = clusterPolicyReconciler.Reconcile(context.TODO(), req)
+	require.NoError(t, err, "failed to reconcile duplicate ClusterPolicy")
+	require.Empty(t, drainEvents(eventRecorder), "unexpected event on spurious reconciliation")
+}
@@ -0,0 +1,363 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	policyv1 "github.com/xilinx/fpga-operator/api/v1"
+)
+
+const (
+	// HostSetupJobLabel is the label of the Jobs of host-setup, whose value is the DaemonSet they are run from
+	HostSetupJobLabel = "xilinx.com/host-setup-daemonset"
+	// HostSetupHashLabel is the label of the Jobs of host-setup, whose value is the hash of the setup they run
+	HostSetupHashLabel = "xilinx.com/host-setup-hash"
+	// HostSetupNodeAnnotation is the annotation of the Jobs of host-setup, whose value is the node they are run on
+	HostSetupNodeAnnotation = "xilinx.com/host-setup-node"
+	// maxJobNameLength is the max length of a Job name, as it is the value of the job-name label of its pods
+	maxJobNameLength = 63
+)
+
+// isHostSetupJobMode returns true if the state is host-setup run in a Job per node
+func (n ControlContext) isHostSetupJobMode(stateName string) bool {
+	return stateName == "state-host-setup" && n.singleton.Spec.HostSetup.IsJobMode()
+}
+
+// setupHash returns the hash of the setup of a node in the pod template, ie. the init containers
+// installing XRT and flashing the cards, so the Job of the node is re-run only once they are changed
+func setupHash(podSpec *corev1.PodSpec) (string, error) {
+	data, err := json.Marshal(podSpec.InitContainers)
+	if err != nil {
+		return "", err
+	}
+	hash := fnv.New32a()
+	hash.Write(data)
+	return fmt.Sprintf("%08x", hash.Sum32()), nil
+}
+
+// hostSetupJobHash returns the hash of the node and of the setup run on it, so the names of the Jobs
+// of the nodes are unique even once truncated
+func hostSetupJobHash(nodeName, setup string) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(nodeName + "/" + setup))
+	return fmt.Sprintf("%08x", hash.Sum32())
+}
+
+// hostSetupJob returns the Job running the pod template of the host-setup DaemonSet once on the node.
+// The Job is named after the DaemonSet, the node and the hash of both the node and the setup
+func hostSetupJob(ds *appsv1.DaemonSet, nodeName string) (*batchv1.Job, error) {
+	template := ds.Spec.Template.DeepCopy()
+	template.Spec.NodeName = nodeName
+	template.Spec.RestartPolicy = corev1.RestartPolicyOnFailure
+
+	setup, err := setupHash(&template.Spec)
+	if err != nil {
+		return nil, err
+	}
+	hash := hostSetupJobHash(nodeName, setup)
+	name := strings.TrimSuffix(ds.Name, "-daemonset") + "-" + nodeName
+	if len(name) > maxJobNameLength-len(hash)-1 {
+		name = strings.TrimRight(name[:maxJobNameLength-len(hash)-1], "-.")
+	}
+
+	labels := map[string]string{HostSetupJobLabel: ds.Name, HostSetupHashLabel: setup}
+	for key, value := range ds.Labels {
+		labels[key] = value
+	}
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name + "-" + hash,
+			Namespace:   ds.Namespace,
+			Labels:      labels,
+			Annotations: map[string]string{HostSetupNodeAnnotation: nodeName},
+		},
+		Spec: batchv1.JobSpec{Template: *template},
+	}, nil
+}
+
+// nodeToleratesPodSpec returns true if the pod tolerates the taints of the node preventing it from running there.
+// The pods of Jobs are bound to their nodes, so they are not checked by the scheduler as the pods of DaemonSets
+func nodeToleratesPodSpec(node *corev1.Node, podSpec *corev1.PodSpec) bool {
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == corev1.TaintEffectPreferNoSchedule {
+			continue
+		}
+		tolerated := false
+		for j := range podSpec.Tolerations {
+			if podSpec.Tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+// isJobComplete returns true if the Job has completed successfully
+func isJobComplete(job *batchv1.Job) bool {
+	return hasJobCondition(job, batchv1.JobComplete)
+}
+
+// hasJobCondition returns true if the condition of the Job is true
+func hasJobCondition(job *batchv1.Job, conditionType batchv1.JobConditionType) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// reconcileHostSetupJobs runs the pod template of the host-setup DaemonSet in a Job per matching node, and
+// re-runs the Job of a node once its setup is changed. The DaemonSet deployed before in daemonset mode is
+// removed first, so the setup of a node is never run twice at the same time
+func (ctrl *ControlContext) reconcileHostSetupJobs(ds *appsv1.DaemonSet) (policyv1.State, error) {
+	gone, err := ctrl.deleteDaemonSetReplaced(ds.Name)
+	if err != nil || !gone {
+		return policyv1.NotReady, err
+	}
+
+	nodes := &corev1.NodeList{}
+	err = ctrl.rec.Client.List(context.TODO(), nodes)
+	if err != nil {
+		return policyv1.NotReady, fmt.Errorf("unable to list nodes to run Jobs of %s: %v", ds.Name, err)
+	}
+
+	selectors := fpgaNodeSelectors(&ctrl.singleton.Spec.FPGANodes, ctrl.singleton.Spec.NodeLabeller.IsEnabled())
+	jobs := map[string]*batchv1.Job{}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		if !hasFPGALables(selectors, node.Labels) || !nodeMatchesPodSpec(node, &ds.Spec.Template.Spec) ||
+			!nodeToleratesPodSpec(node, &ds.Spec.Template.Spec) {
+			continue
+		}
+		job, err := hostSetupJob(ds, node.Name)
+		if err != nil {
+			return policyv1.NotReady, err
+		}
+		jobs[job.Name] = job
+	}
+
+	// the Jobs of the nodes no longer matching, or of a previous setup, are removed
+	if _, err := ctrl.deleteHostSetupJobs(ds.Name, jobs); err != nil {
+		return policyv1.NotReady, err
+	}
+	if len(jobs) == 0 {
+		return policyv1.NoMatchingNodes, nil
+	}
+
+	result := policyv1.Ready
+	for _, job := range jobs {
+		found := &batchv1.Job{}
+		err := ctrl.rec.Client.Get(context.TODO(), client.ObjectKeyFromObject(job), found)
+		if err != nil && !errors.IsNotFound(err) {
+			return policyv1.NotReady, err
+		}
+		if err == nil {
+			if !isJobComplete(found) {
+				result = policyv1.NotReady
+			}
+			continue
+		}
+
+		if err := controllerutil.SetControllerReference(ctrl.singleton, job, ctrl.rec.Scheme); err != nil {
+			return policyv1.NotReady, err
+		}
+		ctrl.rec.Log.Info("Creating Job", "Name", job.Name, "Node", job.Spec.Template.Spec.NodeName)
+		err = ctrl.rec.Client.Create(context.TODO(), job, client.FieldOwner(FieldManager))
+		if err != nil {
+			recordEvent(*ctrl, nil, corev1.EventTypeWarning, EventReasonCreateFailed,
+				fmt.Sprintf("Failed to create Job %s: %v", job.Name, err))
+			return policyv1.NotReady, err
+		}
+		recordEvent(*ctrl, job, corev1.EventTypeNormal, EventReasonCreated,
+			fmt.Sprintf("Created Job %s on node %s", job.Name, job.Spec.Template.Spec.NodeName))
+		result = policyv1.NotReady
+	}
+	return result, nil
+}
+
+// deleteDaemonSetReplaced deletes the host-setup DaemonSet replaced by Jobs, it returns true once the DaemonSet is gone
+func (ctrl *ControlContext) deleteDaemonSetReplaced(name string) (bool, error) {
+	ds := &appsv1.DaemonSet{}
+	err := ctrl.rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: ctrl.operatorNamespace, Name: name}, ds)
+	if errors.IsNotFound(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	if ds.DeletionTimestamp != nil {
+		// being deleted, wait for the pods to be removed
+		return false, nil
+	}
+
+	ctrl.rec.Log.Info("Deleting DaemonSet", "Name", name)
+	err = ctrl.rec.Client.Delete(context.TODO(), ds, client.PropagationPolicy(metav1.DeletePropagationForeground))
+	if errors.IsNotFound(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	recordEvent(*ctrl, nil, corev1.EventTypeNormal, EventReasonDeleted,
+		fmt.Sprintf("Deleted DaemonSet %s as host-setup runs in a Job per node", name))
+	return false, nil
+}
+
+// hostSetupJobs returns the Jobs run from the host-setup DaemonSet, except the ones being deleted
+func (ctrl *ControlContext) hostSetupJobs(dsName string) ([]batchv1.Job, error) {
+	list := &batchv1.JobList{}
+	err := ctrl.rec.Client.List(context.TODO(), list, client.InNamespace(ctrl.operatorNamespace),
+		client.MatchingLabels{HostSetupJobLabel: dsName})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list Jobs of %s: %v", dsName, err)
+	}
+	jobs := []batchv1.Job{}
+	for _, job := range list.Items {
+		if job.DeletionTimestamp == nil {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+// deleteHostSetupJobs deletes the Jobs run from the host-setup DaemonSet along with their pods, except the ones kept.
+// It returns true once the Jobs are gone
+func (ctrl *ControlContext) deleteHostSetupJobs(dsName string, keep map[string]*batchv1.Job) (bool, error) {
+	list := &batchv1.JobList{}
+	err := ctrl.rec.Client.List(context.TODO(), list, client.InNamespace(ctrl.operatorNamespace),
+		client.MatchingLabels{HostSetupJobLabel: dsName})
+	if err != nil {
+		return false, fmt.Errorf("unable to list Jobs of %s: %v", dsName, err)
+	}
+
+	gone := true
+	for i := range list.Items {
+		job := &list.Items[i]
+		if _, ok := keep[job.Name]; ok {
+			continue
+		}
+		gone = false
+		if job.DeletionTimestamp != nil {
+			continue
+		}
+		ctrl.rec.Log.Info("Deleting Job", "Name", job.Name)
+		err := ctrl.rec.Client.Delete(context.TODO(), job, client.PropagationPolicy(metav1.DeletePropagationForeground))
+		if err != nil && !errors.IsNotFound(err) {
+			recordEvent(*ctrl, nil, corev1.EventTypeWarning, EventReasonDeleteFailed,
+				fmt.Sprintf("Failed to delete Job %s: %v", job.Name, err))
+			return false, err
+		}
+		recordEvent(*ctrl, nil, corev1.EventTypeNormal, EventReasonDeleted, fmt.Sprintf("Deleted Job %s", job.Name))
+	}
+	return gone, nil
+}
+
+// cleanupHostSetupJobs deletes the Jobs of host-setup run from the DaemonSet, eg. once the state is disabled
+// or runs in daemonset mode. It returns true once the Jobs are gone
+func (ctrl *ControlContext) cleanupHostSetupJobs(dsName string) (bool, error) {
+	if ctrl.stateName != "state-host-setup" {
+		return true, nil
+	}
+	return ctrl.deleteHostSetupJobs(dsName, nil)
+}
+
+// hostSetupJobsProgress returns the progress of the Jobs run from the host-setup DaemonSet,
+// eg. "2/3 nodes complete, 1 failed"
+func hostSetupJobsProgress(jobs []batchv1.Job) string {
+	complete, failed := 0, 0
+	for i := range jobs {
+		if isJobComplete(&jobs[i]) {
+			complete++
+		} else if hasJobCondition(&jobs[i], batchv1.JobFailed) {
+			failed++
+		}
+	}
+	return fmt.Sprintf("%d/%d nodes complete, %d failed", complete, len(jobs), failed)
+}
+
+// isSetupComplete returns true if all the Jobs are complete
+func isSetupComplete(jobs []batchv1.Job) bool {
+	for i := range jobs {
+		if !isJobComplete(&jobs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// hostSetupJobsFailure returns the first failing pod of the Jobs run from the host-setup DaemonSet,
+// or their progress if no pod is failing. It returns false if all the Jobs are complete
+func (ctrl *ControlContext) hostSetupJobsFailure(dsName string) (string, bool) {
+	jobs, err := ctrl.hostSetupJobs(dsName)
+	if err != nil {
+		ctrl.rec.Log.Error(err, "Could not list Jobs of DaemonSet", "DaemonSet", dsName)
+		return "", false
+	}
+	if isSetupComplete(jobs) {
+		return "", false
+	}
+
+	podFailures := []string{}
+	for i := range jobs {
+		if isJobComplete(&jobs[i]) {
+			continue
+		}
+		failures, err := ctrl.diagnoseJob(&jobs[i])
+		if err != nil {
+			ctrl.rec.Log.Error(err, "Could not list pods of Job", "Job", jobs[i].Name)
+		}
+		podFailures = append(podFailures, failures...)
+	}
+	return summarizeFailures(dsName, hostSetupJobsProgress(jobs), podFailures), true
+}
+
+// jobsSetupOnNode returns the hash of the setup run by the Jobs bound to the node, or an empty hash if no Job
+// is bound to the node, eg. the Job just created is not listed yet. The setup is ready once all the Jobs are complete
+func jobsSetupOnNode(node *corev1.Node, jobs []batchv1.Job) (string, bool) {
+	hashes := []string{}
+	ready := true
+	for i := range jobs {
+		if jobs[i].Spec.Template.Spec.NodeName != node.Name {
+			continue
+		}
+		hashes = append(hashes, jobs[i].Labels[HostSetupHashLabel])
+		if !isJobComplete(&jobs[i]) {
+			ready = false
+		}
+	}
+	sort.Strings(hashes)
+	return strings.Join(hashes, ","), ready && len(hashes) > 0
+}
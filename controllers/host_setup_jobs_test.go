@@ -0,0 +1,264 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	policyv1 "github.com/xilinx/fpga-operator/api/v1"
+)
+
+func TestHostSetupJobs(t *testing.T) {
+	cp := clusterPolicy.DeepCopy()
+	cp.Spec.SetDefaults()
+	n, err := newTestContext(cp, filepath.Join(cfg.root, hostSetupAssestsPath))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := n.removeReadyLabels(); err != nil {
+			t.Fatalf("error removing node labels: %v", err)
+		}
+		if err := deleteResources(); err != nil {
+			t.Fatalf("error removing state: %v", err)
+		}
+	})
+
+	listJobs := func() map[string]batchv1.Job {
+		list := &batchv1.JobList{}
+		require.NoError(t, n.rec.Client.List(context.TODO(), list, client.InNamespace(testNamespace)))
+		jobs := map[string]batchv1.Job{}
+		for _, job := range list.Items {
+			jobs[job.Spec.Template.Spec.NodeName] = job
+		}
+		return jobs
+	}
+	countDaemonSets := func() int {
+		list := &appsv1.DaemonSetList{}
+		require.NoError(t, n.rec.Client.List(context.TODO(), list, client.InNamespace(testNamespace)))
+		return len(list.Items)
+	}
+
+	// the DaemonSets deployed in daemonset mode are removed before the Jobs are run
+	_, err = n.step(0)
+	require.NoError(t, err)
+	require.Equal(t, 4, countDaemonSets())
+	cp.Spec.HostSetup.Mode = policyv1.HostSetupJob
+	state, err := n.step(0)
+	require.NoError(t, err)
+	require.Equal(t, policyv1.NotReady, state)
+	require.Zero(t, countDaemonSets(), "DaemonSets not removed in job mode")
+	require.Empty(t, listJobs(), "Jobs run before DaemonSets are removed")
+
+	// a Job is run on each node matching an OS distribution
+	state, err = n.step(0)
+	require.NoError(t, err)
+	require.Equal(t, policyv1.NotReady, state)
+	jobs := listJobs()
+	require.Len(t, jobs, 4)
+	for _, node := range []string{"ubuntu18", "ubuntu20", "ubuntu22", "centos7"} {
+		job, ok := jobs[node]
+		require.True(t, ok, "no Job run on node %s", node)
+		require.True(t, strings.HasPrefix(job.Name, "host-setup-"), "unexpected Job name %s", job.Name)
+		require.LessOrEqual(t, len(job.Name), maxJobNameLength)
+		require.NotEmpty(t, job.Labels[HostSetupJobLabel])
+		podSpec := job.Spec.Template.Spec
+		require.Equal(t, corev1.RestartPolicyOnFailure, podSpec.RestartPolicy)
+		require.NotContains(t, podSpec.Containers[0].Args[1], "sleep", "main container of Job never completes")
+	}
+
+	component := n.componentStatus(0, state, nil)
+	require.Equal(t, policyv1.ReasonJobsNotComplete, component.Reason)
+	require.Contains(t, component.Message, "host-setup-ubuntu20-daemonset (0/1 nodes complete, 0 failed)")
+
+	// the state is ready, and the nodes are labeled, once the Jobs are complete
+	for _, job := range jobs {
+		job := job
+		job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		require.NoError(t, n.rec.Client.Status().Update(context.TODO(), &job))
+	}
+	state, err = n.step(0)
+	require.NoError(t, err)
+	require.Equal(t, policyv1.Ready, state)
+	node := &corev1.Node{}
+	require.NoError(t, n.rec.Client.Get(context.TODO(), client.ObjectKey{Name: "ubuntu20"}, node))
+	require.Equal(t, stateReadyValue, node.Labels[stateReadyLabel("state-host-setup")], "node with Job complete is not labeled")
+
+	// only the Jobs of the nodes whose setup is changed are re-run
+	for i := range cp.Spec.HostSetup.OsDists {
+		if cp.Spec.HostSetup.OsDists[i].OsMajorVersion == "20" {
+			cp.Spec.HostSetup.OsDists[i].Version = "2022.2"
+		}
+	}
+	state, err = n.step(0)
+	require.NoError(t, err)
+	require.Equal(t, policyv1.NotReady, state)
+	rerun := listJobs()
+	require.Len(t, rerun, 4)
+	for node, job := range jobs {
+		if node == "ubuntu20" {
+			job := rerun[node]
+			require.NotEqual(t, jobs[node].Name, job.Name, "Job not re-run once the setup is changed")
+			require.False(t, isJobComplete(&job))
+		} else {
+			require.Equal(t, job.Name, rerun[node].Name, "Job re-run without the setup changed")
+		}
+	}
+	require.NoError(t, n.rec.Client.Get(context.TODO(), client.ObjectKey{Name: "ubuntu20"}, node))
+	require.NotContains(t, node.Labels, stateReadyLabel("state-host-setup"), "node with Job re-run is labeled")
+
+	// the Jobs are removed before the DaemonSets are deployed back
+	cp.Spec.HostSetup.Mode = policyv1.HostSetupDaemonSet
+	_, err = n.step(0)
+	require.NoError(t, err)
+	require.Empty(t, listJobs(), "Jobs not removed in daemonset mode")
+	require.Zero(t, countDaemonSets(), "DaemonSets deployed before Jobs are removed")
+	_, err = n.step(0)
+	require.NoError(t, err)
+	require.Equal(t, 4, countDaemonSets())
+	drainEvents(eventRecorder)
+}
+
+func TestHostSetupJobsLongNodeNames(t *testing.T) {
+	cp := clusterPolicy.DeepCopy()
+	cp.Spec.SetDefaults()
+	cp.Spec.HostSetup.Mode = policyv1.HostSetupJob
+	n, err := newTestContext(cp, filepath.Join(cfg.root, hostSetupAssestsPath))
+	require.NoError(t, err)
+
+	// the nodes share a prefix longer than the Job names
+	prefix := "ubuntu20-" + strings.Repeat("rack-", 12)
+	longNodes := []string{prefix + "0", prefix + "1"}
+	for _, name := range longNodes {
+		node := &corev1.Node{}
+		require.NoError(t, n.rec.Client.Get(context.TODO(), client.ObjectKey{Name: "ubuntu20"}, node))
+		node.ObjectMeta = metav1.ObjectMeta{Name: name, Labels: node.Labels}
+		require.NoError(t, n.rec.Client.Create(context.TODO(), node))
+	}
+	t.Cleanup(func() {
+		for _, name := range longNodes {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+			if err := n.rec.Client.Delete(context.TODO(), node); err != nil {
+				t.Fatalf("error removing node: %v", err)
+			}
+		}
+		if err := deleteResources(); err != nil {
+			t.Fatalf("error removing state: %v", err)
+		}
+	})
+
+	_, err = n.step(0)
+	require.NoError(t, err)
+	list := &batchv1.JobList{}
+	require.NoError(t, n.rec.Client.List(context.TODO(), list, client.InNamespace(testNamespace),
+		client.MatchingLabels{HostSetupJobLabel: "host-setup-ubuntu20-daemonset"}))
+	jobNodes := []string{}
+	for _, job := range list.Items {
+		require.LessOrEqual(t, len(job.Name), maxJobNameLength)
+		require.Equal(t, job.Spec.Template.Spec.NodeName, job.Annotations[HostSetupNodeAnnotation])
+		jobNodes = append(jobNodes, job.Spec.Template.Spec.NodeName)
+	}
+	require.ElementsMatch(t, append(longNodes, "ubuntu20"), jobNodes, "host-setup not run on every node")
+	drainEvents(eventRecorder)
+}
+
+func TestHostSetupJob(t *testing.T) {
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "host-setup-ubuntu20-daemonset", Namespace: testNamespace,
+			Labels: map[string]string{"app": "host-setup"}},
+		Spec: appsv1.DaemonSetSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			InitContainers: []corev1.Container{{Name: "init-xrt-xrm", Args: []string{"-c", "install 2023.1"}}},
+			Containers:     []corev1.Container{{Name: "host-setup"}},
+		}}},
+	}
+	job, err := hostSetupJob(ds, "ubuntu20")
+	require.NoError(t, err)
+	require.Regexp(t, "^host-setup-ubuntu20-ubuntu20-[0-9a-f]{8}$", job.Name)
+	setup, err := setupHash(&ds.Spec.Template.Spec)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"app": "host-setup", HostSetupJobLabel: ds.Name, HostSetupHashLabel: setup},
+		job.Labels)
+	require.Equal(t, map[string]string{HostSetupNodeAnnotation: "ubuntu20"}, job.Annotations)
+	require.Equal(t, "ubuntu20", job.Spec.Template.Spec.NodeName)
+
+	// the Job name is truncated to be a label value, and is unique for the nodes sharing a long prefix
+	prefix := strings.Repeat("node-", 12)
+	long, err := hostSetupJob(ds, prefix+"0")
+	require.NoError(t, err)
+	require.LessOrEqual(t, len(long.Name), maxJobNameLength)
+	require.Regexp(t, "^host-setup-ubuntu20-node-.*[^-]-[0-9a-f]{8}$", long.Name)
+	require.Equal(t, prefix+"0", long.Annotations[HostSetupNodeAnnotation])
+	other, err := hostSetupJob(ds, prefix+"1")
+	require.NoError(t, err)
+	require.LessOrEqual(t, len(other.Name), maxJobNameLength)
+	require.NotEqual(t, long.Name, other.Name, "Jobs of nodes sharing a long prefix collide")
+
+	// the Job is re-run once the setup is changed only
+	ds.Spec.Template.Spec.Containers[0].Image = "host-setup:latest"
+	same, err := hostSetupJob(ds, "ubuntu20")
+	require.NoError(t, err)
+	require.Equal(t, job.Name, same.Name)
+	ds.Spec.Template.Spec.InitContainers[0].Args[1] = "install 2022.2"
+	changed, err := hostSetupJob(ds, "ubuntu20")
+	require.NoError(t, err)
+	require.NotEqual(t, job.Name, changed.Name)
+}
+
+func TestJobsSetupOnNode(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "ubuntu20"}}
+	job := func(nodeName, hash string, complete bool) batchv1.Job {
+		job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{HostSetupHashLabel: hash}}}
+		job.Spec.Template.Spec.NodeName = nodeName
+		if complete {
+			job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+		}
+		return job
+	}
+
+	// the node is not ready until its Job is listed, eg. the Job just created is not in the cache yet
+	hash, ready := jobsSetupOnNode(node, []batchv1.Job{job("ubuntu18", "0badf00d", true)})
+	require.Empty(t, hash)
+	require.False(t, ready)
+
+	hash, ready = jobsSetupOnNode(node, []batchv1.Job{job("ubuntu20", "0badf00d", false)})
+	require.Equal(t, "0badf00d", hash)
+	require.False(t, ready)
+
+	hash, ready = jobsSetupOnNode(node, []batchv1.Job{job("ubuntu20", "0badf00d", true)})
+	require.Equal(t, "0badf00d", hash)
+	require.True(t, ready)
+}
+
+func TestNodeToleratesPodSpec(t *testing.T) {
+	node := &corev1.Node{Spec: corev1.NodeSpec{Taints: []corev1.Taint{
+		{Key: "dedicated", Value: "fpga", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "spot", Effect: corev1.TaintEffectPreferNoSchedule},
+	}}}
+	require.False(t, nodeToleratesPodSpec(node, &corev1.PodSpec{}))
+	require.True(t, nodeToleratesPodSpec(node, &corev1.PodSpec{Tolerations: []corev1.Toleration{
+		{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "fpga", Effect: corev1.TaintEffectNoSchedule},
+	}}))
+	require.True(t, nodeToleratesPodSpec(&corev1.Node{}, &corev1.PodSpec{}))
+}
@@ -0,0 +1,168 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	policyv1 "github.com/xilinx/fpga-operator/api/v1"
+	"github.com/xilinx/fpga-operator/labeller"
+)
+
+const (
+	// hostSetupReporterName is the name of the reporter container of host-setup
+	hostSetupReporterName = "host-setup-reporter"
+	// hostSetupReporterRoot is the directory the host file system is mounted at in the reporter container
+	hostSetupReporterRoot = "/host"
+)
+
+// recordSetupScript defines the shell function record, which sets the KEY=VALUE pairs in the setup status file
+// on the host, mounted at the same path in the init containers of host-setup
+var recordSetupScript = `status=/` + labeller.SetupStatusPath + `; mkdir -p "$(dirname "$status")"; touch "$status"; ` +
+	`record() { for kv in "$@"; do grep -v "^${kv%%=*}=" "$status" > "$status.tmp"; echo "$kv" >> "$status.tmp"; ` +
+	`mv "$status.tmp" "$status"; done; }; `
+
+// recordSetupTime is the argument of record setting the time of the setup in RFC 3339
+const recordSetupTime = `TIME=$(date -u +%Y-%m-%dT%H:%M:%SZ)`
+
+// xrtInstallScript returns the script of init-xrt-xrm, which skips installing XRT and XRM if the versions recorded
+// on the host by the previous setup are the desired ones, eg. when the pod is restarted, and records them otherwise
+func xrtInstallScript(version string, xrm bool, install string) string {
+	xrtVersion := fmt.Sprintf("XRT_VERSION=%s", version)
+	xrmInstalled := fmt.Sprintf("XRM_INSTALLED=%t", xrm)
+	return recordSetupScript +
+		fmt.Sprintf(`if grep -qxF "%s" "$status" && grep -qxF "%s" "$status"; then `, xrtVersion, xrmInstalled) +
+		fmt.Sprintf(`echo "XRT %s already installed, skipping"; `, version) +
+		fmt.Sprintf(`elif (%s); then record "%s" "%s"; `, install, xrtVersion, xrmInstalled) +
+		fmt.Sprintf(`else record RESULT=%s %s; exit 1; fi`, labeller.SetupFailed, recordSetupTime)
+}
+
+// cardFlashScript returns the script of init-card-flash, which records the result of the setup on the host
+func cardFlashScript(flash string) string {
+	return recordSetupScript +
+		fmt.Sprintf(`if (%s); then record RESULT=%s %s; `, flash, labeller.SetupSucceeded, recordSetupTime) +
+		fmt.Sprintf(`else record RESULT=%s %s; exit 1; fi`, labeller.SetupFailed, recordSetupTime)
+}
+
+// setHostSetupReporter adds the reporter container, which publishes the setup recorded on the host
+// and the shells flashed on the cards in the node annotations
+func setHostSetupReporter(obj *appsv1.DaemonSet, config *policyv1.ClusterPolicySpec) {
+	spec := &config.HostSetup.Reporter
+	podSpec := &obj.Spec.Template.Spec
+	automount := true
+	podSpec.AutomountServiceAccountToken = &automount
+
+	allowPrivilegeEscalation := false
+	reporter := corev1.Container{
+		Name:            hostSetupReporterName,
+		Image:           policyv1.OperatorImagePath(spec.Repository, spec.Image, spec.Tag),
+		ImagePullPolicy: policyv1.ImagePullPolicy(spec.ImagePullPolicy),
+		Command:         []string{"/" + hostSetupReporterName},
+		Args:            []string{"--host-root=" + hostSetupReporterRoot},
+		Env: []corev1.EnvVar{{
+			Name:      "NODE_NAME",
+			ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{FieldPath: "spec.nodeName"}},
+		}},
+		SecurityContext: &corev1.SecurityContext{
+			AllowPrivilegeEscalation: &allowPrivilegeEscalation,
+			Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		},
+	}
+	// the pod of a Job completes once the setup is reported
+	if config.HostSetup.IsJobMode() {
+		reporter.Args = append(reporter.Args, "--oneshot")
+	}
+	// detect the same PCI devices as the FPGA nodes selected by NFD labels
+	for _, device := range config.FPGANodes.PCIDevices {
+		pciDevice := labeller.PCIDevice{Vendor: device.Vendor, Class: device.Class, Device: device.Device}
+		reporter.Args = append(reporter.Args, "--pci-device="+pciDevice.String())
+	}
+
+	// the status file is under /var, and the cards are found in sysfs
+	for _, hostPath := range []string{"/var", "/sys"} {
+		name := "host" + strings.ReplaceAll(hostPath, "/", "-")
+		found := false
+		for _, volume := range podSpec.Volumes {
+			if volume.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+				Name:         name,
+				VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: hostPath}},
+			})
+		}
+		reporter.VolumeMounts = append(reporter.VolumeMounts, corev1.VolumeMount{
+			Name: name, MountPath: hostSetupReporterRoot + hostPath, ReadOnly: true,
+		})
+	}
+	podSpec.Containers = append(podSpec.Containers, reporter)
+}
+
+// hostSetupNodeStatuses returns the setup reported in the annotations of the nodes, sorted by node name
+func (n ControlContext) hostSetupNodeStatuses() ([]policyv1.HostSetupNodeStatus, error) {
+	nodes := &corev1.NodeList{}
+	err := n.rec.Client.List(context.TODO(), nodes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list nodes to get their setup: %v", err)
+	}
+
+	var statuses []policyv1.HostSetupNodeStatus
+	for _, node := range nodes.Items {
+		if status, ok := hostSetupNodeStatus(&node); ok {
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Node < statuses[j].Node })
+	return statuses, nil
+}
+
+// hostSetupNodeStatus returns the setup reported in the node annotations, or false if the node has none
+func hostSetupNodeStatus(node *corev1.Node) (policyv1.HostSetupNodeStatus, bool) {
+	annotations := node.GetAnnotations()
+	status := policyv1.HostSetupNodeStatus{
+		Node:            node.Name,
+		XRTVersion:      annotations[labeller.AnnotationXRTVersion],
+		XRMInstalled:    annotations[labeller.AnnotationXRMInstalled] == "true",
+		LastSetupResult: annotations[labeller.AnnotationLastSetupResult],
+	}
+	if shells, ok := annotations[labeller.AnnotationShells]; ok {
+		status.Shells = labeller.ParseShells(shells)
+	}
+	if value, ok := annotations[labeller.AnnotationLastSetupTime]; ok {
+		if setupTime, err := time.Parse(time.RFC3339, value); err == nil {
+			status.LastSetupTime = &metav1.Time{Time: setupTime}
+		}
+	}
+
+	for key := range annotations {
+		if strings.HasPrefix(key, labeller.SetupAnnotationPrefix) {
+			return status, true
+		}
+	}
+	return status, false
+}
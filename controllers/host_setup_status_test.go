@@ -0,0 +1,84 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	policyv1 "github.com/xilinx/fpga-operator/api/v1"
+	"github.com/xilinx/fpga-operator/labeller"
+)
+
+func TestHostSetupNodeStatuses(t *testing.T) {
+	n, err := newTestContext(clusterPolicy.DeepCopy())
+	require.NoError(t, err)
+
+	statuses, err := n.hostSetupNodeStatuses()
+	require.NoError(t, err)
+	require.Empty(t, statuses, "nodes without setup reported")
+
+	// the reporter of host-setup annotates the node
+	node := &corev1.Node{}
+	require.NoError(t, n.rec.Client.Get(context.TODO(), types.NamespacedName{Name: "ubuntu20"}, node))
+	patch := client.MergeFrom(node.DeepCopy())
+	annotations := map[string]string{
+		labeller.AnnotationXRTVersion:      "2023.1",
+		labeller.AnnotationXRMInstalled:    "true",
+		labeller.AnnotationShells:          "0000:3b:00=xilinx_u200_gen3x16_xdma_base_2,0000:af:00=xilinx_u50_gen3x16_xdma_5_202210_1",
+		labeller.AnnotationLastSetupTime:   "2023-03-01T10:00:00Z",
+		labeller.AnnotationLastSetupResult: labeller.SetupSucceeded,
+	}
+	node.Annotations = annotations
+	require.NoError(t, n.rec.Client.Patch(context.TODO(), node, patch))
+	t.Cleanup(func() {
+		patch := client.MergeFrom(node.DeepCopy())
+		node.Annotations = nil
+		if err := n.rec.Client.Patch(context.TODO(), node, patch); err != nil {
+			t.Fatalf("error removing node annotations: %v", err)
+		}
+	})
+
+	statuses, err = n.hostSetupNodeStatuses()
+	require.NoError(t, err)
+	require.Equal(t, []policyv1.HostSetupNodeStatus{{
+		Node:         "ubuntu20",
+		XRTVersion:   "2023.1",
+		XRMInstalled: true,
+		Shells: map[string]string{
+			"0000:3b:00": "xilinx_u200_gen3x16_xdma_base_2",
+			"0000:af:00": "xilinx_u50_gen3x16_xdma_5_202210_1",
+		},
+		LastSetupTime:   &metav1.Time{Time: time.Date(2023, 3, 1, 10, 0, 0, 0, time.UTC)},
+		LastSetupResult: labeller.SetupSucceeded,
+	}}, statuses)
+
+	// the setup reported is a change of the node to reconcile
+	updated := node.DeepCopy()
+	updated.Annotations[labeller.AnnotationLastSetupResult] = labeller.SetupFailed
+	require.True(t, clusterPolicyReconciler.nodeChanged(node, updated), "setup change of node is ignored")
+	updated = node.DeepCopy()
+	updated.Annotations["node.alpha.kubernetes.io/ttl"] = "0"
+	require.False(t, clusterPolicyReconciler.nodeChanged(node, updated), "unrelated annotation change of node is reconciled")
+}
@@ -20,30 +20,59 @@ import (
 	"context"
 	"fmt"
 	"path"
-	"strconv"
+	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/go-logr/logr"
-	"github.com/mitchellh/hashstructure"
 
 	policyv1 "github.com/xilinx/fpga-operator/api/v1"
+	"github.com/xilinx/fpga-operator/labeller"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	nodev1 "k8s.io/api/node/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/csaupgrade"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 const (
-	DefaultRuntimeClass     = "xilinx"
-	DefaultXCRInstallDir    = "/usr/bin"
+	DefaultRuntimeClass     = policyv1.DefaultRuntimeClass
+	DefaultXCRInstallDir    = policyv1.DefaultXCRInstallDir
 	DefaultXCRConfigDir     = "/etc/xilinx-container-runtime"
 	DefaultDockerConfig     = "/etc/docker/daemon.json"
 	DefaultDockerSocket     = "/var/run/docker.sock"
 	DefaultContainerdConfig = "/etc/containerd/config.toml"
 	DefaultContainerdSocket = "/var/run/containerd/containerd.sock"
-	XilinxAnnotationHashKey = "xilinx.com/last-applied-hash"
+	// FieldManager is the field manager of the objects applied by the operator
+	FieldManager = "fpga-operator"
+	// SkipReconcileAnnotation set to "true" on an object deployed by the operator stops reverting its changes, for debugging
+	SkipReconcileAnnotation = "xilinx.com/skip-reconcile"
+	// maxEventFields is the max number of changed fields named in an event
+	maxEventFields = 5
+
+	xcrInstallDirMountPath = "/host-usr/bin"
+	xcrConfigDirMountPath  = "/host-etc/xilinx-container-runtime"
+	xcrUninstallSuffix     = "-uninstall"
+)
+
+const (
+	EventReasonCreated      = "Created"
+	EventReasonUpdated      = "Updated"
+	EventReasonDeleted      = "Deleted"
+	EventReasonCreateFailed = "CreateFailed"
+	EventReasonUpdateFailed = "UpdateFailed"
+	EventReasonDeleteFailed = "DeleteFailed"
+	EventReasonNotReady     = "NotReady"
+	EventReasonTeardown     = "Teardown"
 )
 
 // Error to state spec not found for a daemonset
@@ -53,7 +82,18 @@ func (e *NoSpecError) Error() string {
 	return "no spec found"
 }
 
-type controlFuncs []func(ctrl ClusterPolicyController) (policyv1.State, error)
+type controlFuncs []func(ctrl ControlContext) (policyv1.State, error)
+
+// recordEvent records an event on ClusterPolicy, and also on the owned object if it is given
+func recordEvent(ctrl ControlContext, owned client.Object, eventType, reason, message string) {
+	if ctrl.rec.Recorder == nil {
+		return
+	}
+	ctrl.rec.Recorder.Event(ctrl.singleton, eventType, reason, message)
+	if owned != nil {
+		ctrl.rec.Recorder.Event(owned, eventType, reason, message)
+	}
+}
 
 // getRuntimeClass return the name of runtime class to be created
 func getRuntimeClass(config *policyv1.ClusterPolicySpec) string {
@@ -64,24 +104,218 @@ func getRuntimeClass(config *policyv1.ClusterPolicySpec) string {
 }
 
 // RuntimeClass creates RuntimeClass object
-func RuntimeClass(n ClusterPolicyController) (policyv1.State, error) {
+func RuntimeClass(n ControlContext) (policyv1.State, error) {
 	// get runtimeclass template
-	obj := n.resources[n.idx].RuntimeClass.DeepCopy()
+	obj := n.resources.RuntimeClass.DeepCopy()
 
 	// apply runtime class name as per ClusterPolicy
 	obj.Name = getRuntimeClass(&n.singleton.Spec)
 	obj.Handler = getRuntimeClass(&n.singleton.Spec)
 
-	logger := n.rec.Log.WithValues("RuntimeClass", obj.Name)
+	return createOrUpdateObject(n, "RuntimeClass", obj, &nodev1.RuntimeClass{})
+}
+
+// Objects creates the objects of the kinds without a dedicated control function. The namespaced objects are
+// created in the operator namespace, so are the service accounts bound by RoleBinding and ClusterRoleBinding
+func Objects(n ControlContext) (policyv1.State, error) {
+	result := policyv1.Ready
+	for _, o := range n.resources.Objects {
+		obj := o.DeepCopyObject().(client.Object)
+		kind := obj.GetObjectKind().GroupVersionKind().Kind
+		if isNamespaced(n, obj) {
+			obj.SetNamespace(n.operatorNamespace)
+		}
+		setSubjectsNamespace(obj, n.operatorNamespace)
+
+		state, err := createOrUpdateObject(n, kind, obj, newObjectOf(obj))
+		if meta.IsNoMatchError(err) {
+			// the kind is not installed, eg. ServiceMonitor without Prometheus Operator
+			n.rec.Log.Info("Kind not installed, skipping", "Kind", kind, "Name", obj.GetName())
+			// the event is recorded once the object is skipped, not on every reconciliation
+			if !wasObjectSkipped(n, obj) {
+				recordEvent(n, nil, corev1.EventTypeWarning, EventReasonCreateFailed,
+					fmt.Sprintf("%s %s is not created as its kind is not installed", kind, obj.GetName()))
+			}
+			continue
+		}
+		if err != nil {
+			return state, err
+		}
+		if state != policyv1.Ready {
+			result = state
+		}
+	}
+	return result, nil
+}
+
+// skippedObjectRef returns the reference of obj in the skipped objects of the state status, eg. ServiceMonitor/device-plugin
+func skippedObjectRef(obj client.Object) string {
+	return obj.GetObjectKind().GroupVersionKind().Kind + "/" + obj.GetName()
+}
+
+// isKindInstalled returns false if the kind of obj is not installed, eg. ServiceMonitor without Prometheus Operator
+func isKindInstalled(n ControlContext, obj client.Object) bool {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	_, err := n.rec.Client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	return !meta.IsNoMatchError(err)
+}
+
+// wasObjectSkipped returns true if the object of the state being reconciled
+// is skipped in the previous status of ClusterPolicy
+func wasObjectSkipped(n ControlContext, obj client.Object) bool {
+	ref := skippedObjectRef(obj)
+	for _, component := range n.singleton.Status.Components {
+		if component.Name != n.stateName {
+			continue
+		}
+		for _, skipped := range component.SkippedObjects {
+			if skipped == ref {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isNamespaced returns true if obj is namespaced. An object with a namespace in the manifest is namespaced,
+// eg. "filled_by_operator", otherwise it is looked up in the REST mapping of its kind
+func isNamespaced(n ControlContext, obj client.Object) bool {
+	if obj.GetNamespace() != "" {
+		return true
+	}
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	mapping, err := n.rec.Client.RESTMapper().RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return false
+	}
+	return mapping.Scope.Name() == meta.RESTScopeNameNamespace
+}
+
+// setSubjectsNamespace sets the namespace of the service accounts bound by a RoleBinding or ClusterRoleBinding
+func setSubjectsNamespace(obj client.Object, namespace string) {
+	var subjects []rbacv1.Subject
+	switch binding := obj.(type) {
+	case *rbacv1.ClusterRoleBinding:
+		subjects = binding.Subjects
+	case *rbacv1.RoleBinding:
+		subjects = binding.Subjects
+	}
+	for i := range subjects {
+		if subjects[i].Kind == rbacv1.ServiceAccountKind {
+			subjects[i].Namespace = namespace
+		}
+	}
+}
+
+// newObjectOf returns an empty object of the same kind as obj
+func newObjectOf(obj client.Object) client.Object {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		found := &unstructured.Unstructured{}
+		found.SetGroupVersionKind(u.GroupVersionKind())
+		return found
+	}
+	return reflect.New(reflect.TypeOf(obj).Elem()).Interface().(client.Object)
+}
+
+// Deployment creates Deployment objects in the operator namespace, and checks if they are available
+func Deployment(n ControlContext) (policyv1.State, error) {
+	result := policyv1.Ready
+	for _, deployment := range n.resources.Deployments {
+		obj := deployment.DeepCopy()
+		obj.Namespace = n.operatorNamespace
+
+		if n.isStateEnabled(n.stateName) {
+			if err := preProcessDeployment(obj, n); err != nil {
+				n.rec.Log.Error(err, "Could not pre-process", "Deployment", obj.Name)
+				return policyv1.NotReady, err
+			}
+		}
+
+		state, err := createOrUpdateObject(n, "Deployment", obj, &appsv1.Deployment{})
+		if err != nil {
+			return state, err
+		}
+		if state == policyv1.Ready {
+			state = isDeploymentReady(obj.Namespace, obj.Name, n)
+		}
+		if state != policyv1.Ready {
+			result = state
+		}
+	}
+	return result, nil
+}
+
+// NodeFeatureRule creates the NodeFeatureRule of NFD labeling the FPGA nodes. NFD labels the nodes
+// with the PCI devices of the default classes without it, so the state is ready if the CRD is not installed
+func NodeFeatureRule(n ControlContext) (policyv1.State, error) {
+	obj := n.resources.NodeFeatureRule.DeepCopy()
+	err := unstructured.SetNestedSlice(obj.Object, nodeFeatureRules(&n.singleton.Spec.FPGANodes), "spec", "rules")
+	if err != nil {
+		return policyv1.NotReady, err
+	}
+
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(obj.GroupVersionKind())
+	state, err := createOrUpdateObject(n, "NodeFeatureRule", obj, found)
+	if meta.IsNoMatchError(err) {
+		n.rec.Log.Info("NodeFeatureRule CRD not installed, skipping", "NodeFeatureRule", obj.GetName())
+		if !wasObjectSkipped(n, obj) {
+			recordEvent(n, nil, corev1.EventTypeWarning, EventReasonCreateFailed,
+				fmt.Sprintf("NodeFeatureRule %s is not created as its CRD is not installed", obj.GetName()))
+		}
+		return policyv1.Ready, nil
+	}
+	return state, err
+}
+
+// nodeFeatureRules returns the rules of NodeFeatureRule, which label the nodes with each PCI device of FPGA.
+// The labels are the same as the ones NFD creates by default, eg. pci-1200_10ee.present, so the device ID is matched too
+func nodeFeatureRules(spec *policyv1.FPGANodeSpec) []interface{} {
+	if len(spec.PCIDevices) == 0 {
+		spec = &policyv1.FPGANodeSpec{}
+		spec.SetDefaults()
+	}
+
+	rules := []interface{}{}
+	for _, device := range spec.PCIDevices {
+		label := strings.TrimPrefix(pciDeviceLabel(device), nfdLabelPrefix)
+		expressions := map[string]interface{}{}
+		for attribute, value := range map[string]string{"vendor": device.Vendor, "class": device.Class, "device": device.Device} {
+			if value != "" {
+				expressions[attribute] = map[string]interface{}{"op": "In", "value": []interface{}{value}}
+			}
+		}
+		rules = append(rules, map[string]interface{}{
+			"name":   "xilinx fpga " + strings.TrimSuffix(label, ".present"),
+			"labels": map[string]interface{}{label: "true"},
+			"matchFeatures": []interface{}{
+				map[string]interface{}{"feature": "pci.device", "matchExpressions": expressions},
+			},
+		})
+	}
+	return rules
+}
+
+// createOrUpdateObject creates or updates obj of the current state, or deletes it if the state is disabled.
+// found is an empty object of the same kind to get the existing one into
+func createOrUpdateObject(n ControlContext, kind string, obj client.Object, found client.Object) (policyv1.State, error) {
+	logger := n.rec.Log.WithValues(kind, obj.GetName())
 
 	// check if state is disabled
-	if !n.isStateEnabled(n.stateNames[n.idx]) {
+	if !n.isStateEnabled(n.stateName) {
 		logger.Info("State disabled, not creating resource")
 		err := n.rec.Client.Delete(context.TODO(), obj)
-		if err != nil && !errors.IsNotFound(err) {
+		// the kind may not be installed, eg. a custom resource of an add-on
+		if err != nil && !errors.IsNotFound(err) && !meta.IsNoMatchError(err) {
 			logger.Error(err, "Couldn't delete")
+			recordEvent(n, nil, corev1.EventTypeWarning, EventReasonDeleteFailed,
+				fmt.Sprintf("Failed to delete %s %s of disabled state %s: %v", kind, obj.GetName(), n.stateName, err))
 			return policyv1.NotReady, nil
 		}
+		if err == nil {
+			recordEvent(n, nil, corev1.EventTypeNormal, EventReasonDeleted,
+				fmt.Sprintf("Deleted %s %s as state %s is disabled", kind, obj.GetName(), n.stateName))
+		}
 		return policyv1.Disabled, nil
 	}
 
@@ -90,102 +324,451 @@ func RuntimeClass(n ClusterPolicyController) (policyv1.State, error) {
 		return policyv1.NotReady, err
 	}
 
-	found := &nodev1.RuntimeClass{}
+	_, err := applyObject(n, kind, obj, found)
+	if err != nil {
+		return policyv1.NotReady, err
+	}
+	return policyv1.Ready, nil
+}
+
+// applyObject applies obj server-side with FieldManager, creating it if it is not found. The existing object is
+// only updated if applying obj changes it, eg. a field edited by kubectl or removed from obj since the last apply,
+// unless it is annotated with SkipReconcileAnnotation. found is an empty object of the same kind to get the
+// existing one into. It returns true if the existing object is updated
+func applyObject(n ControlContext, kind string, obj client.Object, found client.Object) (bool, error) {
+	logger := n.rec.Log.WithValues(kind, obj.GetName())
+
+	// the apply patch has to be of the kind and without resourceVersion
+	gvk, err := apiutil.GVKForObject(obj, n.rec.Scheme)
+	if err != nil {
+		return false, err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gvk)
+	obj.SetResourceVersion("")
+	obj.SetManagedFields(nil)
 
-	// create a new runtimeclass
-	err := n.rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: "", Name: obj.Name}, found)
+	err = n.rec.Client.Get(context.TODO(), client.ObjectKeyFromObject(obj), found)
 	if err != nil && errors.IsNotFound(err) {
+		// created by apply, so the fields removed from obj later are pruned by the next apply
 		logger.Info("Not found, creating...")
-		err = n.rec.Client.Create(context.TODO(), obj)
+		err = n.rec.Client.Patch(context.TODO(), obj, client.Apply, client.FieldOwner(FieldManager), client.ForceOwnership)
 		if err != nil {
 			logger.Error(err, "Couldn't create")
-			return policyv1.NotReady, err
+			recordEvent(n, nil, corev1.EventTypeWarning, EventReasonCreateFailed,
+				fmt.Sprintf("Failed to create %s %s: %v", kind, obj.GetName(), err))
+			return false, err
 		}
-		return policyv1.Ready, nil
+		recordEvent(n, obj, corev1.EventTypeNormal, EventReasonCreated, fmt.Sprintf("Created %s %s", kind, obj.GetName()))
+		return false, nil
 	} else if err != nil {
-		return policyv1.NotReady, err
+		return false, err
 	}
 
-	// update an existing runtimeclass
-	logger.Info("Found Resource, updating...")
-	obj.ResourceVersion = found.ResourceVersion
+	// the fields of the object created before by the operator are owned by an update, which apply never prunes
+	if err := upgradeManagedFields(n, found); err != nil {
+		logger.Error(err, "Couldn't upgrade managed fields")
+		return false, err
+	}
 
-	err = n.rec.Client.Update(context.TODO(), obj)
+	// a dry-run apply returns the object as applying obj makes it, with the fields defaulted and pruned by the server
+	applied := obj.DeepCopyObject().(client.Object)
+	err = n.rec.Client.Patch(context.TODO(), applied, client.Apply, client.FieldOwner(FieldManager), client.ForceOwnership,
+		client.DryRunAll)
 	if err != nil {
-		logger.Error(err, "Couldn't update")
-		return policyv1.NotReady, err
+		logger.Error(err, "Couldn't dry-run apply")
+		return false, err
+	}
+	fields, err := changedFields(applied, found)
+	if err != nil {
+		return false, err
+	}
+	if len(fields) == 0 {
+		logger.Info("Found Resource, identical, skipping update")
+		return false, nil
+	}
+	if found.GetAnnotations()[SkipReconcileAnnotation] == "true" {
+		logger.Info("Found Resource changed, skipping update as annotated", "annotation", SkipReconcileAnnotation, "fields", fields)
+		return false, nil
 	}
-	return policyv1.Ready, nil
-}
 
-func getDaemonsetHash(daemonset *appsv1.DaemonSet) string {
-	hash, err := hashstructure.Hash(daemonset, nil)
+	logger.Info("Found Resource, applying...", "fields", fields)
+	err = n.rec.Client.Patch(context.TODO(), obj, client.Apply, client.FieldOwner(FieldManager), client.ForceOwnership)
 	if err != nil {
-		panic(err.Error())
+		logger.Error(err, "Couldn't apply")
+		recordEvent(n, found, corev1.EventTypeWarning, EventReasonUpdateFailed,
+			fmt.Sprintf("Failed to update %s %s: %v", kind, obj.GetName(), err))
+		return false, err
 	}
-	return strconv.FormatUint(hash, 16)
+	recordEvent(n, found, corev1.EventTypeNormal, EventReasonUpdated,
+		fmt.Sprintf("Updated %s %s with changed fields: %s", kind, obj.GetName(), formatFields(fields)))
+	return true, nil
 }
 
-func isDaemonSetReady(namespace string, name string, rec ClusterPolicyReconciler, logger logr.Logger) policyv1.State {
-	logger.Info("Check DaemonSet ready")
-	ds := &appsv1.DaemonSet{}
-	err := rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, ds)
+// upgradeManagedFields moves the fields of found owned by an update of FieldManager to its apply,
+// so they are pruned once they are no longer applied
+func upgradeManagedFields(n ControlContext, found client.Object) error {
+	patch, err := csaupgrade.UpgradeManagedFieldsPatch(found, sets.New(FieldManager), FieldManager)
+	if err != nil || patch == nil {
+		return err
+	}
+	return n.rec.Client.Patch(context.TODO(), found, client.RawPatch(types.JSONPatchType, patch))
+}
 
+// formatFields joins the changed fields for an event, naming maxEventFields of them at most
+func formatFields(fields []string) string {
+	if len(fields) <= maxEventFields {
+		return strings.Join(fields, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(fields[:maxEventFields], ", "), len(fields)-maxEventFields)
+}
+
+// changedFields returns the paths of the fields whose values are different in desired and live,
+// eg. spec.template.spec.containers[0].image, including the fields set in only one of them. desired is
+// the result of a dry-run apply, so the fields defaulted by the API server or set by others are in both.
+// Only labels, annotations and owner references are compared in metadata
+func changedFields(desired client.Object, live client.Object) ([]string, error) {
+	d, err := toUnstructured(desired)
 	if err != nil {
-		rec.Log.Error(err, "Could not get DaemonSet")
-		return policyv1.NotReady
+		return nil, err
+	}
+	l, err := toUnstructured(live)
+	if err != nil {
+		return nil, err
+	}
+	for _, content := range []map[string]interface{}{d, l} {
+		delete(content, "status")
+		metadata, _ := content["metadata"].(map[string]interface{})
+		for key := range metadata {
+			if key != "labels" && key != "annotations" && key != "ownerReferences" {
+				delete(metadata, key)
+			}
+		}
 	}
 
-	// ds := list.Items[0]
-	logger.Info(fmt.Sprintf("Daemonset has %d pod(s) unavailable", ds.Status.NumberUnavailable))
+	fields := []string{}
+	diffFields("", d, l, &fields)
+	sort.Strings(fields)
+	return fields, nil
+}
 
-	if ds.Status.NumberUnavailable != 0 {
-		return policyv1.NotReady
+// toUnstructured converts obj to its unstructured content
+func toUnstructured(obj client.Object) (map[string]interface{}, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u.DeepCopy().UnstructuredContent(), nil
 	}
+	return runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+}
 
-	return policyv1.Ready
+// diffFields appends the paths of the fields which are different in desired and live. The lists are
+// compared by index, and the null or zero values match the fields not set
+func diffFields(path string, desired, live interface{}, fields *[]string) {
+	switch d := desired.(type) {
+	case nil:
+		if !isZeroField(live) {
+			*fields = append(*fields, path)
+		}
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok && live != nil {
+			*fields = append(*fields, path)
+			return
+		}
+		for key, value := range d {
+			diffFields(fieldPath(path, key), value, l[key], fields)
+		}
+		// the fields removed from desired
+		for key, value := range l {
+			if _, ok := d[key]; !ok && !isZeroField(value) {
+				*fields = append(*fields, fieldPath(path, key))
+			}
+		}
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if (!ok && live != nil) || len(l) != len(d) {
+			*fields = append(*fields, path)
+			return
+		}
+		for i := range d {
+			diffFields(fmt.Sprintf("%s[%d]", path, i), d[i], l[i], fields)
+		}
+	default:
+		if live == nil && isZeroField(desired) {
+			return
+		}
+		if !reflect.DeepEqual(normalizeNumber(desired), normalizeNumber(live)) {
+			*fields = append(*fields, path)
+		}
+	}
 }
 
-func isDaemonsetSpecChanged(current *appsv1.DaemonSet, new *appsv1.DaemonSet) bool {
-	if current == nil && new != nil {
+// fieldPath returns the path of the field key in the object at path
+func fieldPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// isZeroField returns true if the unstructured value is null, zero or empty
+func isZeroField(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
 		return true
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
 	}
-	if current.Annotations == nil || new.Annotations == nil {
-		panic("appsv1.DaemonSet.Annotations must be allocated prior to calling isDaemonsetSpecChanged()")
+	return reflect.ValueOf(value).IsZero()
+}
+
+// normalizeNumber converts an integer to float64, as the numbers decoded from YAML are float64
+func normalizeNumber(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int64:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int:
+		return float64(v)
 	}
+	return value
+}
 
-	hashStr := getDaemonsetHash(new)
-	foundHashAnnotation := false
+// daemonSetRolloutState returns the rollout state of the DaemonSet: Ready once the controller has observed
+// the latest generation and all the pods scheduled are updated and available, or NoMatchingNodes if the
+// DaemonSet is not scheduled on any node
+func daemonSetRolloutState(ds *appsv1.DaemonSet) policyv1.State {
+	status := ds.Status
+	switch {
+	case status.ObservedGeneration < ds.Generation:
+		return policyv1.NotReady
+	case status.DesiredNumberScheduled == 0:
+		return policyv1.NoMatchingNodes
+	case status.UpdatedNumberScheduled != status.DesiredNumberScheduled,
+		status.NumberAvailable != status.DesiredNumberScheduled:
+		return policyv1.NotReady
+	}
+	return policyv1.Ready
+}
 
-	for annotation, value := range current.Annotations {
-		if annotation == XilinxAnnotationHashKey {
-			if value != hashStr {
-				// update annotation to be added to Daemonset as per new spec and indicate spec update is required
-				new.Annotations[XilinxAnnotationHashKey] = hashStr
-				return true
+// daemonSetRolloutProgress describes why the DaemonSet is not ready, eg. 1/3 updated, 2/3 available
+func daemonSetRolloutProgress(ds *appsv1.DaemonSet) string {
+	status := ds.Status
+	if status.ObservedGeneration < ds.Generation {
+		return fmt.Sprintf("generation %d not observed", ds.Generation)
+	}
+	return fmt.Sprintf("%d/%d updated, %d/%d available", status.UpdatedNumberScheduled, status.DesiredNumberScheduled,
+		status.NumberAvailable, status.DesiredNumberScheduled)
+}
+
+// wasDaemonSetNotReady returns true if the DaemonSet of the state being reconciled
+// is not rolled out in the previous status of ClusterPolicy
+func wasDaemonSetNotReady(ctrl ControlContext, name string) bool {
+	for _, component := range ctrl.singleton.Status.Components {
+		if component.Name != ctrl.stateName || component.State != policyv1.NotReady {
+			continue
+		}
+		for _, ds := range component.DaemonSets {
+			if ds.Name == name {
+				return ds.UpdatedNumberScheduled != ds.DesiredNumberScheduled || ds.NumberAvailable != ds.DesiredNumberScheduled
 			}
-			foundHashAnnotation = true
-			break
 		}
 	}
+	return false
+}
 
-	if !foundHashAnnotation {
-		// update annotation to be added to Daemonset as per new spec and indicate spec update is required
-		new.Annotations[XilinxAnnotationHashKey] = hashStr
-		return true
+func isDaemonSetReady(namespace string, name string, ctrl ControlContext, logger logr.Logger) policyv1.State {
+	logger.Info("Check DaemonSet ready")
+	ds := &appsv1.DaemonSet{}
+	err := ctrl.rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, ds)
+
+	if err != nil {
+		ctrl.rec.Log.Error(err, "Could not get DaemonSet")
+		return policyv1.NotReady
+	}
+
+	state := daemonSetRolloutState(ds)
+	switch state {
+	case policyv1.NotReady:
+		logger.Info("DaemonSet not ready", "Progress", daemonSetRolloutProgress(ds))
+		// the event is recorded once the DaemonSet becomes not ready, not on every requeue
+		if ctrl.rec.Recorder != nil && !wasDaemonSetNotReady(ctrl, name) {
+			ctrl.rec.Recorder.Eventf(ds, corev1.EventTypeWarning, EventReasonNotReady,
+				"DaemonSet is not ready: %s", daemonSetRolloutProgress(ds))
+		}
+	case policyv1.NoMatchingNodes:
+		logger.Info("DaemonSet not scheduled on any node", "NodeSelector", ds.Spec.Template.Spec.NodeSelector)
+	}
+	return state
+}
+
+// wasDeploymentNotReady returns true if the Deployment of the state being reconciled
+// has unavailable replicas in the previous status of ClusterPolicy
+func wasDeploymentNotReady(n ControlContext, name string) bool {
+	for _, component := range n.singleton.Status.Components {
+		if component.Name != n.stateName || component.State != policyv1.NotReady {
+			continue
+		}
+		for _, deployment := range component.Deployments {
+			if deployment.Name == name {
+				return deployment.UnavailableReplicas != 0
+			}
+		}
 	}
 	return false
 }
 
+func isDeploymentReady(namespace string, name string, n ControlContext) policyv1.State {
+	deployment := &appsv1.Deployment{}
+	err := n.rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, deployment)
+	if err != nil {
+		n.rec.Log.Error(err, "Could not get Deployment")
+		return policyv1.NotReady
+	}
+
+	if deployment.Status.UnavailableReplicas != 0 {
+		// the event is recorded once the Deployment becomes not ready, not on every requeue
+		if !wasDeploymentNotReady(n, name) {
+			recordEvent(n, deployment, corev1.EventTypeWarning, EventReasonNotReady,
+				fmt.Sprintf("Deployment %s has %d replica(s) unavailable", name, deployment.Status.UnavailableReplicas))
+		}
+		return policyv1.NotReady
+	}
+	return policyv1.Ready
+}
+
 // setDaemonSetSelctor add nodeSelector for daemonset
 func setDaemonSetSelector(obj *appsv1.DaemonSet, labels map[string]string) {
 	if obj.Spec.Template.Spec.NodeSelector == nil {
-		obj.Spec.Template.Spec.NodeSelector = labels
-	} else {
-		// append labels to exsiting selector
-		for k, v := range labels {
-			obj.Spec.Template.Spec.NodeSelector[k] = v
+		obj.Spec.Template.Spec.NodeSelector = make(map[string]string, len(labels))
+	}
+	// append labels to exsiting selector
+	for k, v := range labels {
+		obj.Spec.Template.Spec.NodeSelector[k] = v
+	}
+}
+
+// setNodeSelectors restricts daemonset to the nodes with all the labels of any set, a required node affinity
+// is used if there are several label sets
+func setNodeSelectors(obj *appsv1.DaemonSet, selectors []map[string]string) {
+	if len(selectors) == 1 {
+		setDaemonSetSelector(obj, selectors[0])
+		return
+	}
+
+	nodes := &corev1.NodeSelector{}
+	for _, selector := range selectors {
+		// sort the keys, so the daemonset hash is stable
+		keys := make([]string, 0, len(selector))
+		for key := range selector {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		term := corev1.NodeSelectorTerm{}
+		for _, key := range keys {
+			term.MatchExpressions = append(term.MatchExpressions, corev1.NodeSelectorRequirement{
+				Key:      key,
+				Operator: corev1.NodeSelectorOpIn,
+				Values:   []string{selector[key]},
+			})
+		}
+		nodes.NodeSelectorTerms = append(nodes.NodeSelectorTerms, term)
+	}
+
+	podSpec := &obj.Spec.Template.Spec
+	if podSpec.Affinity == nil {
+		podSpec.Affinity = &corev1.Affinity{}
+	}
+	if podSpec.Affinity.NodeAffinity == nil {
+		podSpec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	nodeAffinity := podSpec.Affinity.NodeAffinity
+	nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = andNodeSelectors(
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution, nodes)
+}
+
+// andNodeSelectors returns a node selector matching the nodes selected by both a and b.
+// The terms of a node selector are ORed, so each term of a is combined with each term of b
+func andNodeSelectors(a, b *corev1.NodeSelector) *corev1.NodeSelector {
+	if a == nil || len(a.NodeSelectorTerms) == 0 {
+		return b.DeepCopy()
+	}
+	if b == nil || len(b.NodeSelectorTerms) == 0 {
+		return a.DeepCopy()
+	}
+
+	merged := &corev1.NodeSelector{}
+	for _, termA := range a.NodeSelectorTerms {
+		for _, termB := range b.NodeSelectorTerms {
+			term := termA.DeepCopy()
+			termB := termB.DeepCopy()
+			term.MatchExpressions = append(term.MatchExpressions, termB.MatchExpressions...)
+			term.MatchFields = append(term.MatchFields, termB.MatchFields...)
+			merged.NodeSelectorTerms = append(merged.NodeSelectorTerms, *term)
+		}
+	}
+	return merged
+}
+
+// setPodScheduling merges the scheduling and extra metadata of a component into the pod template of daemonset
+func setPodScheduling(obj *appsv1.DaemonSet, spec *policyv1.PodSchedulingSpec) {
+	podSpec := &obj.Spec.Template.Spec
+
+	if len(spec.NodeSelector) > 0 {
+		setDaemonSetSelector(obj, spec.NodeSelector)
+	}
+
+	if spec.Affinity != nil {
+		affinity := spec.Affinity.DeepCopy()
+		// keep the node affinity of FPGA nodes
+		if podSpec.Affinity != nil && podSpec.Affinity.NodeAffinity != nil &&
+			podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution != nil {
+			if affinity.NodeAffinity == nil {
+				affinity.NodeAffinity = &corev1.NodeAffinity{}
+			}
+			affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = andNodeSelectors(
+				podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+				affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution)
+		}
+		podSpec.Affinity = affinity
+	}
+
+	// append tolerations not defined in the asset yet
+	for _, toleration := range spec.Tolerations {
+		found := false
+		for _, t := range podSpec.Tolerations {
+			if reflect.DeepEqual(t, toleration) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			podSpec.Tolerations = append(podSpec.Tolerations, toleration)
+		}
+	}
+
+	if spec.PriorityClassName != "" {
+		podSpec.PriorityClassName = spec.PriorityClassName
+	}
+
+	// labels selected by the daemonset can't be overridden
+	for k, v := range spec.PodLabels {
+		if _, ok := obj.Spec.Selector.MatchLabels[k]; ok {
+			continue
+		}
+		if obj.Spec.Template.Labels == nil {
+			obj.Spec.Template.Labels = make(map[string]string)
 		}
+		obj.Spec.Template.Labels[k] = v
+	}
+
+	for k, v := range spec.PodAnnotations {
+		if obj.Spec.Template.Annotations == nil {
+			obj.Spec.Template.Annotations = make(map[string]string)
+		}
+		obj.Spec.Template.Annotations[k] = v
 	}
 }
 
@@ -202,6 +785,31 @@ func setContainerEnv(c *corev1.Container, key, value string) {
 	c.Env = append(c.Env, corev1.EnvVar{Name: key, Value: value})
 }
 
+// setContainerOverrides overrides the resources and merges the security context of container
+func setContainerOverrides(c *corev1.Container, spec *policyv1.ContainerOverridesSpec) {
+	if spec == nil {
+		return
+	}
+
+	if spec.Resources != nil {
+		c.Resources = *spec.Resources.DeepCopy()
+	}
+
+	if spec.SecurityContext != nil {
+		if c.SecurityContext == nil {
+			c.SecurityContext = &corev1.SecurityContext{}
+		}
+		// all fields of the security context are pointers, only those set are overridden
+		dst := reflect.ValueOf(c.SecurityContext).Elem()
+		src := reflect.ValueOf(spec.SecurityContext.DeepCopy()).Elem()
+		for i := 0; i < src.NumField(); i++ {
+			if !src.Field(i).IsNil() {
+				dst.Field(i).Set(src.Field(i))
+			}
+		}
+	}
+}
+
 // getRuntimeConfig returns Docker/containerd config filepath
 func getRuntimeConfig(runtime policyv1.Runtime) string {
 	switch runtime {
@@ -227,9 +835,11 @@ func getRuntimeSocket(runtime policyv1.Runtime) string {
 }
 
 // preProcessDaemonset update the daemonset object base on the state name
-func preProcessDaemonSet(obj *appsv1.DaemonSet, ctrl ClusterPolicyController) error {
+func preProcessDaemonSet(obj *appsv1.DaemonSet, ctrl ControlContext) error {
 	logger := ctrl.rec.Log
-	transformations := map[string]func(*appsv1.DaemonSet, *policyv1.ClusterPolicySpec, ClusterPolicyController) error{
+	transformations := map[string]func(*appsv1.DaemonSet, *policyv1.ClusterPolicySpec, ControlContext) error{
+		"nfd-worker-daemonset":               TransformNFDWorker,
+		"node-labeller-daemonset":            TransformNodeLabeller,
 		"xilinx-container-runtime-daemonset": TransformContainerRuntime,
 		"device-plugin-daemonset":            TransformDevicePlugin,
 		"host-setup-ubuntu18-daemonset":      TransformHostSetup,
@@ -254,29 +864,54 @@ func preProcessDaemonSet(obj *appsv1.DaemonSet, ctrl ClusterPolicyController) er
 	return nil
 }
 
+// preProcessDeployment updates the deployment object base on the state name
+func preProcessDeployment(obj *appsv1.Deployment, ctrl ControlContext) error {
+	transformations := map[string]func(*appsv1.Deployment, *policyv1.ClusterPolicySpec, ControlContext) error{
+		"nfd-master": TransformNFDMaster,
+	}
+
+	t, ok := transformations[obj.Name]
+	if !ok {
+		ctrl.rec.Log.Info(fmt.Sprintf("No transformation for Deployment '%s'", obj.Name))
+		return nil
+	}
+	return t(obj, &ctrl.singleton.Spec, ctrl)
+}
+
 // DaemonSet creates DaemonSet resource
-func DaemonSet(ctrl ClusterPolicyController) (policyv1.State, error) {
-	idx := ctrl.idx
+func DaemonSet(ctrl ControlContext) (policyv1.State, error) {
 	result := policyv1.Ready
+	scheduled, noMatchingNodes := 0, 0
 
 	ctrl.rec.Log.Info(fmt.Sprintf("There is %d DaemonSets to be created",
-		len(ctrl.resources[idx].Daemonsets)), "State", ctrl.stateNames[idx])
+		len(ctrl.resources.Daemonsets)), "State", ctrl.stateName)
 
-	for _, daemonSet := range ctrl.resources[idx].Daemonsets {
+	for _, daemonSet := range ctrl.resources.Daemonsets {
 		obj := daemonSet.DeepCopy()
-		// obj := ctrl.resources[idx].DaemonSet.DeepCopy()
+		// obj := ctrl.resources.DaemonSet.DeepCopy()
 		obj.Namespace = ctrl.operatorNamespace
 
 		logger := ctrl.rec.Log.WithValues("DaemonSet", obj.Name, "Namespace", obj.Namespace)
 
 		// Check if state is disabled and cleanup resource if exists
-		if !ctrl.isStateEnabled(ctrl.stateNames[ctrl.idx]) {
+		if !ctrl.isStateEnabled(ctrl.stateName) {
+			if _, err := ctrl.cleanupHostSetupJobs(obj.Name); err != nil {
+				logger.Error(err, "Couldn't delete Jobs")
+				result = policyv1.NotReady
+				continue
+			}
 			err := ctrl.rec.Client.Delete(context.TODO(), obj)
 			if err != nil && !errors.IsNotFound(err) {
 				logger.Error(err, "Couldn't delete")
+				recordEvent(ctrl, nil, corev1.EventTypeWarning, EventReasonDeleteFailed,
+					fmt.Sprintf("Failed to delete DaemonSet %s of disabled state %s: %v", obj.Name, ctrl.stateName, err))
 				result = policyv1.NotReady
 				continue
 			}
+			if err == nil {
+				recordEvent(ctrl, nil, corev1.EventTypeNormal, EventReasonDeleted,
+					fmt.Sprintf("Deleted DaemonSet %s as state %s is disabled", obj.Name, ctrl.stateName))
+			}
 			result = policyv1.Disabled
 			continue
 		}
@@ -287,11 +922,21 @@ func DaemonSet(ctrl ClusterPolicyController) (policyv1.State, error) {
 			if _, ok := err.(*NoSpecError); ok {
 				// no spec found for this daemonset
 				logger.Info(err.Error())
-				// delete the daemonset if it was deployed before
+				// delete the daemonset or the jobs if they were deployed before
+				if _, e := ctrl.cleanupHostSetupJobs(obj.Name); e != nil {
+					logger.Error(e, "Couldn't delete Jobs")
+					result = policyv1.NotReady
+					continue
+				}
 				e := ctrl.rec.Client.Delete(context.TODO(), obj)
 				if e != nil && !errors.IsNotFound(e) {
 					logger.Error(err, "Couldn't delete")
+					recordEvent(ctrl, nil, corev1.EventTypeWarning, EventReasonDeleteFailed,
+						fmt.Sprintf("Failed to delete DaemonSet %s with no spec found: %v", obj.Name, e))
 					result = policyv1.NotReady
+				} else if e == nil {
+					recordEvent(ctrl, nil, corev1.EventTypeNormal, EventReasonDeleted,
+						fmt.Sprintf("Deleted DaemonSet %s as no spec found in ClusterPolicy", obj.Name))
 				}
 				if result == policyv1.Ready {
 					result = policyv1.Ignored
@@ -303,65 +948,118 @@ func DaemonSet(ctrl ClusterPolicyController) (policyv1.State, error) {
 			continue
 		}
 
-		if err := controllerutil.SetControllerReference(ctrl.singleton, obj, ctrl.rec.Scheme); err != nil {
-			logger.Info("SetControllerReference failed", "Error", err)
-			result = policyv1.NotReady
-			continue
-		}
-
-		// Daemonsets will always have at least one annotation applied, so allocate if necessary
-		if obj.Annotations == nil {
-			obj.Annotations = make(map[string]string)
-		}
+		// schedule the pods only on the nodes where the states it depends on are ready
+		setDependencySelectors(obj, ctrl)
 
-		// check from the existed DaemonSet
-		found := &appsv1.DaemonSet{}
-		err = ctrl.rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: obj.Namespace, Name: obj.Name}, found)
-		if err != nil && errors.IsNotFound(err) {
-			logger.Info("DaemonSet not found, creating")
-			// generate hash for the spec to create
-			hashStr := getDaemonsetHash(obj)
-			// add annotation to the Daemonset with hash value during creation
-			obj.Annotations[XilinxAnnotationHashKey] = hashStr
-			err = ctrl.rec.Client.Create(context.TODO(), obj)
-			if err != nil {
-				logger.Error(err, "Couldn't create DaemonSet")
+		// host-setup in job mode runs the pod template in a Job per node instead of the DaemonSet
+		if ctrl.isHostSetupJobMode(ctrl.stateName) {
+			switch state, err := ctrl.reconcileHostSetupJobs(obj); {
+			case err != nil:
+				logger.Error(err, "Could not reconcile Jobs")
 				result = policyv1.NotReady
-			}
-			status := isDaemonSetReady(obj.Namespace, obj.Name, *ctrl.rec, logger)
-			if status == policyv1.NotReady {
+			case state == policyv1.NotReady:
 				result = policyv1.NotReady
+			case state == policyv1.NoMatchingNodes:
+				noMatchingNodes++
+			default:
+				scheduled++
 			}
 			continue
-		} else if err != nil {
-			logger.Error(err, "Failed to get DaemonSet from client")
+		}
+		// wait for the Jobs deployed before in job mode to be removed
+		if gone, err := ctrl.cleanupHostSetupJobs(obj.Name); err != nil || !gone {
+			if err != nil {
+				logger.Error(err, "Couldn't delete Jobs")
+			}
 			result = policyv1.NotReady
 			continue
 		}
 
-		// update the DaemonSet if it is existed already and needing to be updated
-		changed := isDaemonsetSpecChanged(found, obj)
-		if changed {
-			logger.Info("DaemonSet is different, updating")
-			err = ctrl.rec.Client.Update(context.TODO(), obj)
-			if err != nil {
-				logger.Error(err, "Failed to update DaemonSet")
-				result = policyv1.NotReady
-				continue
-			}
-		} else {
-			logger.Info("DaemonSet identical, skipping update")
+		if err := controllerutil.SetControllerReference(ctrl.singleton, obj, ctrl.rec.Scheme); err != nil {
+			logger.Info("SetControllerReference failed", "Error", err)
+			result = policyv1.NotReady
+			continue
 		}
 
-		if isDaemonSetReady(obj.Namespace, obj.Name, *ctrl.rec, logger) == policyv1.NotReady {
+		updated, err := applyObject(ctrl, "DaemonSet", obj, &appsv1.DaemonSet{})
+		if err != nil {
 			result = policyv1.NotReady
+			continue
+		}
+		if updated {
+			ctrl.operatorMetrics.daemonSetUpdates.WithLabelValues(obj.Name).Inc()
 		}
+
+		switch isDaemonSetReady(obj.Namespace, obj.Name, ctrl, logger) {
+		case policyv1.NotReady:
+			result = policyv1.NotReady
+		case policyv1.NoMatchingNodes:
+			noMatchingNodes++
+		default:
+			scheduled++
+		}
+	}
+
+	// the state has no matching nodes only if none of its DaemonSets is scheduled on any node,
+	// eg. the DaemonSets of host-setup for OS distributions absent from the cluster are fine
+	if result == policyv1.Ready && noMatchingNodes > 0 && scheduled == 0 {
+		result = policyv1.NoMatchingNodes
 	}
 	return result, nil
 }
 
+// setNFDImage sets the image of NFD as per ClusterPolicy
+func setNFDImage(podSpec *corev1.PodSpec, config *policyv1.ClusterPolicySpec) {
+	podSpec.Containers[0].Image = policyv1.ImagePath(config.NFD.Repository, config.NFD.Image, config.NFD.Tag)
+	podSpec.Containers[0].ImagePullPolicy = policyv1.ImagePullPolicy(config.NFD.ImagePullPolicy)
+	for _, secret := range config.NFD.ImagePullSecrets {
+		podSpec.ImagePullSecrets = append(podSpec.ImagePullSecrets, corev1.LocalObjectReference{Name: secret})
+	}
+}
+
+// TransformNFDMaster transforms the NFD master deployment with required config as per ClusterPolicy
+func TransformNFDMaster(obj *appsv1.Deployment, config *policyv1.ClusterPolicySpec, ctrl ControlContext) error {
+	setNFDImage(&obj.Spec.Template.Spec, config)
+	return nil
+}
+
+// TransformNFDWorker transforms the NFD worker daemonset with required config as per ClusterPolicy
+func TransformNFDWorker(obj *appsv1.DaemonSet, config *policyv1.ClusterPolicySpec, ctrl ControlContext) error {
+	setNFDImage(&obj.Spec.Template.Spec, config)
+	return nil
+}
+
+// TransformNodeLabeller transforms the node labeller daemonset with required config as per ClusterPolicy
+func TransformNodeLabeller(obj *appsv1.DaemonSet, config *policyv1.ClusterPolicySpec, ctrl ControlContext) error {
+	// update image and pull policy
+	obj.Spec.Template.Spec.Containers[0].Image = policyv1.OperatorImagePath(
+		config.NodeLabeller.Repository, config.NodeLabeller.Image, config.NodeLabeller.Tag)
+	obj.Spec.Template.Spec.Containers[0].ImagePullPolicy = policyv1.ImagePullPolicy(
+		config.NodeLabeller.ImagePullPolicy)
+
+	// set image pull secrets
+	for _, secret := range config.NodeLabeller.ImagePullSecrets {
+		obj.Spec.Template.Spec.ImagePullSecrets = append(
+			obj.Spec.Template.Spec.ImagePullSecrets, corev1.LocalObjectReference{Name: secret})
+	}
+
+	// detect the same PCI devices as the FPGA nodes selected by NFD labels
+	for _, device := range config.FPGANodes.PCIDevices {
+		pciDevice := labeller.PCIDevice{Vendor: device.Vendor, Class: device.Class, Device: device.Device}
+		obj.Spec.Template.Spec.Containers[0].Args = append(obj.Spec.Template.Spec.Containers[0].Args,
+			"--pci-device="+pciDevice.String())
+	}
+
+	// set resources and security context
+	setContainerOverrides(&(obj.Spec.Template.Spec.Containers[0]), &config.NodeLabeller.ContainerOverridesSpec)
+
+	// set scheduling and extra metadata of the pods, the labeller runs on all the nodes by default
+	setPodScheduling(obj, &config.NodeLabeller.PodSchedulingSpec)
+	return nil
+}
+
 // TransformContainerRuntime transforms Xilinx container runtime daemonset with required config as per ClusterPolicy
-func TransformContainerRuntime(obj *appsv1.DaemonSet, config *policyv1.ClusterPolicySpec, ctrl ClusterPolicyController) error {
+func TransformContainerRuntime(obj *appsv1.DaemonSet, config *policyv1.ClusterPolicySpec, ctrl ControlContext) error {
 
 	// udpate image and pull policy
 	image := policyv1.ImagePath(config.ContainerRuntime.Repository,
@@ -391,7 +1089,7 @@ func TransformContainerRuntime(obj *appsv1.DaemonSet, config *policyv1.ClusterPo
 		installDir = config.ContainerRuntime.InstallDir
 	}
 	installDirVolName := "install-dir"
-	installDirMountPath := "/host-usr/bin"
+	installDirMountPath := xcrInstallDirMountPath
 	obj.Spec.Template.Spec.Volumes = append(obj.Spec.Template.Spec.Volumes, corev1.Volume{
 		Name: installDirVolName,
 		VolumeSource: corev1.VolumeSource{
@@ -409,7 +1107,7 @@ func TransformContainerRuntime(obj *appsv1.DaemonSet, config *policyv1.ClusterPo
 	// XCR config directory mount
 	configDir := DefaultXCRConfigDir
 	configDirVolName := "config-dir"
-	configDirMountPath := "/host-etc/xilinx-container-runtime"
+	configDirMountPath := xcrConfigDirMountPath
 	obj.Spec.Template.Spec.Volumes = append(obj.Spec.Template.Spec.Volumes, corev1.Volume{
 		Name: configDirVolName,
 		VolumeSource: corev1.VolumeSource{
@@ -470,12 +1168,54 @@ func TransformContainerRuntime(obj *appsv1.DaemonSet, config *policyv1.ClusterPo
 	obj.Spec.Template.Spec.Containers[0].Args = []string{"-c", toolkitArg}
 
 	// set nodeSelector for daemonset
-	setDaemonSetSelector(obj, fpgaNodeLabels)
+	setNodeSelectors(obj, fpgaNodeSelectors(&config.FPGANodes, config.NodeLabeller.IsEnabled()))
+
+	// set resources and security context of runtime container
+	setContainerOverrides(&(obj.Spec.Template.Spec.Containers[0]), &config.ContainerRuntime.ContainerOverridesSpec)
+
+	// set scheduling and extra metadata of the pods
+	setPodScheduling(obj, &config.ContainerRuntime.PodSchedulingSpec)
 
 	return nil
 }
 
-func TransformDevicePlugin(obj *appsv1.DaemonSet, config *policyv1.ClusterPolicySpec, ctrl ClusterPolicyController) error {
+// TransformContainerRuntimeUninstall transforms Xilinx container runtime daemonset into a daemonset
+// reverting the runtime config and removing Xilinx container runtime from the nodes
+func TransformContainerRuntimeUninstall(obj *appsv1.DaemonSet, config *policyv1.ClusterPolicySpec, ctrl ControlContext) error {
+	// reuse image, mounts and node selector of the install daemonset
+	err := TransformContainerRuntime(obj, config, ctrl)
+	if err != nil {
+		return err
+	}
+
+	// use a different name and labels to not conflict with the install daemonset
+	name := obj.Spec.Template.Labels["name"] + xcrUninstallSuffix
+	obj.Name = obj.Name + xcrUninstallSuffix
+	obj.Labels = map[string]string{"app": name}
+	obj.Spec.Selector.MatchLabels = map[string]string{"name": name}
+	obj.Spec.Template.Labels["name"] = name
+
+	// uninstall in an init container, so the daemonset is ready once it is done on every node
+	installDir := DefaultXCRInstallDir
+	if config.ContainerRuntime.InstallDir != "" {
+		installDir = config.ContainerRuntime.InstallDir
+	}
+	runtimeConfigMountPath := "/runtime/config/" + path.Base(getRuntimeConfig(ctrl.runtime))
+	runtimeSocketMountPath := "/runtime/socket/" + path.Base(getRuntimeSocket(ctrl.runtime))
+	uninstallArgStrFmt := "xilinx-container-toolkit %s unset -p %s -r %s -c %s -s %s; xilinx-container-toolkit uninstall --install-dir %s --config-dir %s"
+	uninstallArg := fmt.Sprintf(uninstallArgStrFmt, ctrl.runtime.String(), installDir, getRuntimeClass(config),
+		runtimeConfigMountPath, runtimeSocketMountPath, xcrInstallDirMountPath, xcrConfigDirMountPath)
+
+	uninstall := obj.Spec.Template.Spec.Containers[0].DeepCopy()
+	uninstall.Name = name
+	uninstall.Args = []string{"-c", uninstallArg}
+	obj.Spec.Template.Spec.InitContainers = []corev1.Container{*uninstall}
+
+	obj.Spec.Template.Spec.Containers[0].Args = []string{"-c", "echo xilinx-container-runtime uninstalled; while true; do sleep 3600; done"}
+	return nil
+}
+
+func TransformDevicePlugin(obj *appsv1.DaemonSet, config *policyv1.ClusterPolicySpec, ctrl ControlContext) error {
 	// update image and pull policy
 	obj.Spec.Template.Spec.Containers[0].Image = policyv1.ImagePath(
 		config.DevicePlugin.Repository, config.DevicePlugin.Image, config.DevicePlugin.Tag)
@@ -498,11 +1238,17 @@ func TransformDevicePlugin(obj *appsv1.DaemonSet, config *policyv1.ClusterPolicy
 	}
 
 	// set node selector
-	setDaemonSetSelector(obj, fpgaNodeLabels)
+	setNodeSelectors(obj, fpgaNodeSelectors(&config.FPGANodes, config.NodeLabeller.IsEnabled()))
+
+	// set resources and security context
+	setContainerOverrides(&(obj.Spec.Template.Spec.Containers[0]), &config.DevicePlugin.ContainerOverridesSpec)
+
+	// set scheduling and extra metadata of the pods
+	setPodScheduling(obj, &config.DevicePlugin.PodSchedulingSpec)
 	return nil
 }
 
-func TransformHostSetup(obj *appsv1.DaemonSet, config *policyv1.ClusterPolicySpec, ctrl ClusterPolicyController) error {
+func TransformHostSetup(obj *appsv1.DaemonSet, config *policyv1.ClusterPolicySpec, ctrl ControlContext) error {
 	// get node selector from daemonset template
 	if obj.Spec.Template.Spec.NodeSelector == nil {
 		return fmt.Errorf("node selector of OS ID and version is missing")
@@ -520,6 +1266,23 @@ func TransformHostSetup(obj *appsv1.DaemonSet, config *policyv1.ClusterPolicySpe
 
 		ctrl.rec.Log.Info("Found spec for daemonset", "Name", obj.Name)
 
+		// match the OS labels of the built-in node labeller as well as the ones of NFD
+		if config.NodeLabeller.IsEnabled() {
+			osSelectors := []map[string]string{
+				{
+					nfdLabelOSReleaseID:    selector[nfdLabelOSReleaseID],
+					nfdLabelOsMajorVersion: selector[nfdLabelOsMajorVersion],
+				},
+				{
+					labeller.LabelOSReleaseID:    selector[nfdLabelOSReleaseID],
+					labeller.LabelOSMajorVersion: selector[nfdLabelOsMajorVersion],
+				},
+			}
+			delete(obj.Spec.Template.Spec.NodeSelector, nfdLabelOSReleaseID)
+			delete(obj.Spec.Template.Spec.NodeSelector, nfdLabelOsMajorVersion)
+			setNodeSelectors(obj, osSelectors)
+		}
+
 		// update iamge and pull policy
 		image := policyv1.ImagePath(osDistSpec.Repository, osDistSpec.Image, osDistSpec.Tag)
 		imagePullPolicy := policyv1.ImagePullPolicy(osDistSpec.ImagePullPolicy)
@@ -538,19 +1301,21 @@ func TransformHostSetup(obj *appsv1.DaemonSet, config *policyv1.ClusterPolicySpe
 			}
 		}
 
-		// install xrt and xrm
+		// install xrt and xrm, unless they are installed by the previous setup of the node
+		xrmInstallation := osDistSpec.XrmInstallation == nil || *osDistSpec.XrmInstallation
 		xrtInstallationStr := fmt.Sprintf("source ./host_setup.sh -y --skip-shell-flash -v %s; source ./xrm_setup.sh", osDistSpec.Version)
-		if osDistSpec.XrmInstallation != nil && !*osDistSpec.XrmInstallation {
+		if !xrmInstallation {
 			xrtInstallationStr = fmt.Sprintf("source ./host_setup.sh -y --skip-xrm-install --skip-shell-flash -v %s", osDistSpec.Version)
 		}
 		obj.Spec.Template.Spec.InitContainers[0].Command = []string{"/bin/bash"}
-		obj.Spec.Template.Spec.InitContainers[0].Args = []string{"-c", xrtInstallationStr}
+		obj.Spec.Template.Spec.InitContainers[0].Args = []string{"-c",
+			xrtInstallScript(osDistSpec.Version, xrmInstallation, xrtInstallationStr)}
 
 		// flash card
 		// check if shell flash is enabled
 		if osDistSpec.ShellFlashEnabled != nil && !*osDistSpec.ShellFlashEnabled {
 			obj.Spec.Template.Spec.InitContainers[1].Command = []string{"/bin/bash"}
-			obj.Spec.Template.Spec.InitContainers[1].Args = []string{"-c", "echo card flash is disabled"}
+			obj.Spec.Template.Spec.InitContainers[1].Args = []string{"-c", cardFlashScript("echo card flash is disabled")}
 		} else {
 
 			// basic flash card string
@@ -568,12 +1333,29 @@ func TransformHostSetup(obj *appsv1.DaemonSet, config *policyv1.ClusterPolicySpe
 
 			// set command args
 			obj.Spec.Template.Spec.InitContainers[1].Command = []string{"/bin/bash"}
-			obj.Spec.Template.Spec.InitContainers[1].Args = []string{"-c", cardFlashStr}
+			obj.Spec.Template.Spec.InitContainers[1].Args = []string{"-c", cardFlashScript(cardFlashStr)}
 
 		}
-		// set command args
+		// set command args, the pod of a Job completes once the node is set up
+		hostSetupCompleteStr := "echo host_setup complete, please refer to logs from init containers for further steps"
+		if !config.HostSetup.IsJobMode() {
+			hostSetupCompleteStr += "; while true; do sleep 3600; done"
+		}
 		obj.Spec.Template.Spec.Containers[0].Command = []string{"/bin/bash"}
-		obj.Spec.Template.Spec.Containers[0].Args = []string{"-c", "echo host_setup complete, please refer to logs from init containers for further steps; while true; do sleep 3600; done"}
+		obj.Spec.Template.Spec.Containers[0].Args = []string{"-c", hostSetupCompleteStr}
+
+		// set resources and security context of init containers and main container
+		setContainerOverrides(&(obj.Spec.Template.Spec.InitContainers[0]), osDistSpec.InitXrtXrm)
+		setContainerOverrides(&(obj.Spec.Template.Spec.InitContainers[1]), osDistSpec.InitCardFlash)
+		setContainerOverrides(&(obj.Spec.Template.Spec.Containers[0]), &osDistSpec.ContainerOverridesSpec)
+
+		// report the setup of the node in the node annotations
+		if config.HostSetup.Reporter.IsEnabled() {
+			setHostSetupReporter(obj, config)
+		}
+
+		// set scheduling and extra metadata of the pods
+		setPodScheduling(obj, &osDistSpec.PodSchedulingSpec)
 		return nil
 	}
 
@@ -19,27 +19,36 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
 	goruntime "runtime"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/bombsimon/logrusr/v3"
 	"github.com/sirupsen/logrus"
 
 	"github.com/stretchr/testify/require"
 	policyv1 "github.com/xilinx/fpga-operator/api/v1"
+	"github.com/xilinx/fpga-operator/labeller"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	nodev1 "k8s.io/api/node/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer/json"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
@@ -51,6 +60,9 @@ const (
 	devicePluginAssestsPath     = "assets/state-device-plugin"
 	containerRuntimeAssestsPath = "assets/state-container-runtime"
 	hostSetupAssestsPath        = "assets/state-host-setup"
+	nodeLabellerAssestsPath     = "assets/state-node-labeller"
+	nfdAssestsPath              = "assets/state-nfd"
+	testNamespace               = "fpga-operator"
 )
 
 type testConfig struct {
@@ -70,9 +82,9 @@ type commonDaemonsetSpec struct {
 
 var (
 	cfg                     *testConfig
-	clusterPolicyController ClusterPolicyController
 	clusterPolicyReconciler ClusterPolicyReconciler
 	clusterPolicy           policyv1.ClusterPolicy
+	eventRecorder           *record.FakeRecorder
 	boolTrue                *bool
 	boolFalse               *bool
 )
@@ -177,8 +189,15 @@ var nodes = []corev1.Node{
 }
 
 var kubernetesResources = []client.Object{
+	&corev1.ServiceAccount{},
+	&corev1.Service{},
+	&rbacv1.ClusterRole{},
+	&rbacv1.ClusterRoleBinding{},
 	&appsv1.DaemonSet{},
+	&appsv1.Deployment{},
 	&nodev1.RuntimeClass{},
+	&batchv1.Job{},
+	&corev1.Pod{},
 }
 
 func getModuleRoot(dir string) (string, error) {
@@ -197,8 +216,8 @@ func getModuleRoot(dir string) (string, error) {
 
 // newCluster creates a mock kubernetes cluster and returns the corresponding client object
 func newCluster(nodeCount int, s *runtime.Scheme) (client.Client, error) {
-	// Build fake client
-	cl := fake.NewClientBuilder().WithScheme(s).Build()
+	// Build fake client, which applies objects server-side as the API server
+	cl := newApplyClient(fake.NewClientBuilder().WithScheme(s).Build())
 
 	for i := 0; i < nodeCount; i++ {
 
@@ -213,9 +232,8 @@ func newCluster(nodeCount int, s *runtime.Scheme) (client.Client, error) {
 
 // setup creates a mock kubernetes cluster and client. Nodes are labeled with the minumum
 // required NFD labels to be detected as FPGA nodes by the FPGA Operator. A sample
-// ClusterPolicy resource is applied to the cluster. The ClusterPolicyController
-// object is initialized with the mock kubernetes client as well as other steps
-// mimicking init() in state_manager.go
+// ClusterPolicy resource is applied to the cluster. The ClusterPolicyReconciler
+// is initialized with the mock kubernetes client
 func setup() error {
 	// Used when updating ClusterPolicy spec
 	boolFalse = new(bool)
@@ -235,9 +253,14 @@ func setup() error {
 		return fmt.Errorf("unable to create cluster: %v", err)
 	}
 
+	logger := logrusr.New(logrus.New())
+	ctrl.SetLogger(logger)
+
 	// Get a sample ClusterPolicy manifest
-	manifests := getAssetsFrom(&clusterPolicyController, filepath.Join(cfg.root, clusterPolicyPath))
-	clusterPolicyManifest := manifests[0]
+	clusterPolicyManifest, err := os.ReadFile(filepath.Join(cfg.root, clusterPolicyPath))
+	if err != nil {
+		return fmt.Errorf("unable to read sample ClusterPolicy manifest: %v", err)
+	}
 	ser := json.NewYAMLSerializer(json.DefaultMetaFactory, scheme.Scheme, scheme.Scheme)
 	_, _, err = ser.Decode(clusterPolicyManifest, nil, &clusterPolicy)
 	if err != nil {
@@ -256,34 +279,51 @@ func setup() error {
 		return fmt.Errorf("unable to get ClusterPolicy from client: %v", err)
 	}
 
-	logger := logrusr.New(logrus.New())
-	ctrl.SetLogger(logger)
-
+	eventRecorder = record.NewFakeRecorder(100)
 	clusterPolicyReconciler = ClusterPolicyReconciler{
-		Client: client,
-		Log:    ctrl.Log.WithName("controller").WithName("ClusterPolicy"),
-		Scheme: s,
+		Client:   client,
+		Log:      ctrl.Log.WithName("controller").WithName("ClusterPolicy"),
+		Scheme:   s,
+		Recorder: eventRecorder,
 	}
 
-	clusterPolicyController = ClusterPolicyController{
-		singleton: cp,
-		rec:       &clusterPolicyReconciler,
-	}
-
-	hasNFDLabels, fpgaNodeCount, err := clusterPolicyController.getFPGANodeCount()
+	n, err := newTestContext(cp)
 	if err != nil {
 		return fmt.Errorf("unable to label nodes in cluster: %v", err)
 	}
-	if fpgaNodeCount == 0 {
+	if !n.hasFPGANodes {
 		return fmt.Errorf("no gpu nodes in mock cluster")
 	}
 
-	clusterPolicyController.hasFPGANodes = fpgaNodeCount != 0
-	clusterPolicyController.hasNFDLabels = hasNFDLabels
-
 	return nil
 }
 
+// newTestContext returns the context to reconcile cp in the mock cluster with the states loaded from paths.
+// It mimics newControlContext() in state_manager.go, without the Kubernetes version and metrics
+func newTestContext(cp *policyv1.ClusterPolicy, paths ...string) (*ControlContext, error) {
+	n := &ControlContext{
+		singleton:         cp,
+		rec:               &clusterPolicyReconciler,
+		operatorNamespace: testNamespace,
+		operatorMetrics:   newOperatorMetrics(),
+	}
+	for _, path := range paths {
+		st, err := loadState(n.rec.Log, filepath.Base(path), []fs.FS{os.DirFS(filepath.Dir(path))}, nil)
+		if err != nil {
+			return nil, err
+		}
+		n.states = append(n.states, st)
+	}
+
+	hasNFDLabels, fpgaNodeCount, err := n.getFPGANodeCount()
+	if err != nil {
+		return nil, err
+	}
+	n.hasFPGANodes = fpgaNodeCount != 0
+	n.hasNFDLabels = hasNFDLabels
+	return n, nil
+}
+
 func TestMain(m *testing.M) {
 	_, filename, _, _ := goruntime.Caller(0)
 	moduleRoot, err := getModuleRoot(filename)
@@ -302,33 +342,45 @@ func TestMain(m *testing.M) {
 }
 
 // updateClusterPolicy updates an existing ClusterPolicy instance
-func updateClusterPolicy(n *ClusterPolicyController, cp *policyv1.ClusterPolicy) error {
-	n.singleton = cp
-	err := n.rec.Client.Update(context.TODO(), cp)
+func updateClusterPolicy(cp *policyv1.ClusterPolicy) error {
+	err := clusterPolicyReconciler.Client.Update(context.TODO(), cp)
 	if err != nil && !errors.IsConflict(err) {
 		return fmt.Errorf("failed to update ClusterPolicy: %v", err)
 	}
 	return nil
 }
 
-// removeState deletes all resources, controls, and stateNames tracked
-// by ClusterPolicyController at a specific index. It also deletes
-// all objects from the mock k8s client
-func removeState(n *ClusterPolicyController, idx int) error {
-	var err error
+// deleteResources deletes all objects of kubernetesResources from the mock k8s client,
+// and drains the events recorded so the fake recorder never blocks
+func deleteResources() error {
+	drainEvents(eventRecorder)
+
 	for _, res := range kubernetesResources {
-		// TODO: use n.operatorNamespace once MR is merged
-		err = n.rec.Client.DeleteAllOf(context.TODO(), res)
+		err := clusterPolicyReconciler.Client.DeleteAllOf(context.TODO(), res, client.InNamespace(testNamespace))
+		if err != nil {
+			return fmt.Errorf("error deleting objects from k8s client: %v", err)
+		}
+		// cluster scoped objects
+		err = clusterPolicyReconciler.Client.DeleteAllOf(context.TODO(), res)
 		if err != nil {
 			return fmt.Errorf("error deleting objects from k8s client: %v", err)
 		}
 	}
-	n.resources = append(n.resources[:idx], n.resources[idx+1:]...)
-	n.controlFuncs = append(n.controlFuncs[:idx], n.controlFuncs[idx+1:]...)
-	n.stateNames = append(n.stateNames[:idx], n.stateNames[idx+1:]...)
 	return nil
 }
 
+func drainEvents(recorder *record.FakeRecorder) []string {
+	events := []string{}
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
 // getImagePullSecrets converts a slice of strings (pull secrets)
 // to the corev1 type used by k8s
 func getImagePullSecrets(secrets []string) []corev1.LocalObjectReference {
@@ -339,6 +391,8 @@ func getImagePullSecrets(secrets []string) []corev1.LocalObjectReference {
 	return ret
 }
 
+// testDaemonsetCommon deploys the state of component for cp, and returns the daemonsets deployed.
+// The objects deployed are deleted when the test completes
 func testDaemonsetCommon(t *testing.T, cp *policyv1.ClusterPolicy, component string, numDaemonsets int) ([]appsv1.DaemonSet, error) {
 	var spec commonDaemonsetSpec
 	var dsLabel, mainCtrName, manifestFile string
@@ -380,23 +434,51 @@ func testDaemonsetCommon(t *testing.T, cp *policyv1.ClusterPolicy, component str
 		dsLabel = "host-setup"
 		mainCtrName = "host-setup-ubuntu"
 		manifestFile = filepath.Join(cfg.root, hostSetupAssestsPath)
+	case "NodeLabeller":
+		spec = commonDaemonsetSpec{
+			repository:       cp.Spec.NodeLabeller.Repository,
+			image:            cp.Spec.NodeLabeller.Image,
+			tag:              cp.Spec.NodeLabeller.Tag,
+			imagePullPolicy:  cp.Spec.NodeLabeller.ImagePullPolicy,
+			imagePullSecrets: getImagePullSecrets(cp.Spec.NodeLabeller.ImagePullSecrets),
+		}
+		dsLabel = "node-labeller"
+		mainCtrName = "node-labeller"
+		manifestFile = filepath.Join(cfg.root, nodeLabellerAssestsPath)
+	case "NFD":
+		spec = commonDaemonsetSpec{
+			repository:       cp.Spec.NFD.Repository,
+			image:            cp.Spec.NFD.Image,
+			tag:              cp.Spec.NFD.Tag,
+			imagePullPolicy:  cp.Spec.NFD.ImagePullPolicy,
+			imagePullSecrets: getImagePullSecrets(cp.Spec.NFD.ImagePullSecrets),
+		}
+		dsLabel = "nfd"
+		mainCtrName = "worker"
+		manifestFile = filepath.Join(cfg.root, nfdAssestsPath)
 
 	default:
 		return nil, fmt.Errorf("invalid component for testDaemonsetCommon(): %s", component)
 	}
 
 	// update cluster policy
-	err = updateClusterPolicy(&clusterPolicyController, cp)
+	err = updateClusterPolicy(cp)
 	if err != nil {
 		t.Fatalf("error in test setup: %v", err)
 	}
 	// add manifests
-	err = addState(&clusterPolicyController, manifestFile)
+	n, err := newTestContext(cp, manifestFile)
 	if err != nil {
 		t.Fatalf("unable to add state: %v", err)
 	}
+	// cleanup by deleting all kubernetes objects
+	t.Cleanup(func() {
+		if err := deleteResources(); err != nil {
+			t.Fatalf("error removing state: %v", err)
+		}
+	})
 	// create resources
-	_, err = clusterPolicyController.step()
+	_, err = n.step(0)
 	if err != nil {
 		t.Errorf("error creating resources: %v", err)
 	}
@@ -405,7 +487,7 @@ func testDaemonsetCommon(t *testing.T, cp *policyv1.ClusterPolicy, component str
 		client.MatchingLabels{"app": dsLabel},
 	}
 	list := &appsv1.DaemonSetList{}
-	err = clusterPolicyController.rec.Client.List(context.TODO(), list, opts...)
+	err = clusterPolicyReconciler.Client.List(context.TODO(), list, opts...)
 	if err != nil {
 		t.Fatalf("could not get DaemonSetList from client: %v", err)
 	}
@@ -455,6 +537,30 @@ func getDevicePluginTestInput(testCase string) *policyv1.ClusterPolicy {
 	switch testCase {
 	case "default":
 		// Do nothing
+	case "pci devices":
+		cp.Spec.FPGANodes.PCIDevices = []policyv1.PCIDeviceSpec{
+			{Vendor: "10ee", Class: "1200"},
+			{Vendor: "1022", Class: "1200"},
+		}
+		cp.Spec.DevicePlugin.Affinity = &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{
+							{Key: "example.com/pool", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"cpu"}},
+						},
+					}},
+				},
+			},
+		}
+	case "scheduling":
+		cp.Spec.DevicePlugin.NodeSelector = map[string]string{"example.com/pool": "fpga"}
+		cp.Spec.DevicePlugin.Tolerations = []corev1.Toleration{
+			{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "fpga", Effect: corev1.TaintEffectNoSchedule},
+		}
+		cp.Spec.DevicePlugin.PriorityClassName = "fpga-critical"
+		cp.Spec.DevicePlugin.PodLabels = map[string]string{"example.com/team": "fpga", "name": "overridden"}
+		cp.Spec.DevicePlugin.PodAnnotations = map[string]string{"example.com/scrape": "true"}
 	default:
 		return nil
 	}
@@ -466,8 +572,57 @@ func getDevicePluginTestOutput(testCase string) map[string]interface{} {
 	output := map[string]interface{}{
 		"numDaemonsets": 1,
 		"image":         "public.ecr.aws/xilinx_dcg/k8s-device-plugin:1.1.0",
+		"nodeSelector": map[string]string{
+			"feature.node.kubernetes.io/pci-1200_10ee.present": "true",
+			"state.fpga.xilinx.com/host-setup":                 "ready",
+		},
+		"affinity":          (*corev1.Affinity)(nil),
+		"tolerations":       []corev1.Toleration(nil),
+		"priorityClassName": "system-node-critical",
+		"labels":            map[string]string{"name": "device-plugin"},
+		"annotations":       map[string]string{"scheduler.alpha.kubernetes.io/critical-pod": ""},
 	}
 
+	switch testCase {
+	case "default":
+		// Do nothing
+	case "pci devices":
+		notCPUPool := corev1.NodeSelectorRequirement{Key: "example.com/pool", Operator: corev1.NodeSelectorOpNotIn, Values: []string{"cpu"}}
+		output["nodeSelector"] = map[string]string{"state.fpga.xilinx.com/host-setup": "ready"}
+		output["affinity"] = &corev1.Affinity{
+			NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{MatchExpressions: []corev1.NodeSelectorRequirement{
+							{Key: "feature.node.kubernetes.io/pci-1200_10ee.present", Operator: corev1.NodeSelectorOpIn, Values: []string{"true"}},
+							notCPUPool,
+						}},
+						{MatchExpressions: []corev1.NodeSelectorRequirement{
+							{Key: "feature.node.kubernetes.io/pci-1200_1022.present", Operator: corev1.NodeSelectorOpIn, Values: []string{"true"}},
+							notCPUPool,
+						}},
+					},
+				},
+			},
+		}
+	case "scheduling":
+		output["nodeSelector"] = map[string]string{
+			"feature.node.kubernetes.io/pci-1200_10ee.present": "true",
+			"example.com/pool":                 "fpga",
+			"state.fpga.xilinx.com/host-setup": "ready",
+		}
+		output["tolerations"] = []corev1.Toleration{
+			{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "fpga", Effect: corev1.TaintEffectNoSchedule},
+		}
+		output["priorityClassName"] = "fpga-critical"
+		output["labels"] = map[string]string{"name": "device-plugin", "example.com/team": "fpga"}
+		output["annotations"] = map[string]string{
+			"scheduler.alpha.kubernetes.io/critical-pod": "",
+			"example.com/scrape":                         "true",
+		}
+	default:
+		return nil
+	}
 	return output
 }
 
@@ -482,6 +637,16 @@ func TestDevicePlugin(t *testing.T) {
 			getDevicePluginTestInput("default"),
 			getDevicePluginTestOutput("default"),
 		},
+		{
+			"scheduling",
+			getDevicePluginTestInput("scheduling"),
+			getDevicePluginTestOutput("scheduling"),
+		},
+		{
+			"pci devices",
+			getDevicePluginTestInput("pci devices"),
+			getDevicePluginTestOutput("pci devices"),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -497,12 +662,16 @@ func TestDevicePlugin(t *testing.T) {
 			image := dsList[0].Spec.Template.Spec.Containers[0].Image
 			require.Equal(t, tc.output["image"], image, "Unexpected configuration for device-plugin image")
 
-			// cleanup by deleting all kubernetes objects
-			err = removeState(&clusterPolicyController, clusterPolicyController.idx-1)
-			if err != nil {
-				t.Fatalf("error removing state: %v", err)
-			}
-			clusterPolicyController.idx--
+			podSpec := dsList[0].Spec.Template.Spec
+			require.Equal(t, tc.output["nodeSelector"], podSpec.NodeSelector, "Unexpected node selector for device-plugin")
+			require.Equal(t, tc.output["affinity"], podSpec.Affinity, "Unexpected affinity for device-plugin")
+			require.Equal(t, tc.output["tolerations"], podSpec.Tolerations, "Unexpected tolerations for device-plugin")
+			require.Equal(t, tc.output["priorityClassName"], podSpec.PriorityClassName, "Unexpected priority class for device-plugin")
+			require.Equal(t, tc.output["labels"], dsList[0].Spec.Template.Labels, "Unexpected pod labels for device-plugin")
+			require.Equal(t, tc.output["annotations"], dsList[0].Spec.Template.Annotations, "Unexpected pod annotations for device-plugin")
+
+			events := drainEvents(eventRecorder)
+			require.Contains(t, events, "Normal Created Created DaemonSet device-plugin-daemonset", "creation event not recorded")
 		})
 	}
 }
@@ -567,12 +736,8 @@ func TestContainerRuntime(t *testing.T) {
 			image := dsList[0].Spec.Template.Spec.Containers[0].Image
 			require.Equal(t, tc.output["image"], image, "Unexpected configuration for container-runtime image")
 
-			// cleanup by deleting all kubernetes objects
-			err = removeState(&clusterPolicyController, clusterPolicyController.idx-1)
-			if err != nil {
-				t.Fatalf("error removing state: %v", err)
-			}
-			clusterPolicyController.idx--
+			events := drainEvents(eventRecorder)
+			require.Contains(t, events, "Normal Created Created RuntimeClass xilinx", "creation event not recorded")
 		})
 	}
 }
@@ -584,6 +749,34 @@ func getHostSetupTestInput(testCase string) *policyv1.ClusterPolicy {
 	switch testCase {
 	case "default":
 		// Do nothing
+	case "scheduling":
+		for i := range cp.Spec.HostSetup.OsDists {
+			cp.Spec.HostSetup.OsDists[i].NodeSelector = map[string]string{"example.com/pool": "fpga"}
+			cp.Spec.HostSetup.OsDists[i].Tolerations = []corev1.Toleration{
+				{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "fpga", Effect: corev1.TaintEffectNoSchedule},
+			}
+		}
+	case "resources":
+		for i := range cp.Spec.HostSetup.OsDists {
+			cp.Spec.HostSetup.OsDists[i].InitXrtXrm = &policyv1.ContainerOverridesSpec{
+				Resources: &corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+				},
+				SecurityContext: &corev1.SecurityContext{RunAsUser: new(int64)},
+			}
+		}
+	case "node labeller":
+		cp.Spec.NodeLabeller.Enabled = boolTrue
+	case "reporter":
+		cp.Spec.HostSetup.Reporter = policyv1.HostSetupReporterSpec{
+			Enabled:    boolTrue,
+			Repository: "public.ecr.aws/xilinx_dcg",
+			Image:      "fpga-operator",
+			Tag:        "1.0.0",
+		}
+	case "reporter of operator version":
+		cp.Spec.HostSetup.Reporter = policyv1.HostSetupReporterSpec{Enabled: boolTrue}
+		cp.Spec.HostSetup.SetDefaults()
 	default:
 		return nil
 	}
@@ -594,12 +787,27 @@ func getHostSetupTestInput(testCase string) *policyv1.ClusterPolicy {
 func getHostSetupTestOutput(testCase string) map[string]interface{} {
 	// default output
 	output := map[string]interface{}{
-		"numDaemonSets": 4,
+		"numDaemonSets":  4,
+		"numTolerations": 2,
+		"nodeSelector":   map[string]string{},
 	}
 
 	switch testCase {
 	case "default":
 		// Do nothing
+	case "scheduling":
+		output["numTolerations"] = 3
+		output["nodeSelector"] = map[string]string{"example.com/pool": "fpga"}
+	case "resources":
+		output["initResources"] = corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("512Mi")},
+		}
+	case "node labeller":
+		output["osAffinity"] = true
+	case "reporter":
+		output["reporterImage"] = "public.ecr.aws/xilinx_dcg/fpga-operator:1.0.0"
+	case "reporter of operator version":
+		output["reporterImage"] = "public.ecr.aws/xilinx_dcg/fpga-operator:" + policyv1.OperatorVersion
 	default:
 		return nil
 	}
@@ -618,6 +826,31 @@ func TestHostSetup(t *testing.T) {
 			getHostSetupTestInput("default"),
 			getHostSetupTestOutput("default"),
 		},
+		{
+			"scheduling",
+			getHostSetupTestInput("scheduling"),
+			getHostSetupTestOutput("scheduling"),
+		},
+		{
+			"resources",
+			getHostSetupTestInput("resources"),
+			getHostSetupTestOutput("resources"),
+		},
+		{
+			"node labeller",
+			getHostSetupTestInput("node labeller"),
+			getHostSetupTestOutput("node labeller"),
+		},
+		{
+			"reporter",
+			getHostSetupTestInput("reporter"),
+			getHostSetupTestOutput("reporter"),
+		},
+		{
+			"reporter of operator version",
+			getHostSetupTestInput("reporter of operator version"),
+			getHostSetupTestOutput("reporter of operator version"),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -630,12 +863,714 @@ func TestHostSetup(t *testing.T) {
 				return
 			}
 
-			// cleanup by deleting all kubernetes objects
-			err = removeState(&clusterPolicyController, clusterPolicyController.idx-1)
+			for _, ds := range dsList {
+				podSpec := ds.Spec.Template.Spec
+				if _, ok := tc.output["osAffinity"]; ok {
+					// OS labels of either NFD or the node labeller are matched
+					require.NotContains(t, podSpec.NodeSelector, nfdLabelOSReleaseID, "Unexpected OS node selector for host-setup")
+					terms := podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+					require.Len(t, terms, 2, "Unexpected OS node affinity for host-setup")
+					require.Equal(t, labeller.LabelOSReleaseID, terms[1].MatchExpressions[0].Key)
+					require.Equal(t, labeller.LabelOSMajorVersion, terms[1].MatchExpressions[1].Key)
+				} else {
+					require.Contains(t, podSpec.NodeSelector, nfdLabelOSReleaseID, "OS node selector missing for host-setup")
+				}
+				for k, v := range tc.output["nodeSelector"].(map[string]string) {
+					require.Equal(t, v, podSpec.NodeSelector[k], "Unexpected node selector for host-setup")
+				}
+				require.Len(t, podSpec.Tolerations, tc.output["numTolerations"].(int), "Unexpected tolerations for host-setup")
+
+				initXrtXrm := podSpec.InitContainers[0]
+				require.True(t, *initXrtXrm.SecurityContext.Privileged, "init-xrt-xrm is not privileged")
+				if resources, ok := tc.output["initResources"]; ok {
+					require.Equal(t, resources, initXrtXrm.Resources, "Unexpected resources for init-xrt-xrm")
+					require.Equal(t, int64(0), *initXrtXrm.SecurityContext.RunAsUser, "Unexpected security context for init-xrt-xrm")
+					require.Empty(t, podSpec.InitContainers[1].Resources, "Unexpected resources for init-card-flash")
+				}
+				require.Contains(t, initXrtXrm.Args[1], "already installed, skipping", "init-xrt-xrm does not skip the installed XRT")
+				require.Contains(t, podSpec.InitContainers[1].Args[1], "record RESULT=Succeeded", "init-card-flash does not record the result")
+
+				image, ok := tc.output["reporterImage"]
+				if !ok {
+					require.Len(t, podSpec.Containers, 1, "Unexpected reporter for host-setup")
+					require.Nil(t, podSpec.AutomountServiceAccountToken, "Unexpected token mounted for host-setup")
+					continue
+				}
+				require.Len(t, podSpec.Containers, 2, "Reporter missing for host-setup")
+				reporter := podSpec.Containers[1]
+				require.Equal(t, "host-setup-reporter", reporter.Name)
+				require.Equal(t, image, reporter.Image, "Unexpected image for reporter")
+				require.Equal(t, []string{"--host-root=/host"}, reporter.Args, "Unexpected args for reporter")
+				require.True(t, *podSpec.AutomountServiceAccountToken, "Token not mounted for reporter")
+				for _, mount := range reporter.VolumeMounts {
+					require.True(t, mount.ReadOnly, "Host path %s is writable by reporter", mount.MountPath)
+				}
+				require.Contains(t, podSpec.Volumes, corev1.Volume{Name: "host-sys", VolumeSource: corev1.VolumeSource{
+					HostPath: &corev1.HostPathVolumeSource{Path: "/sys"},
+				}}, "sysfs not mounted for reporter")
+			}
+		})
+	}
+}
+
+func getNodeLabellerTestInput(testCase string) *policyv1.ClusterPolicy {
+	// default cluster policy
+	cp := clusterPolicy.DeepCopy()
+	cp.Spec.NodeLabeller.Enabled = boolTrue
+	cp.Spec.NodeLabeller.Repository = "public.ecr.aws/xilinx_dcg"
+	cp.Spec.NodeLabeller.Image = "fpga-operator"
+	cp.Spec.NodeLabeller.Tag = "1.0.0"
+
+	switch testCase {
+	case "default":
+		// Do nothing
+	case "disabled":
+		cp.Spec.NodeLabeller.Enabled = boolFalse
+	case "operator version":
+		cp.Spec.NodeLabeller.Tag = ""
+	case "pci devices":
+		cp.Spec.FPGANodes.PCIDevices = []policyv1.PCIDeviceSpec{
+			{Vendor: "10ee", Class: "1200"},
+			{Vendor: "10ee", Device: "5000"},
+		}
+	default:
+		return nil
+	}
+	return cp
+}
+
+func getNodeLabellerTestOutput(testCase string) map[string]interface{} {
+	// default output
+	output := map[string]interface{}{
+		"numDaemonSets": 1,
+		"image":         "public.ecr.aws/xilinx_dcg/fpga-operator:1.0.0",
+		"args":          []string{"--host-root=/host"},
+	}
+
+	switch testCase {
+	case "default":
+		// Do nothing
+	case "disabled":
+		output["numDaemonSets"] = 0
+	case "operator version":
+		output["image"] = "public.ecr.aws/xilinx_dcg/fpga-operator:" + policyv1.OperatorVersion
+	case "pci devices":
+		output["args"] = []string{"--host-root=/host", "--pci-device=10ee:1200", "--pci-device=10ee::5000"}
+	default:
+		return nil
+	}
+	return output
+}
+
+func TestNodeLabeller(t *testing.T) {
+	testCases := []struct {
+		description   string
+		clusterpolicy *policyv1.ClusterPolicy
+		output        map[string]interface{}
+	}{
+		{
+			"default",
+			getNodeLabellerTestInput("default"),
+			getNodeLabellerTestOutput("default"),
+		},
+		{
+			"disabled",
+			getNodeLabellerTestInput("disabled"),
+			getNodeLabellerTestOutput("disabled"),
+		},
+		{
+			"operator version",
+			getNodeLabellerTestInput("operator version"),
+			getNodeLabellerTestOutput("operator version"),
+		},
+		{
+			"pci devices",
+			getNodeLabellerTestInput("pci devices"),
+			getNodeLabellerTestOutput("pci devices"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			dsList, err := testDaemonsetCommon(t, tc.clusterpolicy, "NodeLabeller", tc.output["numDaemonSets"].(int))
+			if err != nil {
+				t.Fatalf("error in testDaemonsetCommon(): %v", err)
+			}
+
+			if dsList != nil {
+				podSpec := dsList[0].Spec.Template.Spec
+				require.Equal(t, tc.output["image"], podSpec.Containers[0].Image, "Unexpected configuration for node-labeller image")
+				require.Equal(t, tc.output["args"], podSpec.Containers[0].Args, "Unexpected args for node-labeller")
+				require.Empty(t, podSpec.NodeSelector, "node-labeller is not deployed on all the nodes")
+				require.Nil(t, podSpec.Affinity, "node-labeller is not deployed on all the nodes")
+
+				// the node labeller is bound to its cluster role in the operator namespace
+				binding := &rbacv1.ClusterRoleBinding{}
+				err = clusterPolicyReconciler.Client.Get(context.TODO(), types.NamespacedName{Name: "fpga-operator-node-labeller"}, binding)
+				require.NoError(t, err, "cluster role binding is not created")
+				require.Equal(t, testNamespace, binding.Subjects[0].Namespace, "Unexpected namespace of service account")
+				sa := &corev1.ServiceAccount{}
+				err = clusterPolicyReconciler.Client.Get(context.TODO(),
+					types.NamespacedName{Namespace: testNamespace, Name: podSpec.ServiceAccountName}, sa)
+				require.NoError(t, err, "service account is not created")
+			}
+		})
+	}
+}
+
+func getNFDTestInput(testCase string) *policyv1.ClusterPolicy {
+	// default cluster policy
+	cp := clusterPolicy.DeepCopy()
+	cp.Spec.NFD.Enabled = boolTrue
+	cp.Spec.NFD.Repository = "registry.k8s.io/nfd"
+	cp.Spec.NFD.Image = "node-feature-discovery"
+	cp.Spec.NFD.Tag = "v0.12.0"
+
+	switch testCase {
+	case "default":
+		// Do nothing
+	case "disabled":
+		cp.Spec.NFD.Enabled = boolFalse
+	case "image pull secrets":
+		cp.Spec.NFD.ImagePullSecrets = []string{"pull-secret"}
+	default:
+		return nil
+	}
+	return cp
+}
+
+func getNFDTestOutput(testCase string) map[string]interface{} {
+	// default output
+	output := map[string]interface{}{
+		"numDaemonSets":    1,
+		"numDeployments":   1,
+		"image":            "registry.k8s.io/nfd/node-feature-discovery:v0.12.0",
+		"imagePullSecrets": []corev1.LocalObjectReference(nil),
+	}
+
+	switch testCase {
+	case "default":
+		// Do nothing
+	case "disabled":
+		output["numDaemonSets"] = 0
+		output["numDeployments"] = 0
+	case "image pull secrets":
+		output["imagePullSecrets"] = getImagePullSecrets([]string{"pull-secret"})
+	default:
+		return nil
+	}
+	return output
+}
+
+func TestNFD(t *testing.T) {
+	testCases := []struct {
+		description   string
+		clusterpolicy *policyv1.ClusterPolicy
+		output        map[string]interface{}
+	}{
+		{
+			"default",
+			getNFDTestInput("default"),
+			getNFDTestOutput("default"),
+		},
+		{
+			"disabled",
+			getNFDTestInput("disabled"),
+			getNFDTestOutput("disabled"),
+		},
+		{
+			"image pull secrets",
+			getNFDTestInput("image pull secrets"),
+			getNFDTestOutput("image pull secrets"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			dsList, err := testDaemonsetCommon(t, tc.clusterpolicy, "NFD", tc.output["numDaemonSets"].(int))
 			if err != nil {
-				t.Fatalf("error removing state: %v", err)
+				t.Fatalf("error in testDaemonsetCommon(): %v", err)
 			}
-			clusterPolicyController.idx--
+
+			deployments := &appsv1.DeploymentList{}
+			err = clusterPolicyReconciler.Client.List(context.TODO(), deployments, client.MatchingLabels{"app": "nfd"})
+			require.NoError(t, err, "could not get DeploymentList from client")
+			require.Equal(t, tc.output["numDeployments"], len(deployments.Items), "unexpected # of deployments")
+
+			if dsList != nil {
+				workerSpec := dsList[0].Spec.Template.Spec
+				require.Equal(t, tc.output["image"], workerSpec.Containers[0].Image, "Unexpected configuration for nfd-worker image")
+				require.Equal(t, tc.output["imagePullSecrets"], workerSpec.ImagePullSecrets, "Unexpected image pull secrets of nfd-worker")
+
+				masterSpec := deployments.Items[0].Spec.Template.Spec
+				require.Equal(t, tc.output["image"], masterSpec.Containers[0].Image, "Unexpected configuration for nfd-master image")
+				require.Equal(t, tc.output["imagePullSecrets"], masterSpec.ImagePullSecrets, "Unexpected image pull secrets of nfd-master")
+
+				// the workers report the features to the master through its service
+				service := &corev1.Service{}
+				err = clusterPolicyReconciler.Client.Get(context.TODO(),
+					types.NamespacedName{Namespace: testNamespace, Name: "nfd-master"}, service)
+				require.NoError(t, err, "nfd-master service is not created")
+			}
+		})
+	}
+}
+
+func TestObjects(t *testing.T) {
+	t.Cleanup(func() {
+		if err := deleteResources(); err != nil {
+			t.Fatalf("error removing state: %v", err)
+		}
+	})
+
+	// a state with the objects of kinds without a dedicated control function
+	dir := filepath.Join(t.TempDir(), "state-device-plugin")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	manifests := `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: test
+  namespace: "filled_by_operator"
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: test
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: test
+subjects:
+- kind: ServiceAccount
+  name: test
+  namespace: "filled_by_operator"
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: test
+  namespace: "filled_by_operator"
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+      - name: test
+        image: test
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0100_test.yaml"), []byte(manifests), 0644))
+
+	n, err := newTestContext(clusterPolicy.DeepCopy(), dir)
+	require.NoError(t, err)
+	state, err := n.step(0)
+	require.NoError(t, err)
+	require.Equal(t, policyv1.Ready, state)
+
+	sa := &corev1.ServiceAccount{}
+	err = clusterPolicyReconciler.Client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: "test"}, sa)
+	require.NoError(t, err, "service account is not created in the operator namespace")
+	binding := &rbacv1.ClusterRoleBinding{}
+	err = clusterPolicyReconciler.Client.Get(context.TODO(), types.NamespacedName{Name: "test"}, binding)
+	require.NoError(t, err, "cluster role binding is not created")
+	require.Equal(t, testNamespace, binding.Subjects[0].Namespace, "Unexpected namespace of service account")
+	job := &batchv1.Job{}
+	err = clusterPolicyReconciler.Client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: "test"}, job)
+	require.NoError(t, err, "job is not created")
+
+	// the unchanged objects are not updated
+	_, err = n.step(0)
+	require.NoError(t, err)
+	found := &batchv1.Job{}
+	err = clusterPolicyReconciler.Client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: "test"}, found)
+	require.NoError(t, err)
+	require.Equal(t, job.ResourceVersion, found.ResourceVersion, "unchanged job is updated")
+}
+
+func TestApplyObject(t *testing.T) {
+	t.Cleanup(func() {
+		if err := deleteResources(); err != nil {
+			t.Fatalf("error removing state: %v", err)
+		}
+	})
+
+	cp := clusterPolicy.DeepCopy()
+	cp.Spec.DevicePlugin.NodeSelector = map[string]string{"example.com/pool": "fpga"}
+	cp.Spec.DevicePlugin.PodAnnotations = map[string]string{"example.com/scrape": "true"}
+	n, err := newTestContext(cp, filepath.Join(cfg.root, devicePluginAssestsPath))
+	require.NoError(t, err)
+	_, err = n.step(0)
+	require.NoError(t, err)
+
+	key := types.NamespacedName{Namespace: testNamespace, Name: "device-plugin-daemonset"}
+	ds := &appsv1.DaemonSet{}
+	require.NoError(t, clusterPolicyReconciler.Client.Get(context.TODO(), key, ds))
+	image := ds.Spec.Template.Spec.Containers[0].Image
+
+	// a no-op reconciliation makes no write
+	_, err = n.step(0)
+	require.NoError(t, err)
+	found := &appsv1.DaemonSet{}
+	require.NoError(t, clusterPolicyReconciler.Client.Get(context.TODO(), key, found))
+	require.Equal(t, ds.ResourceVersion, found.ResourceVersion, "unchanged DaemonSet is updated")
+
+	// the fields owned by the operator are reverted, and the others are preserved
+	found.Spec.Template.Spec.Containers[0].Image = "edited"
+	found.Labels["edited-by"] = "test"
+	require.NoError(t, clusterPolicyReconciler.Client.Update(context.TODO(), found))
+	_, err = n.step(0)
+	require.NoError(t, err)
+	require.NoError(t, clusterPolicyReconciler.Client.Get(context.TODO(), key, found))
+	require.Equal(t, image, found.Spec.Template.Spec.Containers[0].Image, "edited image is not reverted")
+	require.Equal(t, "test", found.Labels["edited-by"], "label of others is not preserved")
+	require.Contains(t, drainEvents(eventRecorder),
+		"Normal Updated Updated DaemonSet device-plugin-daemonset with changed fields: spec.template.spec.containers[0].image",
+		"changed fields are not named in event")
+
+	// the fields removed from ClusterPolicy are removed from the DaemonSet
+	cp.Spec.DevicePlugin.NodeSelector = nil
+	cp.Spec.DevicePlugin.PodAnnotations = nil
+	_, err = n.step(0)
+	require.NoError(t, err)
+	require.NoError(t, clusterPolicyReconciler.Client.Get(context.TODO(), key, found))
+	require.NotContains(t, found.Spec.Template.Spec.NodeSelector, "example.com/pool", "removed node selector is kept")
+	require.NotContains(t, found.Spec.Template.Annotations, "example.com/scrape", "removed pod annotation is kept")
+	require.Equal(t, "test", found.Labels["edited-by"], "label of others is not preserved")
+	require.Contains(t, drainEvents(eventRecorder),
+		"Normal Updated Updated DaemonSet device-plugin-daemonset with changed fields: "+
+			"spec.template.metadata.annotations.example.com/scrape, spec.template.spec.nodeSelector.example.com/pool",
+		"removed fields are not named in event")
+
+	// the changes are kept if the DaemonSet is annotated to skip reconciliation
+	found.Spec.Template.Spec.Containers[0].Image = "edited"
+	found.Annotations = map[string]string{SkipReconcileAnnotation: "true"}
+	require.NoError(t, clusterPolicyReconciler.Client.Update(context.TODO(), found))
+	_, err = n.step(0)
+	require.NoError(t, err)
+	require.NoError(t, clusterPolicyReconciler.Client.Get(context.TODO(), key, found))
+	require.Equal(t, "edited", found.Spec.Template.Spec.Containers[0].Image, "image of annotated DaemonSet is reverted")
+}
+
+func TestDriftRemovedFields(t *testing.T) {
+	t.Cleanup(func() {
+		if err := deleteResources(); err != nil {
+			t.Fatalf("error removing state: %v", err)
+		}
+	})
+
+	cp := clusterPolicy.DeepCopy()
+	n, err := newTestContext(cp, filepath.Join(cfg.root, hostSetupAssestsPath))
+	require.NoError(t, err)
+	_, err = n.step(0)
+	require.NoError(t, err)
+
+	key := types.NamespacedName{Namespace: testNamespace, Name: "host-setup-ubuntu20-daemonset"}
+	ds := &appsv1.DaemonSet{}
+	require.NoError(t, clusterPolicyReconciler.Client.Get(context.TODO(), key, ds))
+	require.Contains(t, ds.Spec.Template.Spec.NodeSelector, nfdLabelOSReleaseID)
+	drainEvents(eventRecorder)
+
+	// the OS node selector of NFD is no longer set once the node labeller is enabled
+	cp.Spec.NodeLabeller.Enabled = boolTrue
+	_, err = n.step(0)
+	require.NoError(t, err)
+	require.NoError(t, clusterPolicyReconciler.Client.Get(context.TODO(), key, ds))
+	require.NotContains(t, ds.Spec.Template.Spec.NodeSelector, nfdLabelOSReleaseID, "OS node selector of NFD is kept")
+	require.NotContains(t, ds.Spec.Template.Spec.NodeSelector, nfdLabelOsMajorVersion, "OS node selector of NFD is kept")
+	require.Contains(t, drainEvents(eventRecorder),
+		"Normal Updated Updated DaemonSet host-setup-ubuntu20-daemonset with changed fields: "+
+			"spec.template.spec.affinity.nodeAffinity.requiredDuringSchedulingIgnoredDuringExecution.nodeSelectorTerms, "+
+			"spec.template.spec.nodeSelector",
+		"removed node selector is not named in event")
+}
+
+func TestDaemonSetRolloutState(t *testing.T) {
+	testCases := []struct {
+		description string
+		generation  int64
+		status      appsv1.DaemonSetStatus
+		expected    policyv1.State
+	}{
+		{
+			"rolled out",
+			2,
+			appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 2, UpdatedNumberScheduled: 2, NumberAvailable: 2},
+			policyv1.Ready,
+		},
+		{
+			"new generation not observed",
+			3,
+			appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 2, UpdatedNumberScheduled: 2, NumberAvailable: 2},
+			policyv1.NotReady,
+		},
+		{
+			"pods not updated",
+			2,
+			appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 2, UpdatedNumberScheduled: 1, NumberAvailable: 2},
+			policyv1.NotReady,
+		},
+		{
+			"pods not available",
+			2,
+			appsv1.DaemonSetStatus{ObservedGeneration: 2, DesiredNumberScheduled: 2, UpdatedNumberScheduled: 2, NumberAvailable: 1},
+			policyv1.NotReady,
+		},
+		{
+			"no matching nodes",
+			2,
+			appsv1.DaemonSetStatus{ObservedGeneration: 2},
+			policyv1.NoMatchingNodes,
+		},
+		{
+			"no matching nodes with new generation not observed",
+			3,
+			appsv1.DaemonSetStatus{ObservedGeneration: 2},
+			policyv1.NotReady,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			ds := &appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "test", Generation: tc.generation}, Status: tc.status}
+			require.Equal(t, tc.expected, daemonSetRolloutState(ds))
 		})
 	}
 }
+
+func TestDaemonSetNotReadyEvent(t *testing.T) {
+	t.Cleanup(func() {
+		if err := deleteResources(); err != nil {
+			t.Fatalf("error removing state: %v", err)
+		}
+	})
+
+	cp := clusterPolicy.DeepCopy()
+	n, err := newTestContext(cp, filepath.Join(cfg.root, devicePluginAssestsPath))
+	require.NoError(t, err)
+	_, err = n.step(0)
+	require.NoError(t, err)
+
+	// the DaemonSet is scheduled on a node, whose pod is not available
+	ds := &appsv1.DaemonSet{}
+	err = n.rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: "device-plugin-daemonset"}, ds)
+	require.NoError(t, err)
+	ds.Status = appsv1.DaemonSetStatus{ObservedGeneration: ds.Generation, DesiredNumberScheduled: 1, UpdatedNumberScheduled: 1}
+	require.NoError(t, n.rec.Client.Status().Update(context.TODO(), ds))
+	drainEvents(eventRecorder)
+
+	notReadyEvent := "Warning NotReady DaemonSet is not ready: 1/1 updated, 0/1 available"
+	state, err := n.step(0)
+	require.NoError(t, err)
+	require.Equal(t, policyv1.NotReady, state)
+	require.Contains(t, drainEvents(eventRecorder), notReadyEvent, "not ready event is not recorded")
+
+	// the event is not recorded again while the DaemonSet stays not ready
+	component := n.componentStatus(0, state, nil)
+	cp.Status.Components = []policyv1.ComponentStatus{component}
+	_, err = n.step(0)
+	require.NoError(t, err)
+	require.NotContains(t, drainEvents(eventRecorder), notReadyEvent, "not ready event is recorded on requeue")
+
+	// the event is recorded again once the DaemonSet becomes not ready after being ready
+	cp.Status.Components[0].State = policyv1.Ready
+	cp.Status.Components[0].DaemonSets[0].NumberAvailable = 1
+	_, err = n.step(0)
+	require.NoError(t, err)
+	require.Contains(t, drainEvents(eventRecorder), notReadyEvent, "not ready event is not recorded on state change")
+}
+
+func TestDeploymentNotReadyEvent(t *testing.T) {
+	t.Cleanup(func() {
+		if err := deleteResources(); err != nil {
+			t.Fatalf("error removing state: %v", err)
+		}
+	})
+
+	cp := clusterPolicy.DeepCopy()
+	cp.Spec.NFD.Enabled = boolTrue
+	n, err := newTestContext(cp)
+	require.NoError(t, err)
+	assets := fstest.MapFS{"state-nfd/0100_deployment.yaml": {Data: []byte(
+		"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: test-deployment\n  namespace: filled_by_operator\n")}}
+	st, err := loadState(n.rec.Log, "state-nfd", []fs.FS{assets}, nil)
+	require.NoError(t, err)
+	n.states = append(n.states, st)
+	_, err = n.step(0)
+	require.NoError(t, err)
+
+	// the replica of the Deployment is not available
+	deployment := &appsv1.Deployment{}
+	err = n.rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: "test-deployment"}, deployment)
+	require.NoError(t, err)
+	deployment.Status = appsv1.DeploymentStatus{Replicas: 1, UnavailableReplicas: 1}
+	require.NoError(t, n.rec.Client.Status().Update(context.TODO(), deployment))
+	drainEvents(eventRecorder)
+
+	notReadyEvent := "Warning NotReady Deployment test-deployment has 1 replica(s) unavailable"
+	state, err := n.step(0)
+	require.NoError(t, err)
+	require.Equal(t, policyv1.NotReady, state)
+	require.Contains(t, drainEvents(eventRecorder), notReadyEvent, "not ready event is not recorded")
+
+	// the event is not recorded again while the Deployment stays not ready
+	component := n.componentStatus(0, state, nil)
+	require.Equal(t, []policyv1.DeploymentStatus{{Name: "test-deployment", Replicas: 1, UnavailableReplicas: 1}},
+		component.Deployments)
+	cp.Status.Components = []policyv1.ComponentStatus{component}
+	_, err = n.step(0)
+	require.NoError(t, err)
+	require.NotContains(t, drainEvents(eventRecorder), notReadyEvent, "not ready event is recorded on requeue")
+
+	// the event is recorded again once the Deployment becomes not ready after being ready
+	cp.Status.Components[0].State = policyv1.Ready
+	cp.Status.Components[0].Deployments[0].UnavailableReplicas = 0
+	_, err = n.step(0)
+	require.NoError(t, err)
+	require.Contains(t, drainEvents(eventRecorder), notReadyEvent, "not ready event is not recorded on state change")
+}
+
+// uninstalledKindClient fails the requests for the objects of kind, as the API server without the kind installed
+type uninstalledKindClient struct {
+	client.Client
+	kind string
+}
+
+func (c *uninstalledKindClient) noMatch(obj client.Object) error {
+	gvk := obj.GetObjectKind().GroupVersionKind()
+	if gvk.Kind != c.kind {
+		return nil
+	}
+	return &meta.NoKindMatchError{GroupKind: gvk.GroupKind(), SearchedVersions: []string{gvk.Version}}
+}
+
+func (c *uninstalledKindClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := c.noMatch(obj); err != nil {
+		return err
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c *uninstalledKindClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if err := c.noMatch(obj); err != nil {
+		return err
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func TestObjectsKindNotInstalled(t *testing.T) {
+	installed := clusterPolicyReconciler.Client
+	clusterPolicyReconciler.Client = &uninstalledKindClient{Client: installed, kind: "ServiceMonitor"}
+	t.Cleanup(func() {
+		clusterPolicyReconciler.Client = installed
+		if err := deleteResources(); err != nil {
+			t.Fatalf("error removing state: %v", err)
+		}
+	})
+
+	cp := clusterPolicy.DeepCopy()
+	n, err := newTestContext(cp)
+	require.NoError(t, err)
+	assets := fstest.MapFS{"state-device-plugin/0900_service_monitor.yaml": {Data: []byte(
+		"apiVersion: monitoring.coreos.com/v1\nkind: ServiceMonitor\nmetadata:\n  name: device-plugin\n" +
+			"  namespace: filled_by_operator\n")}}
+	st, err := loadState(n.rec.Log, "state-device-plugin", []fs.FS{assets}, nil)
+	require.NoError(t, err)
+	n.states = append(n.states, st)
+	drainEvents(eventRecorder)
+
+	// the object of a kind not installed is skipped with an event
+	skippedEvent := "Warning CreateFailed ServiceMonitor device-plugin is not created as its kind is not installed"
+	state, err := n.step(0)
+	require.NoError(t, err)
+	require.Equal(t, policyv1.Ready, state)
+	require.Contains(t, drainEvents(eventRecorder), skippedEvent, "skipped object event is not recorded")
+	component := n.componentStatus(0, state, nil)
+	require.Equal(t, []string{"ServiceMonitor/device-plugin"}, component.SkippedObjects)
+
+	// the event is not recorded again while the kind stays not installed
+	cp.Status.Components = []policyv1.ComponentStatus{component}
+	_, err = n.step(0)
+	require.NoError(t, err)
+	require.NotContains(t, drainEvents(eventRecorder), skippedEvent, "skipped object event is recorded again")
+}
+
+func TestChangedFields(t *testing.T) {
+	desired := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: map[string]string{"app": "test"}},
+		Spec: appsv1.DaemonSetSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "test", Image: "test:v1"}}},
+			},
+		},
+	}
+
+	// the metadata set by the server and the status are ignored
+	live := desired.DeepCopy()
+	live.ResourceVersion = "2"
+	live.Generation = 3
+	live.Status.NumberReady = 1
+	fields, err := changedFields(desired, live)
+	require.NoError(t, err)
+	require.Empty(t, fields, "unexpected changed fields")
+
+	live.Spec.Template.Spec.Containers[0].Image = "test:v2"
+	delete(live.Labels, "app")
+	fields, err = changedFields(desired, live)
+	require.NoError(t, err)
+	require.Equal(t, []string{"metadata.labels.app", "spec.template.spec.containers[0].image"}, fields)
+
+	// the fields removed from the desired object are changed too
+	live = desired.DeepCopy()
+	live.Labels["removed"] = "test"
+	live.Spec.Template.Spec.NodeSelector = map[string]string{"removed": "test"}
+	fields, err = changedFields(desired, live)
+	require.NoError(t, err)
+	require.Equal(t, []string{"metadata.labels.removed", "spec.template.spec.nodeSelector"}, fields)
+
+	// the numbers of unstructured objects decoded from YAML are float64
+	desiredRule := &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{"weight": float64(1)}}}
+	liveRule := &unstructured.Unstructured{Object: map[string]interface{}{"spec": map[string]interface{}{"weight": int64(1)}}}
+	fields, err = changedFields(desiredRule, liveRule)
+	require.NoError(t, err)
+	require.Empty(t, fields, "unexpected changed fields of unstructured object")
+}
+
+func TestNodeFeatureRules(t *testing.T) {
+	spec := &policyv1.FPGANodeSpec{
+		PCIDevices: []policyv1.PCIDeviceSpec{
+			{Vendor: "10ee", Class: "1200"},
+			{Vendor: "10ee", Device: "5000"},
+		},
+	}
+	expected := []interface{}{
+		map[string]interface{}{
+			"name":   "xilinx fpga pci-1200_10ee",
+			"labels": map[string]interface{}{"pci-1200_10ee.present": "true"},
+			"matchFeatures": []interface{}{
+				map[string]interface{}{
+					"feature": "pci.device",
+					"matchExpressions": map[string]interface{}{
+						"vendor": map[string]interface{}{"op": "In", "value": []interface{}{"10ee"}},
+						"class":  map[string]interface{}{"op": "In", "value": []interface{}{"1200"}},
+					},
+				},
+			},
+		},
+		map[string]interface{}{
+			"name":   "xilinx fpga pci-10ee_5000",
+			"labels": map[string]interface{}{"pci-10ee_5000.present": "true"},
+			"matchFeatures": []interface{}{
+				map[string]interface{}{
+					"feature": "pci.device",
+					"matchExpressions": map[string]interface{}{
+						"vendor": map[string]interface{}{"op": "In", "value": []interface{}{"10ee"}},
+						"device": map[string]interface{}{"op": "In", "value": []interface{}{"5000"}},
+					},
+				},
+			},
+		},
+	}
+	require.Equal(t, expected, nodeFeatureRules(spec), "unexpected rules of NodeFeatureRule")
+
+	// the default PCI devices are used if none is specified
+	require.Len(t, nodeFeatureRules(&policyv1.FPGANodeSpec{}), 1, "unexpected rules of default PCI devices")
+}
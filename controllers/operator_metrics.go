@@ -0,0 +1,174 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	policyv1 "github.com/xilinx/fpga-operator/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	operatorMetricsNamespace = "fpga_operator"
+
+	reconciliationStatusSuccess                  = 1
+	reconciliationStatusNotReady                 = 0
+	reconciliationStatusClusterPolicyUnavailable = -1
+	reconciliationStatusClusterOperatorError     = -2
+)
+
+// OperatorMetrics holds the custom metrics of FPGA operator
+type OperatorMetrics struct {
+	reconciliationStatus      prometheus.Gauge
+	reconciliationLastSuccess prometheus.Gauge
+	reconciliationTotal       prometheus.Counter
+	reconciliationFailed      prometheus.Counter
+	reconciliationDuration    prometheus.Histogram
+
+	stateStatus      *prometheus.GaugeVec
+	daemonSetUpdates *prometheus.CounterVec
+
+	fpgaNodes        prometheus.Gauge
+	osDistNodes      *prometheus.GaugeVec
+	containerRuntime *prometheus.GaugeVec
+}
+
+// stateStatuses lists all the statuses a state can be reported in
+var stateStatuses = []policyv1.State{policyv1.Ready, policyv1.NotReady, policyv1.Disabled, policyv1.Ignored,
+	policyv1.NoMatchingNodes}
+
+var (
+	// operatorMetrics are the operator metrics of the process, shared by the reconcilers
+	operatorMetrics     *OperatorMetrics
+	operatorMetricsOnce sync.Once
+)
+
+// initOperatorMetrics returns the operator metrics, which are created and registered with the controller-runtime
+// registry once per process, as the registry rejects the metrics registered twice
+func initOperatorMetrics() *OperatorMetrics {
+	operatorMetricsOnce.Do(func() {
+		operatorMetrics = newOperatorMetrics()
+		metrics.Registry.MustRegister(
+			operatorMetrics.reconciliationStatus,
+			operatorMetrics.reconciliationLastSuccess,
+			operatorMetrics.reconciliationTotal,
+			operatorMetrics.reconciliationFailed,
+			operatorMetrics.reconciliationDuration,
+			operatorMetrics.stateStatus,
+			operatorMetrics.daemonSetUpdates,
+			operatorMetrics.fpgaNodes,
+			operatorMetrics.osDistNodes,
+			operatorMetrics.containerRuntime,
+		)
+	})
+	return operatorMetrics
+}
+
+// newOperatorMetrics creates the operator metrics, without registering them
+func newOperatorMetrics() *OperatorMetrics {
+	return &OperatorMetrics{
+		reconciliationStatus: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: operatorMetricsNamespace,
+				Name:      "reconciliation_status",
+				Help: "1 if the ClusterPolicy is ready, 0 if some states are not ready, " +
+					"-1 if the ClusterPolicy is unavailable, -2 if the operator failed to reconcile",
+			}),
+		reconciliationLastSuccess: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: operatorMetricsNamespace,
+				Name:      "reconciliation_last_success_ts_seconds",
+				Help:      "Timestamp (in seconds) of the last reconciliation in which the ClusterPolicy was ready",
+			}),
+		reconciliationTotal: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: operatorMetricsNamespace,
+				Name:      "reconciliation_total",
+				Help:      "Total number of reconciliations",
+			}),
+		reconciliationFailed: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: operatorMetricsNamespace,
+				Name:      "reconciliation_failed_total",
+				Help:      "Number of reconciliations that failed with an error",
+			}),
+		reconciliationDuration: prometheus.NewHistogram(
+			prometheus.HistogramOpts{
+				Namespace: operatorMetricsNamespace,
+				Name:      "reconciliation_duration_seconds",
+				Help:      "Duration of ClusterPolicy reconciliations",
+				Buckets:   prometheus.ExponentialBuckets(0.05, 2, 10),
+			}),
+		stateStatus: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: operatorMetricsNamespace,
+				Name:      "state_status",
+				Help:      "1 if the state is in the given status, 0 otherwise",
+			}, []string{"state", "status"}),
+		daemonSetUpdates: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: operatorMetricsNamespace,
+				Name:      "daemonset_updates_total",
+				Help:      "Number of updates applied to DaemonSets deployed by the operator",
+			}, []string{"daemonset"}),
+		fpgaNodes: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: operatorMetricsNamespace,
+				Name:      "fpga_nodes_total",
+				Help:      "Number of nodes with FPGA(s) detected in the cluster",
+			}),
+		osDistNodes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: operatorMetricsNamespace,
+				Name:      "nodes_os_distribution_total",
+				Help:      "Number of nodes per OS distribution",
+			}, []string{"os"}),
+		containerRuntime: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: operatorMetricsNamespace,
+				Name:      "container_runtime",
+				Help:      "1 for the container runtime detected in the cluster",
+			}, []string{"runtime"}),
+	}
+}
+
+// setStateStatus reports the current status of a state
+func (m *OperatorMetrics) setStateStatus(stateName string, state policyv1.State) {
+	for _, s := range stateStatuses {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		m.stateStatus.WithLabelValues(stateName, string(s)).Set(value)
+	}
+}
+
+// setOsDistributions reports the number of nodes per OS distribution
+func (m *OperatorMetrics) setOsDistributions(osDists map[string]int) {
+	m.osDistNodes.Reset()
+	for dist, count := range osDists {
+		m.osDistNodes.WithLabelValues(dist).Set(float64(count))
+	}
+}
+
+// setContainerRuntime reports the container runtime detected in the cluster
+func (m *OperatorMetrics) setContainerRuntime(runtime policyv1.Runtime) {
+	m.containerRuntime.Reset()
+	m.containerRuntime.WithLabelValues(runtime.String()).Set(1)
+}
@@ -0,0 +1,58 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	policyv1 "github.com/xilinx/fpga-operator/api/v1"
+)
+
+func TestOperatorMetrics(t *testing.T) {
+	m := newOperatorMetrics()
+
+	m.setStateStatus("state-device-plugin", policyv1.NotReady)
+	require.Equal(t, 1.0, testutil.ToFloat64(m.stateStatus.WithLabelValues("state-device-plugin", "notReady")))
+	require.Equal(t, 0.0, testutil.ToFloat64(m.stateStatus.WithLabelValues("state-device-plugin", "ready")))
+
+	m.setStateStatus("state-device-plugin", policyv1.Ready)
+	require.Equal(t, 0.0, testutil.ToFloat64(m.stateStatus.WithLabelValues("state-device-plugin", "notReady")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.stateStatus.WithLabelValues("state-device-plugin", "ready")))
+
+	m.setOsDistributions(map[string]int{"ubuntu18.04": 2, "centos7": 1})
+	require.Equal(t, 2, testutil.CollectAndCount(m.osDistNodes), "unexpected # of OS distributions")
+	require.Equal(t, 2.0, testutil.ToFloat64(m.osDistNodes.WithLabelValues("ubuntu18.04")))
+
+	// stale distributions are removed
+	m.setOsDistributions(map[string]int{"ubuntu20.04": 1})
+	require.Equal(t, 1, testutil.CollectAndCount(m.osDistNodes), "unexpected # of OS distributions")
+
+	m.setContainerRuntime(policyv1.Docker)
+	m.setContainerRuntime(policyv1.Containerd)
+	require.Equal(t, 1, testutil.CollectAndCount(m.containerRuntime), "unexpected # of container runtimes")
+	require.Equal(t, 1.0, testutil.ToFloat64(m.containerRuntime.WithLabelValues("containerd")))
+}
+
+func TestInitOperatorMetrics(t *testing.T) {
+	// the metrics are registered once, however many reconcilers are created
+	m := initOperatorMetrics()
+	require.NotPanics(t, func() {
+		require.Same(t, m, initOperatorMetrics())
+	})
+}
@@ -0,0 +1,192 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// podLogLines is the number of the last log lines of a failing container reported in the status
+const podLogLines = 10
+
+// failingWaitingReasons are the reasons of a waiting container which will not start without intervention
+var failingWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+}
+
+// podFailure describes why a pod of a DaemonSet does not become available
+type podFailure struct {
+	// Pod and Node are the names of the failing pod and the node it is scheduled on
+	Pod  string
+	Node string
+	// Container is the name of the failing container, empty if the pod is not scheduled
+	Container string
+	// Init is true if the failing container is an init container
+	Init bool
+	// Reason describes the failure, eg. CrashLoopBackOff, last exit code 1
+	Reason string
+	// Previous is true if the logs are of the previous instance of the restarting container
+	Previous bool
+	// HasLogs is true if the container has run and may have logs
+	HasLogs bool
+}
+
+// String returns the failure in the format of pod <pod> on node <node>: init container <container> <reason>
+func (f podFailure) String() string {
+	location := fmt.Sprintf("pod %s", f.Pod)
+	if f.Node != "" {
+		location += fmt.Sprintf(" on node %s", f.Node)
+	}
+	if f.Container == "" {
+		return fmt.Sprintf("%s: %s", location, f.Reason)
+	}
+	kind := "container"
+	if f.Init {
+		kind = "init container"
+	}
+	return fmt.Sprintf("%s: %s %s %s", location, kind, f.Container, f.Reason)
+}
+
+// diagnosePod returns the failure of the pod, or false if none of its containers is failing
+func diagnosePod(pod *corev1.Pod) (podFailure, bool) {
+	failure := podFailure{Pod: pod.Name, Node: pod.Spec.NodeName}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse &&
+			condition.Reason == corev1.PodReasonUnschedulable {
+			failure.Reason = fmt.Sprintf("unschedulable: %s", condition.Message)
+			return failure, true
+		}
+	}
+
+	// init containers run in order, so only the first failing one matters
+	for _, status := range pod.Status.InitContainerStatuses {
+		if diagnoseContainer(&failure, status) {
+			failure.Init = true
+			return failure, true
+		}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if diagnoseContainer(&failure, status) {
+			return failure, true
+		}
+	}
+	return failure, false
+}
+
+// diagnoseContainer fills the failure with the container, and returns true if the container is failing
+func diagnoseContainer(failure *podFailure, status corev1.ContainerStatus) bool {
+	lastExit := ""
+	if terminated := status.LastTerminationState.Terminated; terminated != nil && terminated.ExitCode != 0 {
+		lastExit = fmt.Sprintf(", last exit code %d", terminated.ExitCode)
+	}
+
+	switch {
+	case status.State.Waiting != nil && failingWaitingReasons[status.State.Waiting.Reason]:
+		failure.Reason = status.State.Waiting.Reason + lastExit
+		if status.State.Waiting.Message != "" && lastExit == "" {
+			failure.Reason += fmt.Sprintf(" (%s)", status.State.Waiting.Message)
+		}
+		failure.Previous = status.LastTerminationState.Terminated != nil
+		failure.HasLogs = failure.Previous
+	case status.State.Terminated != nil && status.State.Terminated.ExitCode != 0:
+		failure.Reason = fmt.Sprintf("exited with code %d", status.State.Terminated.ExitCode)
+		if status.State.Terminated.Reason != "" {
+			failure.Reason += fmt.Sprintf(" (%s)", status.State.Terminated.Reason)
+		}
+		failure.HasLogs = true
+	default:
+		return false
+	}
+	failure.Container = status.Name
+	return true
+}
+
+// diagnoseDaemonSet returns the failures of the pods of the DaemonSet, with the last log lines of the first failure
+func (ctrl *ControlContext) diagnoseDaemonSet(ds *appsv1.DaemonSet) ([]string, error) {
+	return ctrl.diagnosePods(ds, ds.Spec.Selector)
+}
+
+// diagnoseJob returns the failures of the pods of the Job, with the last log lines of the first failure
+func (ctrl *ControlContext) diagnoseJob(job *batchv1.Job) ([]string, error) {
+	return ctrl.diagnosePods(job, job.Spec.Selector)
+}
+
+// diagnosePods returns the failures of the pods selected and controlled by the owner
+func (ctrl *ControlContext) diagnosePods(owner metav1.Object, selector *metav1.LabelSelector) ([]string, error) {
+	if selector == nil {
+		return nil, nil
+	}
+	pods := &corev1.PodList{}
+	err := ctrl.rec.Client.List(context.TODO(), pods, client.InNamespace(owner.GetNamespace()),
+		client.MatchingLabels(selector.MatchLabels))
+	if err != nil {
+		return nil, err
+	}
+
+	failures := []string{}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !metav1.IsControlledBy(pod, owner) {
+			continue
+		}
+		failure, ok := diagnosePod(pod)
+		if !ok {
+			continue
+		}
+		message := failure.String()
+		if len(failures) == 0 && failure.HasLogs {
+			if logs := ctrl.containerLogs(pod.Namespace, failure); logs != "" {
+				message += fmt.Sprintf(", last logs:\n%s", logs)
+			}
+		}
+		failures = append(failures, message)
+	}
+	return failures, nil
+}
+
+// containerLogs returns the last log lines of the failing container, or empty if the logs are not available
+func (ctrl *ControlContext) containerLogs(namespace string, failure podFailure) string {
+	if ctrl.rec.Clientset == nil {
+		return ""
+	}
+	lines := int64(podLogLines)
+	logs, err := ctrl.rec.Clientset.CoreV1().Pods(namespace).GetLogs(failure.Pod, &corev1.PodLogOptions{
+		Container: failure.Container,
+		TailLines: &lines,
+		Previous:  failure.Previous,
+	}).DoRaw(context.TODO())
+	if err != nil {
+		ctrl.rec.Log.Info("Could not get logs of failing container", "Pod", failure.Pod,
+			"Container", failure.Container, "Error", err.Error())
+		return ""
+	}
+	return strings.TrimRight(string(logs), "\n")
+}
@@ -0,0 +1,190 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	policyv1 "github.com/xilinx/fpga-operator/api/v1"
+)
+
+func TestDiagnosePod(t *testing.T) {
+	crashLoop := corev1.ContainerStatus{
+		Name:         "init-xrt-xrm",
+		State:        corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+		RestartCount: 3,
+		LastTerminationState: corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"},
+		},
+	}
+	running := corev1.ContainerStatus{
+		Name:  "host-setup",
+		State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	}
+
+	testCases := []struct {
+		description string
+		status      corev1.PodStatus
+		expected    string
+		previous    bool
+		hasLogs     bool
+	}{
+		{
+			"running",
+			corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{running}},
+			"",
+			false,
+			false,
+		},
+		{
+			"init container in crash loop",
+			corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{crashLoop},
+				ContainerStatuses:     []corev1.ContainerStatus{{Name: "host-setup"}},
+			},
+			"pod test-pod on node test-node: init container init-xrt-xrm CrashLoopBackOff, last exit code 1",
+			true,
+			true,
+		},
+		{
+			"image pull failed",
+			corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name: "host-setup",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+					Reason: "ImagePullBackOff", Message: "Back-off pulling image \"host-setup:bad\"",
+				}},
+			}}},
+			"pod test-pod on node test-node: container host-setup ImagePullBackOff (Back-off pulling image \"host-setup:bad\")",
+			false,
+			false,
+		},
+		{
+			"init container exited",
+			corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "init-card-flash",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 2, Reason: "Error"}},
+			}}},
+			"pod test-pod on node test-node: init container init-card-flash exited with code 2 (Error)",
+			false,
+			true,
+		},
+		{
+			"unschedulable",
+			corev1.PodStatus{Conditions: []corev1.PodCondition{{
+				Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: corev1.PodReasonUnschedulable,
+				Message: "0/1 nodes are available: 1 Insufficient memory.",
+			}}},
+			"pod test-pod on node test-node: unschedulable: 0/1 nodes are available: 1 Insufficient memory.",
+			false,
+			false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-pod"},
+				Spec:       corev1.PodSpec{NodeName: "test-node"},
+				Status:     tc.status,
+			}
+			failure, ok := diagnosePod(pod)
+			if tc.expected == "" {
+				require.False(t, ok, "unexpected failure: %s", failure)
+				return
+			}
+			require.True(t, ok, "failure not found")
+			require.Equal(t, tc.expected, failure.String())
+			require.Equal(t, tc.previous, failure.Previous, "unexpected logs of previous container")
+			require.Equal(t, tc.hasLogs, failure.HasLogs, "unexpected logs availability")
+		})
+	}
+}
+
+func TestCheckProgress(t *testing.T) {
+	clusterPolicyReconciler.Clientset = fake.NewSimpleClientset()
+	t.Cleanup(func() {
+		clusterPolicyReconciler.Clientset = nil
+		if err := deleteResources(); err != nil {
+			t.Fatalf("error removing state: %v", err)
+		}
+	})
+
+	cp := clusterPolicy.DeepCopy()
+	cp.Spec.SetDefaults()
+	n, err := newTestContext(cp, filepath.Join(cfg.root, devicePluginAssestsPath))
+	require.NoError(t, err)
+	_, err = n.step(0)
+	require.NoError(t, err)
+
+	// the DaemonSet is scheduled on a node, whose pod is in a crash loop
+	ds := &appsv1.DaemonSet{}
+	err = n.rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: "device-plugin-daemonset"}, ds)
+	require.NoError(t, err)
+	ds.Status = appsv1.DaemonSetStatus{ObservedGeneration: ds.Generation, DesiredNumberScheduled: 1, UpdatedNumberScheduled: 1}
+	require.NoError(t, n.rec.Client.Status().Update(context.TODO(), ds))
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "device-plugin-daemonset-x2k9p",
+			Namespace:       testNamespace,
+			Labels:          ds.Spec.Selector.MatchLabels,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(ds, appsv1.SchemeGroupVersion.WithKind("DaemonSet"))},
+		},
+		Spec: corev1.PodSpec{NodeName: "fpga-node", Containers: []corev1.Container{{Name: "device-plugin", Image: "test"}}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			Name:  "device-plugin",
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			LastTerminationState: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{ExitCode: 2},
+			},
+		}}},
+	}
+	require.NoError(t, n.rec.Client.Create(context.TODO(), pod))
+
+	now := time.Now()
+	component := n.componentStatus(0, policyv1.NotReady, nil)
+	n.checkProgress(0, &component, nil, now)
+	require.Equal(t, policyv1.ReasonDaemonSetsNotReady, component.Reason, "state without previous status exceeds deadline")
+	require.Equal(t, now, component.LastTransitionTime.Time, "unexpected transition time of new state")
+
+	// the state has been not ready for longer than the default deadline
+	since := metav1.NewTime(now.Add(-11 * time.Minute))
+	previous := []policyv1.ComponentStatus{{Name: component.Name, State: policyv1.NotReady, LastTransitionTime: &since}}
+	component = n.componentStatus(0, policyv1.NotReady, nil)
+	n.checkProgress(0, &component, previous, now)
+	require.Equal(t, policyv1.ReasonProgressDeadlineExceeded, component.Reason)
+	require.Equal(t, since, *component.LastTransitionTime, "transition time of unchanged state is not kept")
+	require.Equal(t, "DaemonSets not ready within 10m0s: pod device-plugin-daemonset-x2k9p on node fpga-node: "+
+		"container device-plugin CrashLoopBackOff, last exit code 2, last logs:\nfake logs", component.Message)
+	require.Contains(t, drainEvents(eventRecorder), "Warning ProgressDeadlineExceeded State state-device-plugin: "+component.Message,
+		"progress deadline event not recorded")
+
+	// the deadline is configurable per state
+	*cp.Spec.DevicePlugin.ProgressDeadlineSeconds = 3600
+	component = n.componentStatus(0, policyv1.NotReady, nil)
+	n.checkProgress(0, &component, previous, now)
+	require.Equal(t, policyv1.ReasonDaemonSetsNotReady, component.Reason, "state exceeds the configured deadline")
+}
@@ -17,108 +17,309 @@ limitations under the License.
 package controllers
 
 import (
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"path"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	nodev1 "k8s.io/api/node/v1"
-	"k8s.io/apimachinery/pkg/runtime/serializer/json"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 )
 
-type assetsFromFile []byte
+// assetsFromFile is an asset file of a state, which has one or more manifests separated by "---"
+type assetsFromFile struct {
+	name string
+	data []byte
+}
+
+// document is a YAML document in an asset file, starting at line of the file
+type document struct {
+	line int
+	data []byte
+}
+
+// deserializer decodes the manifests of the kinds in the scheme
+var deserializer = scheme.Codecs.UniversalDeserializer()
+
+// yamlErrorLineRegexp finds the line number in an error of the YAML parser
+var yamlErrorLineRegexp = regexp.MustCompile(`yaml: line (\d+):`)
 
 type Resources struct {
-	Daemonsets   []appsv1.DaemonSet
+	Deployments []appsv1.Deployment
+	Daemonsets  []appsv1.DaemonSet
+	// RuntimeClass is named as per ClusterPolicy, so a state has only one
 	RuntimeClass nodev1.RuntimeClass
+	// NodeFeatureRule is a custom resource of NFD, which is not in the scheme. A state has only one,
+	// whose rules are set as per ClusterPolicy
+	NodeFeatureRule unstructured.Unstructured
+	// Objects are the objects of the kinds without a dedicated control function, eg. ServiceAccount
+	// or ClusterRole, and the extra RuntimeClass and NodeFeatureRule of a state, which are applied
+	// as they are. The kinds not in the scheme are unstructured, eg. ServiceMonitor
+	Objects []client.Object
 }
 
-func filePathWalkDir(ctrl *ClusterPolicyController, root string) ([]string, error) {
-	var files []string
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			ctrl.rec.Log.Error(err, "error in filepath.Walk on %s", root)
-			return nil
+// AssetsError is an error loading the asset files of a state
+type AssetsError struct {
+	State string
+	Err   error
+}
+
+func (e *AssetsError) Error() string {
+	return fmt.Sprintf("unable to load assets of %s: %v", e.State, e.Err)
+}
+
+func (e *AssetsError) Unwrap() error {
+	return e.Err
+}
+
+// isAssetsError returns true if err is an AssetsError or wraps one
+func isAssetsError(err error) bool {
+	var assetsErr *AssetsError
+	return errors.As(err, &assetsErr)
+}
+
+// ManifestError is an error parsing the manifest at Line of the asset file
+type ManifestError struct {
+	File string
+	Line int
+	Err  error
+}
+
+func (e *ManifestError) Error() string {
+	return fmt.Sprintf("%s:%d: %v", e.File, e.Line, e.Err)
+}
+
+func (e *ManifestError) Unwrap() error {
+	return e.Err
+}
+
+// splitDocuments splits the YAML documents in an asset file, skipping the documents of comments only
+func splitDocuments(data []byte) []document {
+	docs := []document{}
+	lines := strings.Split(string(data), "\n")
+	start := 0
+	for i := 0; i <= len(lines); i++ {
+		if i < len(lines) && strings.TrimRight(lines[i], " \t\r") != "---" {
+			continue
 		}
-		if !info.IsDir() {
-			files = append(files, path)
+		if !isEmptyDocument(lines[start:i]) {
+			docs = append(docs, document{line: start + 1, data: []byte(strings.Join(lines[start:i], "\n"))})
 		}
-		return nil
-	})
-	return files, err
+		start = i + 1
+	}
+	return docs
 }
 
-func getAssetsFrom(ctrl *ClusterPolicyController, path string) []assetsFromFile {
-	manifests := []assetsFromFile{}
-	files, err := filePathWalkDir(ctrl, path)
-	if err != nil {
-		panic(err)
+// isEmptyDocument returns true if the lines of a document are blank or comments
+func isEmptyDocument(lines []string) bool {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
 	}
-	sort.Strings(files)
-	for _, file := range files {
+	return true
+}
 
-		buffer, err := ioutil.ReadFile(file)
+// yamlErrorLine returns the line in the file of an error parsing the document,
+// or the first line of the document if the error has no line number
+func yamlErrorLine(doc document, err error) int {
+	match := yamlErrorLineRegexp.FindStringSubmatch(err.Error())
+	if match == nil {
+		return doc.line
+	}
+	line, _ := strconv.Atoi(match[1])
+	return doc.line + line - 1
+}
+
+// readAssets reads the asset files of the state in fsys keyed by the file name.
+// The state has no asset file in fsys if its directory is missing
+func readAssets(fsys fs.FS, stateName string) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	entries, err := fs.ReadDir(fsys, stateName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return files, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		buffer, err := fs.ReadFile(fsys, path.Join(stateName, entry.Name()))
 		if err != nil {
-			panic(err)
+			return nil, err
 		}
-		manifests = append(manifests, buffer)
+		files[entry.Name()] = buffer
+	}
+	return files, nil
+}
+
+// getAssetsFrom returns the asset files sorted by the file name, which is the order of deployment
+func getAssetsFrom(files map[string][]byte) []assetsFromFile {
+	names := []string{}
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	manifests := []assetsFromFile{}
+	for _, name := range names {
+		manifests = append(manifests, assetsFromFile{name: name, data: files[name]})
 	}
 	return manifests
 }
 
-func panicIfError(err error) {
+// assetsConfigMapFiles returns the asset files of the ConfigMap per state, the keys of the ConfigMap
+// are in the format of <state>.<file>, eg. state-device-plugin.0100_device_plugin.yaml
+func assetsConfigMapFiles(cm *corev1.ConfigMap, stateNames []string) (map[string]map[string][]byte, error) {
+	known := map[string]bool{}
+	for _, name := range stateNames {
+		known[name] = true
+	}
+
+	files := map[string]map[string][]byte{}
+	for key, data := range cm.Data {
+		fields := strings.SplitN(key, ".", 2)
+		if len(fields) != 2 || fields[1] == "" {
+			return nil, &AssetsError{State: fields[0],
+				Err: fmt.Errorf("invalid key %q in ConfigMap %s, expected <state>.<file>", key, cm.Name)}
+		}
+		if !known[fields[0]] {
+			return nil, &AssetsError{State: fields[0],
+				Err: fmt.Errorf("unknown state of key %q in ConfigMap %s", key, cm.Name)}
+		}
+		if files[fields[0]] == nil {
+			files[fields[0]] = map[string][]byte{}
+		}
+		files[fields[0]][fields[1]] = []byte(data)
+	}
+	return files, nil
+}
+
+// decodeManifest decodes the manifest into obj, the errors are reported at the line of the document
+func decodeManifest(m assetsFromFile, doc document, obj runtime.Object) error {
+	_, _, err := deserializer.Decode(doc.data, nil, obj)
 	if err != nil {
-		panic(err)
+		return &ManifestError{File: m.name, Line: doc.line, Err: err}
 	}
+	return nil
 }
 
-func addResourceControls(ctrl *ClusterPolicyController, path string) (Resources, controlFuncs) {
+// decodeObject decodes the manifest into an object of its kind in the scheme, or an unstructured object
+// if the kind is not in the scheme, eg. a custom resource
+func decodeObject(m assetsFromFile, doc document) (client.Object, error) {
+	obj, _, err := deserializer.Decode(doc.data, nil, nil)
+	if runtime.IsNotRegisteredError(err) {
+		u := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(doc.data, &u.Object); err != nil {
+			return nil, &ManifestError{File: m.name, Line: yamlErrorLine(doc, err), Err: err}
+		}
+		return u, nil
+	} else if err != nil {
+		return nil, &ManifestError{File: m.name, Line: doc.line, Err: err}
+	}
+
+	o, ok := obj.(client.Object)
+	if !ok {
+		return nil, &ManifestError{File: m.name, Line: doc.line, Err: fmt.Errorf("%T is not an object", obj)}
+	}
+	return o, nil
+}
+
+func addResourceControls(logger logr.Logger, stateName string, manifests []assetsFromFile) (Resources, controlFuncs, error) {
 	res := Resources{}
 	ctrlFuncs := controlFuncs{}
 
-	ctrl.rec.Log.Info("Getting assets from:", "path:", path)
-
-	manifests := getAssetsFrom(ctrl, path)
-	s := json.NewYAMLSerializer(json.DefaultMetaFactory, scheme.Scheme,
-		scheme.Scheme)
-	// regexp to find the asset kind
-	reg, _ := regexp.Compile(`\b(\w*kind:\w*)\B.*\b`)
+	logger.Info("Getting assets", "State", stateName)
 
 	for _, m := range manifests {
-		// get the kind of this resource
-		kind := reg.FindString(string(m))
-		slice := strings.Split(kind, ":")
-		kind = strings.TrimSpace(slice[1])
-		ctrl.rec.Log.Info(fmt.Sprintf("Looking for %s in %s", kind, path))
-
-		switch kind {
-		// add control func for each kind of resource
-		case "DaemonSet":
-			// _, _, err := s.Decode(m, nil, &res.DaemonSet)
-			ds := appsv1.DaemonSet{}
-			_, _, err := s.Decode(m, nil, &ds)
-			panicIfError(err)
-
-			// found DaemonSet
-			ctrl.rec.Log.Info("Found DaemonSet", "Name", ds.Name, "Path", path)
-			if res.Daemonsets == nil {
-				res.Daemonsets = []appsv1.DaemonSet{}
-				ctrlFuncs = append(ctrlFuncs, DaemonSet)
+		for _, doc := range splitDocuments(m.data) {
+			// get the kind of this resource
+			typeMeta := metav1.TypeMeta{}
+			if err := yaml.Unmarshal(doc.data, &typeMeta); err != nil {
+				return res, nil, &ManifestError{File: m.name, Line: yamlErrorLine(doc, err), Err: err}
+			}
+			kind := typeMeta.Kind
+			if kind == "" {
+				return res, nil, &ManifestError{File: m.name, Line: doc.line, Err: fmt.Errorf("kind not found")}
+			}
+			logger.Info(fmt.Sprintf("Looking for %s in %s", kind, stateName))
+
+			switch {
+			// add control func for each kind of resource
+			case kind == "Deployment":
+				deployment := appsv1.Deployment{}
+				if err := decodeManifest(m, doc, &deployment); err != nil {
+					return res, nil, err
+				}
+
+				// found Deployment
+				logger.Info("Found Deployment", "Name", deployment.Name, "State", stateName)
+				if res.Deployments == nil {
+					res.Deployments = []appsv1.Deployment{}
+					ctrlFuncs = append(ctrlFuncs, Deployment)
+				}
+				res.Deployments = append(res.Deployments, deployment)
+			case kind == "DaemonSet":
+				ds := appsv1.DaemonSet{}
+				if err := decodeManifest(m, doc, &ds); err != nil {
+					return res, nil, err
+				}
+
+				// found DaemonSet
+				logger.Info("Found DaemonSet", "Name", ds.Name, "State", stateName)
+				if res.Daemonsets == nil {
+					res.Daemonsets = []appsv1.DaemonSet{}
+					ctrlFuncs = append(ctrlFuncs, DaemonSet)
+				}
+				res.Daemonsets = append(res.Daemonsets, ds)
+			case kind == "RuntimeClass" && res.RuntimeClass.Name == "":
+				if err := decodeManifest(m, doc, &res.RuntimeClass); err != nil {
+					return res, nil, err
+				}
+
+				// found RuntimeClass
+				logger.Info("Found RuntimeClass", "Name", res.RuntimeClass.Name)
+				ctrlFuncs = append(ctrlFuncs, RuntimeClass)
+			case kind == "NodeFeatureRule" && res.NodeFeatureRule.Object == nil:
+				rule := map[string]interface{}{}
+				if err := yaml.Unmarshal(doc.data, &rule); err != nil {
+					return res, nil, &ManifestError{File: m.name, Line: yamlErrorLine(doc, err), Err: err}
+				}
+				res.NodeFeatureRule.Object = rule
+
+				// found NodeFeatureRule
+				logger.Info("Found NodeFeatureRule", "Name", res.NodeFeatureRule.GetName())
+				ctrlFuncs = append(ctrlFuncs, NodeFeatureRule)
+			default:
+				// the extra RuntimeClass and NodeFeatureRule of the state are applied as they are
+				obj, err := decodeObject(m, doc)
+				if err != nil {
+					return res, nil, err
+				}
+
+				// found an object applied as it is
+				logger.Info("Found "+kind, "Name", obj.GetName())
+				if res.Objects == nil {
+					res.Objects = []client.Object{}
+					ctrlFuncs = append(ctrlFuncs, Objects)
+				}
+				res.Objects = append(res.Objects, obj)
 			}
-			res.Daemonsets = append(res.Daemonsets, ds)
-		case "RuntimeClass":
-			_, _, err := s.Decode(m, nil, &res.RuntimeClass)
-			panicIfError(err)
-
-			// found RuntimeClass
-			ctrl.rec.Log.Info("Found RuntimeClass", "Name", &res.RuntimeClass.Name)
-			ctrlFuncs = append(ctrlFuncs, RuntimeClass)
 		}
 	}
-	return res, ctrlFuncs
+	return res, ctrlFuncs, nil
 }
@@ -0,0 +1,282 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
+	k8spolicyv1 "k8s.io/api/policy/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	policyv1 "github.com/xilinx/fpga-operator/api/v1"
+)
+
+func TestLoadState(t *testing.T) {
+	embedded := fstest.MapFS{
+		"state-test/0100_service_account.yaml": {Data: []byte(
+			"apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: embedded\n")},
+		"state-test/0200_service.yaml": {Data: []byte(
+			"apiVersion: v1\nkind: Service\nmetadata:\n  name: embedded\n")},
+	}
+	dir := fstest.MapFS{
+		"state-test/0100_service_account.yaml": {Data: []byte(
+			"apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: dir\n")},
+	}
+
+	st, err := loadState(logr.Discard(), "state-test", []fs.FS{embedded, dir}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{"dir", "embedded"}, objectNames(st.resources), "embedded file is not overridden by the directory")
+	require.Len(t, st.controlFuncs, 1)
+
+	st, err = loadState(logr.Discard(), "state-test", []fs.FS{embedded, dir}, map[string][]byte{
+		"0200_service.yaml": []byte("apiVersion: v1\nkind: Service\nmetadata:\n  name: configmap\n"),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"dir", "configmap"}, objectNames(st.resources), "file is not overridden by the ConfigMap")
+
+	_, err = loadState(logr.Discard(), "state-missing", []fs.FS{embedded, dir}, nil)
+	var assetsErr *AssetsError
+	require.True(t, errors.As(err, &assetsErr), "state without asset files is not an AssetsError")
+	require.Equal(t, "state-missing", assetsErr.State)
+
+	_, err = loadState(logr.Discard(), "state-test", []fs.FS{embedded}, map[string][]byte{
+		"0300_invalid.yaml": []byte("apiVersion: v1\nmetadata:\n  name: invalid\n"),
+	})
+	require.True(t, errors.As(err, &assetsErr), "asset without kind is not an AssetsError")
+}
+
+// objectNames returns the names of the objects without a dedicated control function
+func objectNames(res Resources) []string {
+	names := []string{}
+	for _, obj := range res.Objects {
+		names = append(names, obj.GetName())
+	}
+	return names
+}
+
+func TestAddResourceControls(t *testing.T) {
+	testCases := []struct {
+		description  string
+		data         string
+		controlFuncs int
+		deployments  []string
+		objects      []client.Object
+		line         int
+	}{
+		{
+			"multi-document file",
+			"# license\n---\napiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: test\n---\n" +
+				"apiVersion: apps/v1\nkind: DaemonSet\nmetadata:\n  name: test-1\n---\n" +
+				"apiVersion: apps/v1\nkind: DaemonSet\nmetadata:\n  name: test-2\n---\n# comment only\n",
+			2,
+			nil,
+			[]client.Object{&corev1.ServiceAccount{}},
+			0,
+		},
+		{
+			"arbitrary kinds",
+			"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n---\n" +
+				"apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: test\n---\n" +
+				"apiVersion: policy/v1\nkind: PodDisruptionBudget\nmetadata:\n  name: test\n---\n" +
+				"apiVersion: rbac.authorization.k8s.io/v1\nkind: RoleBinding\nmetadata:\n  name: test\n---\n" +
+				"apiVersion: monitoring.coreos.com/v1\nkind: ServiceMonitor\nmetadata:\n  name: test\n",
+			1,
+			nil,
+			[]client.Object{&corev1.ConfigMap{}, &batchv1.Job{}, &k8spolicyv1.PodDisruptionBudget{},
+				&rbacv1.RoleBinding{}, &unstructured.Unstructured{}},
+			0,
+		},
+		{
+			"multiple Deployments",
+			"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: test-1\n---\n" +
+				"apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: test-2\n",
+			1,
+			[]string{"test-1", "test-2"},
+			nil,
+			0,
+		},
+		{
+			"extra RuntimeClass and NodeFeatureRule",
+			"apiVersion: node.k8s.io/v1\nkind: RuntimeClass\nmetadata:\n  name: default\nhandler: default\n---\n" +
+				"apiVersion: node.k8s.io/v1\nkind: RuntimeClass\nmetadata:\n  name: test\nhandler: test\n---\n" +
+				"apiVersion: nfd.k8s-sigs.io/v1alpha1\nkind: NodeFeatureRule\nmetadata:\n  name: default\n---\n" +
+				"apiVersion: nfd.k8s-sigs.io/v1alpha1\nkind: NodeFeatureRule\nmetadata:\n  name: test\n" +
+				"spec:\n  rules: []\n",
+			3,
+			nil,
+			[]client.Object{&nodev1.RuntimeClass{}, &unstructured.Unstructured{}},
+			0,
+		},
+		{
+			"malformed YAML",
+			"apiVersion: v1\nkind: ServiceAccount\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: test\n   labels: {}\n",
+			0,
+			nil,
+			nil,
+			8,
+		},
+		{
+			"kind not found",
+			"# license\napiVersion: v1\nmetadata:\n  name: test\n",
+			0,
+			nil,
+			nil,
+			1,
+		},
+		{
+			"invalid field",
+			"apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: test\n---\n" +
+				"apiVersion: apps/v1\nkind: DaemonSet\nmetadata:\n  name: test\nspec:\n  template: []\n",
+			0,
+			nil,
+			nil,
+			6,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			manifests := []assetsFromFile{{name: "0100_test.yaml", data: []byte(tc.data)}}
+			res, ctrlFuncs, err := addResourceControls(logr.Discard(), "state-test", manifests)
+			if tc.line != 0 {
+				var manifestErr *ManifestError
+				require.True(t, errors.As(err, &manifestErr), "invalid manifest is not a ManifestError")
+				require.Equal(t, "0100_test.yaml", manifestErr.File)
+				require.Equal(t, tc.line, manifestErr.Line, "unexpected line of ManifestError: %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, ctrlFuncs, tc.controlFuncs)
+			var deployments []string
+			for _, deployment := range res.Deployments {
+				deployments = append(deployments, deployment.Name)
+			}
+			require.Equal(t, tc.deployments, deployments, "unexpected Deployments")
+			if res.RuntimeClass.Name != "" {
+				require.Equal(t, "default", res.RuntimeClass.Name, "RuntimeClass is replaced")
+			}
+			if res.NodeFeatureRule.Object != nil {
+				require.Equal(t, "default", res.NodeFeatureRule.GetName(), "NodeFeatureRule is replaced")
+				require.NotContains(t, res.NodeFeatureRule.Object, "spec", "NodeFeatureRules are merged")
+			}
+			require.Len(t, res.Objects, len(tc.objects))
+			for i, obj := range res.Objects {
+				require.IsType(t, tc.objects[i], obj)
+				require.Equal(t, "test", obj.GetName())
+			}
+		})
+	}
+}
+
+func TestLoadStatesFailure(t *testing.T) {
+	r := &ClusterPolicyReconciler{
+		Log: logr.Discard(),
+		Assets: fstest.MapFS{
+			"state-device-plugin/0100_service_account.yaml": {Data: []byte(
+				"apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: device-plugin\n")},
+			"state-host-setup/0100_service_account.yaml": {Data: []byte(
+				"apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: host-setup\n  labels: [\n")},
+		},
+	}
+
+	states, err := r.loadStates(nil)
+	require.NoError(t, err)
+	require.Len(t, states, len(stateNames))
+	for _, st := range states {
+		switch st.name {
+		case "state-device-plugin":
+			require.NoError(t, st.err)
+			require.Equal(t, []string{"device-plugin"}, objectNames(st.resources))
+		case "state-host-setup":
+			require.True(t, isAssetsError(st.err), "state with malformed asset is not failed")
+			require.Contains(t, st.err.Error(), "0100_service_account.yaml:")
+		default:
+			require.True(t, isAssetsError(st.err), "state without assets is not failed")
+		}
+	}
+}
+
+func TestAssetsConfigMapFiles(t *testing.T) {
+	testCases := []struct {
+		description string
+		data        map[string]string
+		expected    map[string]map[string][]byte
+		valid       bool
+	}{
+		{
+			"valid keys",
+			map[string]string{
+				"state-device-plugin.0100_device_plugin.yaml": "device plugin",
+				"state-host-setup.0500_host_setup.yaml":       "host setup",
+			},
+			map[string]map[string][]byte{
+				"state-device-plugin": {"0100_device_plugin.yaml": []byte("device plugin")},
+				"state-host-setup":    {"0500_host_setup.yaml": []byte("host setup")},
+			},
+			true,
+		},
+		{
+			"invalid key",
+			map[string]string{"state-device-plugin": "device plugin"},
+			nil,
+			false,
+		},
+		{
+			"unknown state",
+			map[string]string{"state-unknown.0100_service_account.yaml": "service account"},
+			nil,
+			false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "assets"}, Data: tc.data}
+			files, err := assetsConfigMapFiles(cm, stateNames)
+			if !tc.valid {
+				var assetsErr *AssetsError
+				require.True(t, errors.As(err, &assetsErr), "invalid ConfigMap is not an AssetsError")
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, files)
+		})
+	}
+}
+
+func TestAssetsLoadFailedCondition(t *testing.T) {
+	status := &policyv1.ClusterPolicyStatus{State: policyv1.NotReady}
+	setStatusConditions(status, 1, &AssetsError{State: "state-device-plugin", Err: errors.New("no asset file found")})
+
+	degraded := meta.FindStatusCondition(status.Conditions, policyv1.ConditionDegraded)
+	require.NotNil(t, degraded, "Degraded condition not set")
+	require.Equal(t, metav1.ConditionTrue, degraded.Status)
+	require.Equal(t, policyv1.ReasonAssetsLoadFailed, degraded.Reason)
+	require.Equal(t, "unable to load assets of state-device-plugin: no asset file found", degraded.Message)
+}
@@ -0,0 +1,352 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// StateReadyLabelPrefix is the prefix of the node labels set once the pods of a state are ready on the node,
+	// eg. state.fpga.xilinx.com/host-setup=ready. It differs from the prefix of the node labeller, which owns its labels
+	StateReadyLabelPrefix = "state.fpga.xilinx.com/"
+	// stateReadyValue is the value of the node label of a ready state
+	stateReadyValue = "ready"
+)
+
+// stateDependencies are the states each state depends on. The pods of a state are only scheduled on the nodes
+// where the pods of its enabled dependencies are ready, eg. device-plugin is useless until host-setup has installed
+// XRT and flashed the shell on the node. They are not in the assets of the states, as they order the loading of
+// the states and gate the scheduling of the device plugin, which an assets ConfigMap must not be able to break
+var stateDependencies = map[string][]string{
+	"state-device-plugin": {"state-host-setup"},
+}
+
+// stateReadyLabel returns the node label set once the pods of the state are ready on the node
+func stateReadyLabel(stateName string) string {
+	return StateReadyLabelPrefix + strings.TrimPrefix(stateName, "state-")
+}
+
+// stateSetupAnnotation returns the node annotation recording the hash of the setup the node is labeled ready for
+func stateSetupAnnotation(stateName string) string {
+	return stateReadyLabel(stateName) + "-hash"
+}
+
+// orderStates returns the states ordered so each state comes after its dependencies,
+// keeping the order of names otherwise. It fails if the dependencies have a cycle or an unknown state
+func orderStates(names []string, dependencies map[string][]string) ([]string, error) {
+	known := map[string]bool{}
+	for _, name := range names {
+		known[name] = true
+	}
+
+	ordered := []string{}
+	// visiting marks the states being ordered to detect a cycle, and done the ones already ordered
+	visiting := map[string]bool{}
+	done := map[string]bool{}
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		if done[name] {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("state dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		}
+		visiting[name] = true
+		for _, dependency := range dependencies[name] {
+			if !known[dependency] {
+				return fmt.Errorf("state %s depends on unknown state %s", name, dependency)
+			}
+			if err := visit(dependency, append(path, name)); err != nil {
+				return err
+			}
+		}
+		visiting[name] = false
+		done[name] = true
+		ordered = append(ordered, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// isDependency returns true if any state depends on the state
+func isDependency(stateName string) bool {
+	for _, dependencies := range stateDependencies {
+		for _, dependency := range dependencies {
+			if dependency == stateName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// enabledDependencies returns the enabled states the state depends on, the disabled ones are managed
+// out of the operator, eg. XRT installed manually, so they do not gate the state
+func (n ControlContext) enabledDependencies(stateName string) []string {
+	enabled := []string{}
+	for _, dependency := range stateDependencies[stateName] {
+		if n.isStateEnabled(dependency) {
+			enabled = append(enabled, dependency)
+		}
+	}
+	return enabled
+}
+
+// setDependencySelectors restricts the DaemonSet to the nodes where the enabled dependencies of the state are ready
+func setDependencySelectors(obj *appsv1.DaemonSet, n ControlContext) {
+	for _, dependency := range n.enabledDependencies(n.stateName) {
+		setDaemonSetSelector(obj, map[string]string{stateReadyLabel(dependency): stateReadyValue})
+	}
+}
+
+// labelReadyNodes sets the ready label of the state at index idx on the FPGA nodes where all its pods scheduled
+// are ready, or its Jobs are complete, and records the hash of their setup. The label is kept while the pods are
+// not ready, eg. restarted, unless the setup of the node is changed, so the pods of the states depending on it
+// are not evicted. It is removed from the nodes where nothing of the state is scheduled, and from all the nodes
+// if the state is disabled
+func (ctrl *ControlContext) labelReadyNodes(idx int) error {
+	name := ctrl.states[idx].name
+	enabled := ctrl.isStateEnabled(name)
+
+	nodes := &corev1.NodeList{}
+	err := ctrl.rec.Client.List(context.TODO(), nodes)
+	if err != nil {
+		return fmt.Errorf("unable to list nodes to label state %s: %v", name, err)
+	}
+
+	daemonSets := []*appsv1.DaemonSet{}
+	pods := &corev1.PodList{}
+	jobs := []batchv1.Job{}
+	jobMode := ctrl.isHostSetupJobMode(name)
+	if enabled && jobMode {
+		// the setup of a node is ready once its Jobs are complete
+		for _, daemonSet := range ctrl.states[idx].resources.Daemonsets {
+			dsJobs, err := ctrl.hostSetupJobs(daemonSet.Name)
+			if err != nil {
+				return err
+			}
+			jobs = append(jobs, dsJobs...)
+		}
+	} else if enabled {
+		for _, daemonSet := range ctrl.states[idx].resources.Daemonsets {
+			ds := &appsv1.DaemonSet{}
+			err := ctrl.rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: ctrl.operatorNamespace, Name: daemonSet.Name}, ds)
+			if errors.IsNotFound(err) {
+				// eg. no spec found for the DaemonSet
+				continue
+			} else if err != nil {
+				return err
+			}
+			daemonSets = append(daemonSets, ds)
+		}
+		err = ctrl.rec.Client.List(context.TODO(), pods, client.InNamespace(ctrl.operatorNamespace))
+		if err != nil {
+			return fmt.Errorf("unable to list pods to label state %s: %v", name, err)
+		}
+	}
+
+	selectors := fpgaNodeSelectors(&ctrl.singleton.Spec.FPGANodes, ctrl.singleton.Spec.NodeLabeller.IsEnabled())
+	key := stateReadyLabel(name)
+	setupKey := stateSetupAnnotation(name)
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		hash, ready := "", false
+		if enabled && hasFPGALables(selectors, node.Labels) {
+			if jobMode {
+				hash, ready = jobsSetupOnNode(node, jobs)
+			} else {
+				hash, ready = daemonSetsSetupOnNode(node, daemonSets, pods.Items)
+			}
+		}
+		_, labeled := node.Labels[key]
+		// the node stays labeled while the pods of the setup it is labeled for are not ready
+		keep := labeled && hash != "" && node.Annotations[setupKey] == hash
+		if ready || keep {
+			if labeled && node.Annotations[setupKey] == hash {
+				continue
+			}
+		} else if _, annotated := node.Annotations[setupKey]; !labeled && !annotated {
+			continue
+		}
+
+		patch := client.MergeFrom(node.DeepCopy())
+		if ready || keep {
+			if node.Labels == nil {
+				node.Labels = map[string]string{}
+			}
+			if node.Annotations == nil {
+				node.Annotations = map[string]string{}
+			}
+			node.Labels[key] = stateReadyValue
+			node.Annotations[setupKey] = hash
+		} else {
+			delete(node.Labels, key)
+			delete(node.Annotations, setupKey)
+		}
+		if err := ctrl.rec.Client.Patch(context.TODO(), node, patch); err != nil {
+			return fmt.Errorf("unable to label node %s for state %s: %v", node.Name, name, err)
+		}
+		ctrl.rec.Log.Info("State readiness on node changed", "State", name, "Node", node.Name, "Ready", ready || keep)
+	}
+	return nil
+}
+
+// removeReadyLabels removes the ready labels of all the states, and the hashes of their setup, from the nodes
+func (ctrl *ControlContext) removeReadyLabels() error {
+	nodes := &corev1.NodeList{}
+	err := ctrl.rec.Client.List(context.TODO(), nodes)
+	if err != nil {
+		return fmt.Errorf("unable to list nodes to remove state labels: %v", err)
+	}
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		patch := client.MergeFrom(node.DeepCopy())
+		changed := false
+		for _, values := range []map[string]string{node.Labels, node.Annotations} {
+			for key := range values {
+				if strings.HasPrefix(key, StateReadyLabelPrefix) {
+					delete(values, key)
+					changed = true
+				}
+			}
+		}
+		if !changed {
+			continue
+		}
+		if err := ctrl.rec.Client.Patch(context.TODO(), node, patch); err != nil {
+			return fmt.Errorf("unable to remove state labels from node %s: %v", node.Name, err)
+		}
+	}
+	return nil
+}
+
+// daemonSetsSetupOnNode returns the hash of the setup of the DaemonSets scheduled on the node, or an empty hash
+// if no DaemonSet is scheduled on the node, eg. its OS is not supported. The setup is ready once each DaemonSet
+// has a ready pod on the node
+func daemonSetsSetupOnNode(node *corev1.Node, daemonSets []*appsv1.DaemonSet, pods []corev1.Pod) (string, bool) {
+	hashes := []string{}
+	ready := true
+	for _, ds := range daemonSets {
+		if !nodeMatchesPodSpec(node, &ds.Spec.Template.Spec) {
+			continue
+		}
+		hash, err := setupHash(&ds.Spec.Template.Spec)
+		if err != nil {
+			return "", false
+		}
+		hashes = append(hashes, hash)
+
+		podReady := false
+		for i := range pods {
+			pod := &pods[i]
+			if pod.Spec.NodeName == node.Name && pod.DeletionTimestamp == nil &&
+				metav1.IsControlledBy(pod, ds) && isPodReady(pod) {
+				podReady = true
+				break
+			}
+		}
+		ready = ready && podReady
+	}
+	sort.Strings(hashes)
+	return strings.Join(hashes, ","), ready && len(hashes) > 0
+}
+
+// isPodReady returns true if the Ready condition of the pod is true
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// nodeSelectorOperators maps the operators of node affinity to the ones of label selectors
+var nodeSelectorOperators = map[corev1.NodeSelectorOperator]selection.Operator{
+	corev1.NodeSelectorOpIn:           selection.In,
+	corev1.NodeSelectorOpNotIn:        selection.NotIn,
+	corev1.NodeSelectorOpExists:       selection.Exists,
+	corev1.NodeSelectorOpDoesNotExist: selection.DoesNotExist,
+	corev1.NodeSelectorOpGt:           selection.GreaterThan,
+	corev1.NodeSelectorOpLt:           selection.LessThan,
+}
+
+// nodeMatchesPodSpec returns true if the node matches the node selector and the required node affinity of the pods.
+// Taints are not checked, as the pods deployed by the operator tolerate the taints of the FPGA nodes they need
+func nodeMatchesPodSpec(node *corev1.Node, podSpec *corev1.PodSpec) bool {
+	nodeLabels := labels.Set(node.Labels)
+	if !labels.SelectorFromSet(podSpec.NodeSelector).Matches(nodeLabels) {
+		return false
+	}
+	if podSpec.Affinity == nil || podSpec.Affinity.NodeAffinity == nil ||
+		podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+
+	// the terms are ORed, and the requirements of a term are ANDed
+	for _, term := range podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		if nodeMatchesTerm(node, term) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeMatchesTerm returns true if the node matches all the requirements of the node selector term
+func nodeMatchesTerm(node *corev1.Node, term corev1.NodeSelectorTerm) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		// an empty term matches no node
+		return false
+	}
+	fields := labels.Set{"metadata.name": node.Name}
+	for _, requirements := range []struct {
+		expressions []corev1.NodeSelectorRequirement
+		set         labels.Set
+	}{{term.MatchExpressions, labels.Set(node.Labels)}, {term.MatchFields, fields}} {
+		for _, expression := range requirements.expressions {
+			operator, ok := nodeSelectorOperators[expression.Operator]
+			if !ok {
+				return false
+			}
+			requirement, err := labels.NewRequirement(expression.Key, operator, expression.Values)
+			if err != nil || !requirement.Matches(requirements.set) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,183 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestOrderStates(t *testing.T) {
+	ordered, err := orderStates(stateNames, stateDependencies)
+	require.NoError(t, err)
+	require.Equal(t, []string{"state-nfd", "state-node-labeller", "state-container-runtime", "state-host-setup",
+		"state-device-plugin"}, ordered, "states are not deployed after their dependencies")
+
+	_, err = orderStates([]string{"a", "b", "c"}, map[string][]string{"a": {"c"}, "c": {"b"}, "b": {"a"}})
+	require.EqualError(t, err, "state dependency cycle: a -> c -> b -> a")
+
+	_, err = orderStates([]string{"a"}, map[string][]string{"a": {"b"}})
+	require.EqualError(t, err, "state a depends on unknown state b")
+}
+
+func TestNodeMatchesPodSpec(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   "fpga-node",
+		Labels: map[string]string{"os": "ubuntu", "fpga": "true"},
+	}}
+	affinity := func(terms ...corev1.NodeSelectorTerm) *corev1.Affinity {
+		return &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: terms},
+		}}
+	}
+	expression := func(key string, operator corev1.NodeSelectorOperator, values ...string) corev1.NodeSelectorTerm {
+		return corev1.NodeSelectorTerm{MatchExpressions: []corev1.NodeSelectorRequirement{
+			{Key: key, Operator: operator, Values: values},
+		}}
+	}
+
+	testCases := []struct {
+		description string
+		podSpec     corev1.PodSpec
+		expected    bool
+	}{
+		{"no selector", corev1.PodSpec{}, true},
+		{"node selector matched", corev1.PodSpec{NodeSelector: map[string]string{"os": "ubuntu"}}, true},
+		{"node selector not matched", corev1.PodSpec{NodeSelector: map[string]string{"os": "centos"}}, false},
+		{
+			"any affinity term matched",
+			corev1.PodSpec{Affinity: affinity(expression("os", corev1.NodeSelectorOpIn, "centos"),
+				expression("fpga", corev1.NodeSelectorOpExists))},
+			true,
+		},
+		{
+			"no affinity term matched",
+			corev1.PodSpec{Affinity: affinity(expression("os", corev1.NodeSelectorOpNotIn, "ubuntu"),
+				expression("pool", corev1.NodeSelectorOpExists))},
+			false,
+		},
+		{
+			"node name field matched",
+			corev1.PodSpec{Affinity: affinity(corev1.NodeSelectorTerm{MatchFields: []corev1.NodeSelectorRequirement{
+				{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"fpga-node"}},
+			}})},
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			require.Equal(t, tc.expected, nodeMatchesPodSpec(node, &tc.podSpec))
+		})
+	}
+}
+
+func TestLabelReadyNodes(t *testing.T) {
+	cp := clusterPolicy.DeepCopy()
+	cp.Spec.SetDefaults()
+	n, err := newTestContext(cp, filepath.Join(cfg.root, hostSetupAssestsPath))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		if err := n.removeReadyLabels(); err != nil {
+			t.Fatalf("error removing node labels: %v", err)
+		}
+		if err := deleteResources(); err != nil {
+			t.Fatalf("error removing state: %v", err)
+		}
+	})
+
+	readyNodes := func() []string {
+		list := &corev1.NodeList{}
+		require.NoError(t, n.rec.Client.List(context.TODO(), list))
+		names := []string{}
+		for _, node := range list.Items {
+			if node.Labels[stateReadyLabel("state-host-setup")] == stateReadyValue {
+				names = append(names, node.Name)
+			}
+		}
+		return names
+	}
+
+	// the nodes without a host-setup DaemonSet scheduled, eg. of an OS not supported, are not ready
+	_, err = n.step(0)
+	require.NoError(t, err)
+	require.Empty(t, readyNodes(), "node without host-setup is labeled")
+
+	ds := &appsv1.DaemonSet{}
+	err = n.rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: "host-setup-ubuntu18-daemonset"}, ds)
+	require.NoError(t, err)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "host-setup-ubuntu18-daemonset-x2k9p",
+			Namespace:       testNamespace,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(ds, appsv1.SchemeGroupVersion.WithKind("DaemonSet"))},
+		},
+		Spec: corev1.PodSpec{NodeName: "ubuntu18", Containers: []corev1.Container{{Name: "host-setup", Image: "test"}}},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{
+			{Type: corev1.PodReady, Status: corev1.ConditionFalse},
+		}},
+	}
+	require.NoError(t, n.rec.Client.Create(context.TODO(), pod))
+	require.NoError(t, n.labelReadyNodes(0))
+	require.Empty(t, readyNodes(), "node with pod not ready is labeled")
+
+	pod.Status.Conditions[0].Status = corev1.ConditionTrue
+	require.NoError(t, n.rec.Client.Update(context.TODO(), pod))
+	require.NoError(t, n.labelReadyNodes(0))
+	require.ElementsMatch(t, []string{"ubuntu18"}, readyNodes(), "node with ready pod is not labeled")
+
+	// the label is kept while the pod set up for the current version is not ready, eg. restarted
+	pod.Status.Conditions[0].Status = corev1.ConditionFalse
+	require.NoError(t, n.rec.Client.Update(context.TODO(), pod))
+	require.NoError(t, n.labelReadyNodes(0))
+	require.ElementsMatch(t, []string{"ubuntu18"}, readyNodes(), "node set up is unlabeled once its pod is not ready")
+
+	// the label is removed once the setup of the node is changed, until the pod is ready again
+	for i := range cp.Spec.HostSetup.OsDists {
+		if cp.Spec.HostSetup.OsDists[i].OsMajorVersion == "18" {
+			cp.Spec.HostSetup.OsDists[i].Version = "2022.2"
+		}
+	}
+	_, err = n.step(0)
+	require.NoError(t, err)
+	require.Empty(t, readyNodes(), "node is labeled for a previous setup")
+	node := &corev1.Node{}
+	require.NoError(t, n.rec.Client.Get(context.TODO(), types.NamespacedName{Name: "ubuntu18"}, node))
+	require.NotContains(t, node.Annotations, stateSetupAnnotation("state-host-setup"))
+
+	pod.Status.Conditions[0].Status = corev1.ConditionTrue
+	require.NoError(t, n.rec.Client.Update(context.TODO(), pod))
+	require.NoError(t, n.labelReadyNodes(0))
+	require.ElementsMatch(t, []string{"ubuntu18"}, readyNodes(), "node with ready pod is not labeled")
+	require.NoError(t, n.rec.Client.Get(context.TODO(), types.NamespacedName{Name: "ubuntu18"}, node))
+	require.NoError(t, n.rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: testNamespace, Name: ds.Name}, ds))
+	hash, err := setupHash(&ds.Spec.Template.Spec)
+	require.NoError(t, err)
+	require.Equal(t, hash, node.Annotations[stateSetupAnnotation("state-host-setup")])
+
+	// the labels are removed once the state is disabled
+	*cp.Spec.HostSetup.Enabled = false
+	require.NoError(t, n.labelReadyNodes(0))
+	require.Empty(t, readyNodes(), "nodes of disabled state are labeled")
+}
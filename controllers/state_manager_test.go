@@ -17,10 +17,20 @@ limitations under the License.
 package controllers
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
+	"github.com/stretchr/testify/require"
 	policyv1 "github.com/xilinx/fpga-operator/api/v1"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestGetRuntimeString(t *testing.T) {
@@ -62,3 +72,170 @@ func TestGetRuntimeString(t *testing.T) {
 		})
 	}
 }
+
+func TestHasFPGALabels(t *testing.T) {
+	testCases := []struct {
+		description  string
+		fpgaNodes    policyv1.FPGANodeSpec
+		nodeLabeller bool
+		labels       map[string]string
+		expected     bool
+	}{
+		{
+			"default",
+			policyv1.FPGANodeSpec{},
+			false,
+			map[string]string{"feature.node.kubernetes.io/pci-1200_10ee.present": "true"},
+			true,
+		},
+		{
+			"default with other class",
+			policyv1.FPGANodeSpec{},
+			false,
+			map[string]string{"feature.node.kubernetes.io/pci-0b40_10ee.present": "true"},
+			false,
+		},
+		{
+			"any of pci devices",
+			policyv1.FPGANodeSpec{PCIDevices: []policyv1.PCIDeviceSpec{
+				{Vendor: "10ee", Class: "1200"},
+				{Vendor: "10ee", Class: "0b40"},
+			}},
+			false,
+			map[string]string{"feature.node.kubernetes.io/pci-0b40_10ee.present": "true"},
+			true,
+		},
+		{
+			"pci vendor and device",
+			policyv1.FPGANodeSpec{PCIDevices: []policyv1.PCIDeviceSpec{{Vendor: "1022", Device: "5700"}}},
+			false,
+			map[string]string{"feature.node.kubernetes.io/pci-1022_5700.present": "true"},
+			true,
+		},
+		{
+			"node selector",
+			policyv1.FPGANodeSpec{NodeSelector: map[string]string{"example.com/fpga": "true", "example.com/pool": "fpga"}},
+			false,
+			map[string]string{"example.com/fpga": "true", "example.com/pool": "fpga"},
+			true,
+		},
+		{
+			"node selector partially matched",
+			policyv1.FPGANodeSpec{NodeSelector: map[string]string{"example.com/fpga": "true", "example.com/pool": "fpga"}},
+			false,
+			map[string]string{"example.com/fpga": "true", "feature.node.kubernetes.io/pci-1200_10ee.present": "true"},
+			false,
+		},
+		{
+			"node labeller",
+			policyv1.FPGANodeSpec{},
+			true,
+			map[string]string{"fpga.xilinx.com/fpga.present": "true"},
+			true,
+		},
+		{
+			"node labeller disabled",
+			policyv1.FPGANodeSpec{},
+			false,
+			map[string]string{"fpga.xilinx.com/fpga.present": "true"},
+			false,
+		},
+		{
+			"node selector with node labeller",
+			policyv1.FPGANodeSpec{NodeSelector: map[string]string{"example.com/fpga": "true"}},
+			true,
+			map[string]string{"fpga.xilinx.com/fpga.present": "true"},
+			false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			require.Equal(t, tc.expected, hasFPGALables(fpgaNodeSelectors(&tc.fpgaNodes, tc.nodeLabeller), tc.labels))
+		})
+	}
+}
+
+func TestTeardown(t *testing.T) {
+	n, err := newTestContext(clusterPolicy.DeepCopy(),
+		filepath.Join(cfg.root, containerRuntimeAssestsPath), filepath.Join(cfg.root, devicePluginAssestsPath))
+	require.NoError(t, err)
+	n.runtime = policyv1.Containerd
+
+	// deploy all the states
+	for idx := range n.states {
+		_, err := n.step(idx)
+		require.NoError(t, err, "error creating resources")
+	}
+
+	dsList := &appsv1.DaemonSetList{}
+	require.NoError(t, n.rec.Client.List(context.TODO(), dsList))
+	require.Equal(t, 2, len(dsList.Items), "unexpected # of daemonsets before teardown")
+
+	// teardown is performed over several reconciliations
+	uninstallCreated := false
+	done := false
+	for i := 0; i < 10 && !done; i++ {
+		var err error
+		done, err = n.teardown()
+		require.NoError(t, err, "error tearing down resources")
+
+		require.NoError(t, n.rec.Client.List(context.TODO(), dsList))
+		for _, ds := range dsList.Items {
+			if ds.Name != "xilinx-container-runtime-daemonset"+xcrUninstallSuffix {
+				continue
+			}
+			uninstallCreated = true
+			require.Equal(t, 1, len(ds.Spec.Template.Spec.InitContainers), "uninstall init container not found")
+			require.Contains(t, ds.Spec.Template.Spec.InitContainers[0].Args[1], "xilinx-container-toolkit uninstall")
+		}
+	}
+	require.True(t, done, "teardown not completed")
+	require.True(t, uninstallCreated, "uninstall daemonset not created")
+
+	require.NoError(t, n.rec.Client.List(context.TODO(), dsList))
+	require.Equal(t, 0, len(dsList.Items), "unexpected # of daemonsets after teardown")
+	rcList := &nodev1.RuntimeClassList{}
+	require.NoError(t, n.rec.Client.List(context.TODO(), rcList))
+	require.Equal(t, 0, len(rcList.Items), "unexpected # of runtimeclasses after teardown")
+}
+
+func TestNewControlContextConcurrently(t *testing.T) {
+	t.Setenv("OPERATOR_NAMESPACE", testNamespace)
+	newReconciler := func() *ClusterPolicyReconciler {
+		clientset := fake.NewSimpleClientset()
+		clientset.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{GitVersion: "v1.26.0"}
+		return &ClusterPolicyReconciler{
+			Client:    clusterPolicyReconciler.Client,
+			Log:       clusterPolicyReconciler.Log,
+			Scheme:    clusterPolicyReconciler.Scheme,
+			Recorder:  eventRecorder,
+			Assets:    os.DirFS(filepath.Join(cfg.root, "assets")),
+			Clientset: clientset,
+		}
+	}
+
+	// the reconciliations of several reconcilers share the states of their reconciler and the metrics
+	reconcilers := []*ClusterPolicyReconciler{newReconciler(), newReconciler()}
+	contexts := make([]*ControlContext, 8)
+	errs := make([]error, len(contexts))
+	var wg sync.WaitGroup
+	for i := range contexts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			contexts[i], errs[i] = reconcilers[i%len(reconcilers)].newControlContext(clusterPolicy.DeepCopy())
+		}(i)
+	}
+	wg.Wait()
+
+	for i, n := range contexts {
+		require.NoError(t, errs[i])
+		require.Equal(t, "v1.26.0", n.k8sVersion)
+		require.Equal(t, testNamespace, n.operatorNamespace)
+		require.Len(t, n.states, len(stateNames))
+		require.True(t, n.hasFPGANodes)
+		require.Same(t, contexts[0].operatorMetrics, n.operatorMetrics, "metrics registered twice")
+		require.Same(t, &contexts[i%len(reconcilers)].states[0], &n.states[0], "states loaded twice by a reconciler")
+	}
+}
@@ -19,20 +19,31 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"io/fs"
 	"os"
-	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	// apiconfigv1 "github.com/openshift/api/config/v1"
 	// apiimagev1 "github.com/openshift/api/image/v1"
 	// secv1 "github.com/openshift/api/security/v1"
 	// promv1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/go-logr/logr"
 	policyv1 "github.com/xilinx/fpga-operator/api/v1"
+	"github.com/xilinx/fpga-operator/labeller"
 	"golang.org/x/mod/semver"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	nodev1 "k8s.io/api/node/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
 const (
@@ -40,30 +51,62 @@ const (
 	nfdLabelOSReleaseID    = "feature.node.kubernetes.io/system-os_release.ID"
 	nfdLabelOSVersionID    = "feature.node.kubernetes.io/system-os_release.VERSION_ID"
 	nfdLabelOsMajorVersion = "feature.node.kubernetes.io/system-os_release.VERSION_ID.major"
+	nfdLabelPCIPrefix      = "feature.node.kubernetes.io/pci-"
 )
 
-var fpgaNodeLabels = map[string]string{
-	// "feature.node.kubernetes.io/pci-10ee.present":      "true",
-	"feature.node.kubernetes.io/pci-1200_10ee.present": "true",
+// stateNames are the states in the order of deployment, which are the directories of their asset files.
+// The states are reordered to be deployed after the states they depend on
+var stateNames = []string{
+	"state-nfd",
+	"state-node-labeller",
+	"state-container-runtime",
+	"state-device-plugin",
+	"state-host-setup",
 }
 
-// ClusterPolicyController represents clusterpolicy controller spec for FPGA operator
+// state is a state loaded from the assets, which is read-only once loaded
+type state struct {
+	name         string
+	resources    Resources
+	controlFuncs controlFuncs
+	// err is the error loading the assets, which fails the state only
+	err error
+}
+
+// ClusterPolicyController holds the states loaded from the assets and the cluster info detected once.
+// It is owned by ClusterPolicyReconciler, and is safe for concurrent reconciliations
 type ClusterPolicyController struct {
-	singleton         *policyv1.ClusterPolicy
-	operatorNamespace string
+	mu sync.Mutex
+
+	states []state
+	// assetsVersion identifies the ConfigMap overriding the assets the states are loaded with
+	assetsVersion string
 
-	resources    []Resources
-	controlFuncs []controlFuncs
-	stateNames   []string
-	rec          *ClusterPolicyReconciler
-	idx          int
+	operatorNamespace string
+	k8sVersion        string
+	operatorMetrics   *OperatorMetrics
+}
 
-	k8sVersion string
+// ControlContext is the context of a reconciliation passed to the control functions, with the ClusterPolicy
+// rendered, the cluster info detected in the reconciliation, and the state being reconciled
+type ControlContext struct {
+	singleton         *policyv1.ClusterPolicy
+	rec               *ClusterPolicyReconciler
+	operatorNamespace string
+	k8sVersion        string
+	// operatorMetrics is set along with the states loaded, so it is never nil in a reconciliation
+	operatorMetrics *OperatorMetrics
 
 	runtime      policyv1.Runtime
-	osDists      map[string]bool
+	osDists      map[string]int
 	hasFPGANodes bool
 	hasNFDLabels bool
+
+	// states are shared by the reconciliations and must not be modified
+	states []state
+	// stateName and resources of the state being reconciled
+	stateName string
+	resources *Resources
 }
 
 // hasNFDLabels return true if node labels contain NFD labels
@@ -76,18 +119,56 @@ func hasNFDLabels(labels map[string]string) bool {
 	return false
 }
 
-func hasFPGALables(labels map[string]string) bool {
-	for key, val := range labels {
-		if _, ok := fpgaNodeLabels[key]; ok {
-			if fpgaNodeLabels[key] == val {
-				return true
+// pciDeviceLabel returns the NFD label of the PCI device, the fields are in the order of NFD deviceLabelFields
+func pciDeviceLabel(device policyv1.PCIDeviceSpec) string {
+	fields := []string{}
+	for _, field := range []string{device.Class, device.Vendor, device.Device} {
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return nfdLabelPCIPrefix + strings.Join(fields, "_") + ".present"
+}
+
+// fpgaNodeSelectors returns the label sets of FPGA nodes, a node with all the labels of any set is an FPGA node.
+// The label of the built-in node labeller is also matched if it is enabled
+func fpgaNodeSelectors(spec *policyv1.FPGANodeSpec, nodeLabeller bool) []map[string]string {
+	// never select all the nodes, even if ClusterPolicy is not defaulted
+	if len(spec.NodeSelector) == 0 && len(spec.PCIDevices) == 0 {
+		spec = &policyv1.FPGANodeSpec{}
+		spec.SetDefaults()
+	}
+	if len(spec.NodeSelector) > 0 {
+		return []map[string]string{spec.NodeSelector}
+	}
+	selectors := []map[string]string{}
+	for _, device := range spec.PCIDevices {
+		selectors = append(selectors, map[string]string{pciDeviceLabel(device): "true"})
+	}
+	if nodeLabeller {
+		selectors = append(selectors, map[string]string{labeller.LabelFPGAPresent: "true"})
+	}
+	return selectors
+}
+
+// hasFPGALables return true if node labels match any of the FPGA node selectors
+func hasFPGALables(selectors []map[string]string, labels map[string]string) bool {
+	for _, selector := range selectors {
+		matched := true
+		for key, val := range selector {
+			if labels[key] != val {
+				matched = false
+				break
 			}
 		}
+		if matched {
+			return true
+		}
 	}
 	return false
 }
 
-func (ctrl *ClusterPolicyController) getFPGANodeCount() (bool, int, error) {
+func (ctrl *ControlContext) getFPGANodeCount() (bool, int, error) {
 	// fetch all nodes
 	opts := []client.ListOption{}
 	nodes := &corev1.NodeList{}
@@ -98,13 +179,14 @@ func (ctrl *ClusterPolicyController) getFPGANodeCount() (bool, int, error) {
 
 	clusterHasNFDLabels := false
 	fpgaNodesTotal := 0
+	selectors := fpgaNodeSelectors(&ctrl.singleton.Spec.FPGANodes, ctrl.singleton.Spec.NodeLabeller.IsEnabled())
 	for _, node := range nodes.Items {
 		// get node labels
 		labels := node.GetLabels()
 		if !clusterHasNFDLabels {
 			clusterHasNFDLabels = hasNFDLabels(labels)
 		}
-		if hasFPGALables(labels) {
+		if hasFPGALables(selectors, labels) {
 			fpgaNodesTotal++
 			ctrl.rec.Log.Info("Node has FGPA(s)", "NodeName", node.ObjectMeta.Name)
 		}
@@ -113,7 +195,8 @@ func (ctrl *ClusterPolicyController) getFPGANodeCount() (bool, int, error) {
 	return clusterHasNFDLabels, fpgaNodesTotal, nil
 }
 
-func (ctrl *ClusterPolicyController) getOsDistributions() (map[string]bool, error) {
+// getOsDistributions returns the number of nodes per OS distribution
+func (ctrl *ControlContext) getOsDistributions() (map[string]int, error) {
 	// fetch all nodes
 	opts := []client.ListOption{}
 	nodes := &corev1.NodeList{}
@@ -122,60 +205,194 @@ func (ctrl *ClusterPolicyController) getOsDistributions() (map[string]bool, erro
 		return nil, fmt.Errorf("unable to list nodes to check labels, err %s", err.Error())
 	}
 
-	dists := make(map[string]bool)
+	dists := make(map[string]int)
 	for _, node := range nodes.Items {
 		// get node labels
 		labels := node.GetLabels()
 		release := labels[nfdLabelOSReleaseID]
 		version := labels[nfdLabelOSVersionID]
-		dists[release+version] = true
+		if release == "" {
+			// labeled by the built-in node labeller instead of NFD
+			release = labels[labeller.LabelOSReleaseID]
+			version = labels[labeller.LabelOSVersionID]
+		}
+		dists[release+version]++
 	}
 	return dists, nil
 }
 
-func addState(ctrl *ClusterPolicyController, path string) error {
-	res, ctrlFunc := addResourceControls(ctrl, path)
-	ctrl.resources = append(ctrl.resources, res)
-	ctrl.controlFuncs = append(ctrl.controlFuncs, ctrlFunc)
-	ctrl.stateNames = append(ctrl.stateNames, filepath.Base(path))
-	return nil
+// loadState loads the state from the asset files in its directory of the layers, a file of a later layer
+// overrides the file of the same name in the earlier layers, and overrides replace the files of all layers
+func loadState(logger logr.Logger, name string, layers []fs.FS, overrides map[string][]byte) (state, error) {
+	files := map[string][]byte{}
+	for _, layer := range layers {
+		layerFiles, err := readAssets(layer, name)
+		if err != nil {
+			return state{}, &AssetsError{State: name, Err: err}
+		}
+		for file, data := range layerFiles {
+			files[file] = data
+		}
+	}
+	for file, data := range overrides {
+		files[file] = data
+	}
+	if len(files) == 0 {
+		return state{}, &AssetsError{State: name, Err: fmt.Errorf("no asset file found")}
+	}
+
+	res, ctrlFuncs, err := addResourceControls(logger, name, getAssetsFrom(files))
+	if err != nil {
+		return state{}, &AssetsError{State: name, Err: err}
+	}
+	return state{name: name, resources: res, controlFuncs: ctrlFuncs}, nil
+}
+
+// loadStates loads all the states from the embedded assets, the assets directory and the ConfigMap of ClusterPolicy
+func (r *ClusterPolicyReconciler) loadStates(cm *corev1.ConfigMap) ([]state, error) {
+	layers := []fs.FS{}
+	if r.Assets != nil {
+		layers = append(layers, r.Assets)
+	}
+	if r.AssetsDir != "" {
+		layers = append(layers, os.DirFS(r.AssetsDir))
+	}
+	overrides := map[string]map[string][]byte{}
+	if cm != nil {
+		var err error
+		overrides, err = assetsConfigMapFiles(cm, stateNames)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	names, err := orderStates(stateNames, stateDependencies)
+	if err != nil {
+		return nil, err
+	}
+
+	states := []state{}
+	for _, name := range names {
+		st, err := loadState(r.Log, name, layers, overrides[name])
+		if err != nil {
+			r.Log.Error(err, "Failed to load state", "State", name)
+			st = state{name: name, err: err}
+		}
+		states = append(states, st)
+	}
+	return states, nil
+}
+
+// assetsConfigMap returns the ConfigMap overriding the assets, and the version of the assets it identifies.
+// It returns nil and an empty version if ClusterPolicy has no ConfigMap
+func (r *ClusterPolicyReconciler) assetsConfigMap(namespace string, clusterPolicy *policyv1.ClusterPolicy) (*corev1.ConfigMap, string, error) {
+	name := clusterPolicy.Spec.Operator.AssetsConfigMap
+	if name == "" {
+		return nil, "", nil
+	}
+	cm := &corev1.ConfigMap{}
+	err := r.Client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, cm)
+	if err != nil {
+		return nil, "", &AssetsError{State: "ConfigMap " + name, Err: err}
+	}
+	return cm, name + "/" + cm.ResourceVersion, nil
 }
 
-// init adds all the states declared in specs
-func (ctrl *ClusterPolicyController) init(reconciler *ClusterPolicyReconciler, clusterPolicy *policyv1.ClusterPolicy) error {
-	ctrl.singleton = clusterPolicy
-	ctrl.rec = reconciler
-	ctrl.idx = 0
+// load loads the states from the assets and detects the Kubernetes version once, the states are
+// loaded again if the ConfigMap overriding the assets is changed. It returns the context shared by the reconciliations
+func (c *ClusterPolicyController) load(r *ClusterPolicyReconciler, clusterPolicy *policyv1.ClusterPolicy) (ControlContext, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	if len(ctrl.controlFuncs) == 0 {
-		ctrl.operatorNamespace = os.Getenv("OPERATOR_NAMESPACE")
-		if clusterPolicyCtrl.operatorNamespace == "" {
-			ctrl.rec.Log.Info("OPERATOR_NAMESPACE environment variable not set, using default")
-			ctrl.operatorNamespace = "default"
+	if c.operatorMetrics == nil {
+		c.operatorMetrics = initOperatorMetrics()
+	}
+
+	if c.k8sVersion == "" {
+		c.operatorNamespace = os.Getenv("OPERATOR_NAMESPACE")
+		if c.operatorNamespace == "" {
+			r.Log.Info("OPERATOR_NAMESPACE environment variable not set, using default")
+			c.operatorNamespace = "default"
 		}
 
-		k8sVersion, err := kubernetesVersion()
+		k8sVersion, err := kubernetesVersion(r.Clientset)
 		if err != nil {
-			return err
+			return ControlContext{}, err
 		}
 		if !semver.IsValid(k8sVersion) {
-			return fmt.Errorf("k8s version detected '%s' is not a valid semantic version", k8sVersion)
+			return ControlContext{}, fmt.Errorf("k8s version detected '%s' is not a valid semantic version", k8sVersion)
 		}
-		ctrl.k8sVersion = k8sVersion
-		ctrl.rec.Log.Info("Kubernetes version detected", "version", k8sVersion)
+		c.k8sVersion = k8sVersion
+		r.Log.Info("Kubernetes version detected", "version", k8sVersion)
+	}
 
-		// detect the container runtime on worker nodes
-		err = ctrl.getRuntime()
+	// add components
+	cm, version, err := r.assetsConfigMap(c.operatorNamespace, clusterPolicy)
+	if err != nil && clusterPolicy.ObjectMeta.DeletionTimestamp.IsZero() {
+		return ControlContext{}, err
+	} else if err != nil {
+		// the ConfigMap may be deleted before ClusterPolicy, which is torn down with the loaded states
+		r.Log.Info("Ignoring assets ConfigMap for teardown", "error", err.Error())
+		version = c.assetsVersion
+	}
+	if len(c.states) == 0 || version != c.assetsVersion {
+		states, err := r.loadStates(cm)
 		if err != nil {
-			return err
+			return ControlContext{}, err
 		}
-		ctrl.rec.Log.Info(fmt.Sprintf("Using container runtime: %s", ctrl.runtime.String()))
+		c.states = states
+		c.assetsVersion = version
+	}
+
+	return ControlContext{
+		operatorNamespace: c.operatorNamespace,
+		k8sVersion:        c.k8sVersion,
+		operatorMetrics:   c.operatorMetrics,
+		states:            c.states,
+	}, nil
+}
+
+// namespace returns the operator namespace, empty before the states are loaded
+func (c *ClusterPolicyController) namespace() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.operatorNamespace
+}
+
+// metrics returns the operator metrics, nil before the states are loaded
+func (c *ClusterPolicyController) metrics() *OperatorMetrics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.operatorMetrics
+}
+
+// newControlContext returns the context to reconcile clusterPolicy, with the cluster info detected
+func (r *ClusterPolicyReconciler) newControlContext(clusterPolicy *policyv1.ClusterPolicy) (*ControlContext, error) {
+	n, err := r.ctrl.load(r, clusterPolicy)
+	if err != nil {
+		return nil, err
+	}
+	// render with the defaults, in case ClusterPolicy is not defaulted by the webhook
+	n.singleton = clusterPolicy.DeepCopy()
+	n.singleton.Spec.SetDefaults()
+	n.rec = r
+
+	err = n.detect()
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
 
-		// add components
-		addState(ctrl, "/opt/fpga-operator/state-container-runtime")
-		addState(ctrl, "/opt/fpga-operator/state-device-plugin")
-		addState(ctrl, "/opt/fpga-operator/state-host-setup")
+// detect detects the container runtime, FPGA nodes and OS distributions of the nodes
+func (ctrl *ControlContext) detect() error {
+	// detect the container runtime on worker nodes, which may change as nodes join or are upgraded
+	err := ctrl.getRuntime()
+	if err != nil {
+		return err
 	}
+	ctrl.rec.Log.V(1).Info(fmt.Sprintf("Using container runtime: %s", ctrl.runtime.String()))
+	ctrl.operatorMetrics.setContainerRuntime(ctrl.runtime)
 
 	hasNFDLabels, fpgaNodeCount, err := ctrl.getFPGANodeCount()
 	if err != nil {
@@ -183,20 +400,35 @@ func (ctrl *ClusterPolicyController) init(reconciler *ClusterPolicyReconciler, c
 	}
 	ctrl.hasNFDLabels = hasNFDLabels
 	ctrl.hasFPGANodes = fpgaNodeCount > 0
+	ctrl.operatorMetrics.fpgaNodes.Set(float64(fpgaNodeCount))
 
 	osDists, err := ctrl.getOsDistributions()
 	if err != nil {
 		return err
 	}
 	ctrl.osDists = osDists
+	ctrl.operatorMetrics.setOsDistributions(osDists)
 	return nil
 }
 
-func (ctrl *ClusterPolicyController) step() (policyv1.State, error) {
+// forState returns the context to reconcile the state at index idx
+func (ctrl ControlContext) forState(idx int) ControlContext {
+	ctrl.stateName = ctrl.states[idx].name
+	ctrl.resources = &ctrl.states[idx].resources
+	return ctrl
+}
+
+// step reconciles the state at index idx
+func (ctrl *ControlContext) step(idx int) (policyv1.State, error) {
 	result := policyv1.Ready
 
-	for _, fs := range ctrl.controlFuncs[ctrl.idx] {
-		state, err := fs(*ctrl)
+	if ctrl.states[idx].err != nil {
+		return policyv1.NotReady, ctrl.states[idx].err
+	}
+
+	n := ctrl.forState(idx)
+	for _, fs := range ctrl.states[idx].controlFuncs {
+		state, err := fs(n)
 		if err != nil {
 			// failed to deploy resource
 			return state, err
@@ -207,19 +439,357 @@ func (ctrl *ClusterPolicyController) step() (policyv1.State, error) {
 		}
 	}
 
-	// move to next state
-	ctrl.idx = ctrl.idx + 1
+	// the states depending on this one are scheduled on the nodes labeled ready
+	if isDependency(ctrl.states[idx].name) {
+		if err := ctrl.labelReadyNodes(idx); err != nil {
+			return policyv1.NotReady, err
+		}
+	}
 	return result, nil
 }
 
-func (ctrl ClusterPolicyController) last() bool {
-	return ctrl.idx == len(ctrl.controlFuncs)
+// componentStatus returns status of the state at index idx, including rollout status of its DaemonSets
+func (ctrl *ControlContext) componentStatus(idx int, state policyv1.State, stateErr error) policyv1.ComponentStatus {
+	component := policyv1.ComponentStatus{
+		Name:  ctrl.states[idx].name,
+		State: state,
+	}
+
+	daemonSetsNotReady := []string{}
+	daemonSetsNoNodes := []string{}
+	jobsNotComplete := []string{}
+	for _, daemonSet := range ctrl.states[idx].resources.Daemonsets {
+		if ctrl.isHostSetupJobMode(component.Name) {
+			jobs, err := ctrl.hostSetupJobs(daemonSet.Name)
+			if err == nil && len(jobs) > 0 && !isSetupComplete(jobs) {
+				jobsNotComplete = append(jobsNotComplete, fmt.Sprintf("%s (%s)", daemonSet.Name, hostSetupJobsProgress(jobs)))
+			}
+			continue
+		}
+		ds := &appsv1.DaemonSet{}
+		err := ctrl.rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: ctrl.operatorNamespace, Name: daemonSet.Name}, ds)
+		if err != nil {
+			// daemonset is not deployed, eg. state disabled or no spec found
+			continue
+		}
+		component.DaemonSets = append(component.DaemonSets, policyv1.DaemonSetStatus{
+			Name:                   ds.Name,
+			NodeSelector:           ds.Spec.Template.Spec.NodeSelector,
+			DesiredNumberScheduled: ds.Status.DesiredNumberScheduled,
+			NumberReady:            ds.Status.NumberReady,
+			NumberUnavailable:      ds.Status.NumberUnavailable,
+			UpdatedNumberScheduled: ds.Status.UpdatedNumberScheduled,
+			NumberAvailable:        ds.Status.NumberAvailable,
+		})
+		switch daemonSetRolloutState(ds) {
+		case policyv1.NotReady:
+			daemonSetsNotReady = append(daemonSetsNotReady, fmt.Sprintf("%s (%s)", ds.Name, daemonSetRolloutProgress(ds)))
+		case policyv1.NoMatchingNodes:
+			daemonSetsNoNodes = append(daemonSetsNoNodes, ds.Name)
+		}
+	}
+
+	for _, deployment := range ctrl.states[idx].resources.Deployments {
+		found := &appsv1.Deployment{}
+		err := ctrl.rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: ctrl.operatorNamespace, Name: deployment.Name}, found)
+		if err != nil {
+			// deployment is not deployed, eg. state disabled
+			continue
+		}
+		component.Deployments = append(component.Deployments, policyv1.DeploymentStatus{
+			Name:                found.Name,
+			Replicas:            found.Status.Replicas,
+			UnavailableReplicas: found.Status.UnavailableReplicas,
+		})
+	}
+
+	resources := ctrl.states[idx].resources
+	skippable := append([]client.Object{}, resources.Objects...)
+	if resources.NodeFeatureRule.Object != nil {
+		skippable = append(skippable, &resources.NodeFeatureRule)
+	}
+	for _, obj := range skippable {
+		if !isKindInstalled(*ctrl, obj) {
+			component.SkippedObjects = append(component.SkippedObjects, skippedObjectRef(obj))
+		}
+	}
+
+	switch {
+	case isAssetsError(stateErr):
+		component.Reason = policyv1.ReasonAssetsLoadFailed
+		component.Message = stateErr.Error()
+	case stateErr != nil:
+		component.Reason = policyv1.ReasonReconcileFailed
+		component.Message = stateErr.Error()
+	case state == policyv1.Ready:
+		component.Reason = policyv1.ReasonStateReady
+	case state == policyv1.Disabled:
+		component.Reason = policyv1.ReasonStateDisabled
+		component.Message = "State is disabled in ClusterPolicy"
+	case state == policyv1.Ignored:
+		component.Reason = policyv1.ReasonNoMatchingSpec
+		component.Message = "No spec found in ClusterPolicy for some DaemonSets"
+	case state == policyv1.NoMatchingNodes:
+		component.Reason = policyv1.ReasonNoMatchingNodes
+		component.Message = fmt.Sprintf("No node matches the node selector of DaemonSets: %s", strings.Join(daemonSetsNoNodes, ", "))
+		if ctrl.isHostSetupJobMode(component.Name) {
+			component.Message = "No node matches the node selector of the Jobs"
+		}
+		if dependencies := ctrl.enabledDependencies(component.Name); len(dependencies) > 0 {
+			component.Message += fmt.Sprintf(", or no node has %s ready", strings.Join(dependencies, ", "))
+		}
+	default:
+		component.Reason = policyv1.ReasonDaemonSetsNotReady
+		component.Message = "Waiting for resources to become ready"
+		if len(daemonSetsNotReady) > 0 {
+			component.Message = fmt.Sprintf("DaemonSets not ready: %s", strings.Join(daemonSetsNotReady, ", "))
+		}
+		if len(jobsNotComplete) > 0 {
+			component.Reason = policyv1.ReasonJobsNotComplete
+			component.Message = fmt.Sprintf("Jobs not complete: %s", strings.Join(jobsNotComplete, ", "))
+		}
+	}
+	return component
+}
+
+// checkProgress keeps the transition time of the state at index idx from the previous status. Once the DaemonSets
+// or Jobs of the state are not ready for longer than its progress deadline, it reports the failing containers of their pods
+func (ctrl *ControlContext) checkProgress(idx int, component *policyv1.ComponentStatus,
+	previous []policyv1.ComponentStatus, now time.Time) {
+	component.LastTransitionTime = &metav1.Time{Time: now}
+	wasExceeded := false
+	for _, prev := range previous {
+		if prev.Name == component.Name && prev.State == component.State && prev.LastTransitionTime != nil {
+			component.LastTransitionTime = prev.LastTransitionTime.DeepCopy()
+			wasExceeded = prev.Reason == policyv1.ReasonProgressDeadlineExceeded
+		}
+	}
+
+	deadline := ctrl.progressDeadline(component.Name)
+	notReady := component.Reason == policyv1.ReasonDaemonSetsNotReady || component.Reason == policyv1.ReasonJobsNotComplete
+	if !notReady || now.Sub(component.LastTransitionTime.Time) < deadline {
+		return
+	}
+
+	failures := []string{}
+	for _, daemonSet := range ctrl.states[idx].resources.Daemonsets {
+		if component.Reason == policyv1.ReasonJobsNotComplete {
+			if failure, ok := ctrl.hostSetupJobsFailure(daemonSet.Name); ok {
+				failures = append(failures, failure)
+			}
+			continue
+		}
+		ds := &appsv1.DaemonSet{}
+		err := ctrl.rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: ctrl.operatorNamespace, Name: daemonSet.Name}, ds)
+		if err != nil || daemonSetRolloutState(ds) != policyv1.NotReady {
+			continue
+		}
+		podFailures, err := ctrl.diagnoseDaemonSet(ds)
+		if err != nil {
+			ctrl.rec.Log.Error(err, "Could not list pods of DaemonSet", "DaemonSet", ds.Name)
+		}
+		failures = append(failures, summarizeFailures(ds.Name, daemonSetRolloutProgress(ds), podFailures))
+	}
+
+	resources := "DaemonSets not ready"
+	if component.Reason == policyv1.ReasonJobsNotComplete {
+		resources = "Jobs not complete"
+	}
+	component.Reason = policyv1.ReasonProgressDeadlineExceeded
+	component.Message = fmt.Sprintf("%s within %s: %s", resources, deadline, strings.Join(failures, "; "))
+	if !wasExceeded {
+		recordEvent(*ctrl, nil, corev1.EventTypeWarning, policyv1.ReasonProgressDeadlineExceeded,
+			fmt.Sprintf("State %s: %s", component.Name, component.Message))
+	}
+}
+
+// summarizeFailures returns the first failing pod of a resource, or its progress if no pod is failing
+func summarizeFailures(name string, progress string, podFailures []string) string {
+	switch len(podFailures) {
+	case 0:
+		return fmt.Sprintf("%s (%s)", name, progress)
+	case 1:
+		return podFailures[0]
+	default:
+		return fmt.Sprintf("%s (and %d more failing pods)", podFailures[0], len(podFailures)-1)
+	}
 }
 
-func (n ClusterPolicyController) isStateEnabled(stateName string) bool {
+// progressDeadline returns the time for the DaemonSets of the state to roll out before it is reported as degraded
+func (n ControlContext) progressDeadline(stateName string) time.Duration {
 	clusterPolicySpec := &n.singleton.Spec
 
+	var seconds *int32
 	switch stateName {
+	case "state-nfd":
+		seconds = clusterPolicySpec.NFD.ProgressDeadlineSeconds
+	case "state-node-labeller":
+		seconds = clusterPolicySpec.NodeLabeller.ProgressDeadlineSeconds
+	case "state-container-runtime":
+		seconds = clusterPolicySpec.ContainerRuntime.ProgressDeadlineSeconds
+	case "state-device-plugin":
+		seconds = clusterPolicySpec.DevicePlugin.ProgressDeadlineSeconds
+	case "state-host-setup":
+		seconds = clusterPolicySpec.HostSetup.ProgressDeadlineSeconds
+	}
+	if seconds == nil {
+		return time.Duration(policyv1.DefaultProgressDeadlineSeconds) * time.Second
+	}
+	return time.Duration(*seconds) * time.Second
+}
+
+// teardown removes resources of all the states in reverse order. It returns true once all the resources are removed,
+// otherwise it has to be called again after the resources being deleted are gone
+func (ctrl *ControlContext) teardown() (bool, error) {
+	for idx := len(ctrl.states) - 1; idx >= 0; idx-- {
+		done, err := ctrl.teardownState(idx)
+		if err != nil || !done {
+			return false, err
+		}
+	}
+	return true, ctrl.removeReadyLabels()
+}
+
+// teardownState removes resources of the state at index idx
+func (ctrl *ControlContext) teardownState(idx int) (bool, error) {
+	logger := ctrl.rec.Log.WithValues("State", ctrl.states[idx].name)
+
+	// remove the daemonsets deployed by the state
+	done := true
+	for _, daemonSet := range ctrl.states[idx].resources.Daemonsets {
+		gone, err := ctrl.deleteDaemonSet(daemonSet.Name)
+		if err != nil {
+			return false, err
+		}
+		done = done && gone
+		if ctrl.states[idx].name == "state-host-setup" {
+			gone, err := ctrl.deleteHostSetupJobs(daemonSet.Name, nil)
+			if err != nil {
+				return false, err
+			}
+			done = done && gone
+		}
+	}
+	if !done {
+		logger.Info("Waiting for DaemonSets and Jobs to be removed")
+		return false, nil
+	}
+
+	if ctrl.states[idx].resources.RuntimeClass.Name == "" {
+		return true, nil
+	}
+
+	// revert the runtime config on nodes before removing the runtimeclass
+	runtimeClass := &nodev1.RuntimeClass{}
+	err := ctrl.rec.Client.Get(context.TODO(), types.NamespacedName{Name: getRuntimeClass(&ctrl.singleton.Spec)}, runtimeClass)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	if errors.IsNotFound(err) {
+		// runtimeclass is removed already, cleanup the uninstall daemonsets
+		done := true
+		for _, daemonSet := range ctrl.states[idx].resources.Daemonsets {
+			gone, err := ctrl.deleteDaemonSet(daemonSet.Name + xcrUninstallSuffix)
+			if err != nil {
+				return false, err
+			}
+			done = done && gone
+		}
+		return done, nil
+	}
+
+	uninstalled := true
+	for _, daemonSet := range ctrl.states[idx].resources.Daemonsets {
+		done, err := ctrl.uninstallContainerRuntime(&daemonSet)
+		if err != nil {
+			return false, err
+		}
+		uninstalled = uninstalled && done
+	}
+	if !uninstalled {
+		logger.Info("Waiting for Xilinx container runtime to be uninstalled")
+		return false, nil
+	}
+
+	logger.Info("Deleting RuntimeClass", "Name", runtimeClass.Name)
+	err = ctrl.rec.Client.Delete(context.TODO(), runtimeClass)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	recordEvent(*ctrl, nil, corev1.EventTypeNormal, EventReasonTeardown,
+		fmt.Sprintf("Deleted RuntimeClass %s", runtimeClass.Name))
+	// uninstall daemonsets are removed in the next call
+	return false, nil
+}
+
+// deleteDaemonSet deletes a daemonset along with its pods, it returns true once the daemonset is gone
+func (ctrl *ControlContext) deleteDaemonSet(name string) (bool, error) {
+	ds := &appsv1.DaemonSet{}
+	err := ctrl.rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: ctrl.operatorNamespace, Name: name}, ds)
+	if errors.IsNotFound(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	if ds.DeletionTimestamp != nil {
+		// being deleted, wait for the pods to be removed
+		return false, nil
+	}
+
+	ctrl.rec.Log.Info("Deleting DaemonSet", "Name", name)
+	err = ctrl.rec.Client.Delete(context.TODO(), ds, client.PropagationPolicy(metav1.DeletePropagationForeground))
+	if errors.IsNotFound(err) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	recordEvent(*ctrl, nil, corev1.EventTypeNormal, EventReasonTeardown, fmt.Sprintf("Deleted DaemonSet %s", name))
+	return false, nil
+}
+
+// uninstallContainerRuntime deploys a daemonset reverting the runtime config on nodes,
+// it returns true once the uninstallation is completed on all the nodes
+func (ctrl *ControlContext) uninstallContainerRuntime(daemonSet *appsv1.DaemonSet) (bool, error) {
+	obj := daemonSet.DeepCopy()
+	obj.Namespace = ctrl.operatorNamespace
+	err := TransformContainerRuntimeUninstall(obj, &ctrl.singleton.Spec, *ctrl)
+	if err != nil {
+		return false, err
+	}
+
+	found := &appsv1.DaemonSet{}
+	err = ctrl.rec.Client.Get(context.TODO(), types.NamespacedName{Namespace: obj.Namespace, Name: obj.Name}, found)
+	if errors.IsNotFound(err) {
+		if err := controllerutil.SetControllerReference(ctrl.singleton, obj, ctrl.rec.Scheme); err != nil {
+			return false, err
+		}
+		ctrl.rec.Log.Info("Creating DaemonSet", "Name", obj.Name)
+		err = ctrl.rec.Client.Create(context.TODO(), obj)
+		if err != nil {
+			return false, err
+		}
+		recordEvent(*ctrl, nil, corev1.EventTypeNormal, EventReasonTeardown,
+			fmt.Sprintf("Created DaemonSet %s to uninstall Xilinx container runtime", obj.Name))
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	// the uninstall init container has completed on every node scheduled
+	status := found.Status
+	return status.ObservedGeneration >= found.Generation &&
+		status.NumberReady == status.DesiredNumberScheduled &&
+		status.NumberUnavailable == 0, nil
+}
+
+func (n ControlContext) isStateEnabled(stateName string) bool {
+	clusterPolicySpec := &n.singleton.Spec
+
+	switch stateName {
+	case "state-nfd":
+		return clusterPolicySpec.NFD.IsEnabled()
+	case "state-node-labeller":
+		return clusterPolicySpec.NodeLabeller.IsEnabled()
 	case "state-container-runtime":
 		return clusterPolicySpec.ContainerRuntime.IsEnabled()
 	case "state-device-plugin":
@@ -232,12 +802,18 @@ func (n ClusterPolicyController) isStateEnabled(stateName string) bool {
 	}
 }
 
-// kubernetesVersion fetches the Kubernetes API server version
-func kubernetesVersion() (string, error) {
-	cfg := config.GetConfigOrDie()
-	discoveryClient, err := discovery.NewDiscoveryClientForConfig(cfg)
-	if err != nil {
-		return "", fmt.Errorf("error building discovery client: %v", err)
+// kubernetesVersion fetches the Kubernetes API server version with the discovery client of the clientset,
+// or of the config if the clientset is not set
+func kubernetesVersion(clientset kubernetes.Interface) (string, error) {
+	var discoveryClient discovery.DiscoveryInterface
+	if clientset != nil {
+		discoveryClient = clientset.Discovery()
+	} else {
+		var err error
+		discoveryClient, err = discovery.NewDiscoveryClientForConfig(config.GetConfigOrDie())
+		if err != nil {
+			return "", fmt.Errorf("error building discovery client: %v", err)
+		}
 	}
 
 	info, err := discoveryClient.ServerVersion()
@@ -266,7 +842,7 @@ func getRuntimeString(node corev1.Node) (policyv1.Runtime, error) {
 // cluster and correctly set the value for clusterPolicyController.runtime
 // The default runtime is containerd -- if >=1 node is configured with containerd, set
 // clusterPolicyController.runtime = containerd
-func (ctrl *ClusterPolicyController) getRuntime() error {
+func (ctrl *ControlContext) getRuntime() error {
 
 	list := &corev1.NodeList{}
 	err := ctrl.rec.Client.List(context.TODO(), list)
@@ -0,0 +1,194 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package labeller
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	pciDevicesPath = "sys/bus/pci/devices"
+	modulesPath    = "sys/module"
+)
+
+// osReleasePaths are the os-release files in the order of precedence
+var osReleasePaths = []string{"etc/os-release", "usr/lib/os-release"}
+
+// xrtModules are the XRT drivers, whose version is the XRT version
+var xrtModules = []string{"xocl", "xclmgmt"}
+
+// Card is an FPGA card, which may have several PCI functions, eg. management and user functions of Alveo
+type Card struct {
+	// Address is the PCI address of the card without function, eg. 0000:3b:00
+	Address string
+	// Shell is the VBNV of the shell flashed on the card, empty if the XRT driver is not loaded
+	Shell string
+}
+
+// Model returns the model of the card from its shell, eg. u200 from xilinx_u200_gen3x16_xdma_base_2
+func (c Card) Model() string {
+	fields := strings.Split(c.Shell, "_")
+	if len(fields) < 2 {
+		return ""
+	}
+	return fields[1]
+}
+
+// hostPath returns the path on the host file system, resolving the absolute symlink
+// on the host against the root, eg. /etc/os-release -> ../usr/lib/os-release
+func (l *Labeller) hostPath(path string) string {
+	full := filepath.Join(l.Root, path)
+	target, err := os.Readlink(full)
+	if err != nil || !filepath.IsAbs(target) {
+		return full
+	}
+	return filepath.Join(l.Root, target)
+}
+
+// readOSRelease returns the key-value pairs in os-release of the host
+func (l *Labeller) readOSRelease() (map[string]string, error) {
+	var data []byte
+	var err error
+	for _, path := range osReleasePaths {
+		data, err = os.ReadFile(l.hostPath(path))
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("unable to read os-release: %v", err)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("os-release not found in %s", l.Root)
+	}
+
+	return parseKeyValues(data)
+}
+
+// parseKeyValues parses the lines of KEY=VALUE in the format of os-release, skipping the comments
+func parseKeyValues(data []byte) (map[string]string, error) {
+	values := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		values[kv[0]] = strings.Trim(kv[1], `"'`)
+	}
+	return values, scanner.Err()
+}
+
+// scanCards returns the FPGA cards found in sysfs, sorted by PCI address
+func (l *Labeller) scanCards() ([]Card, error) {
+	devicesDir := filepath.Join(l.Root, pciDevicesPath)
+	entries, err := os.ReadDir(devicesDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list PCI devices: %v", err)
+	}
+
+	cards := map[string]*Card{}
+	for _, entry := range entries {
+		dir := filepath.Join(devicesDir, entry.Name())
+		vendor := readPCIID(filepath.Join(dir, "vendor"))
+		class := readPCIID(filepath.Join(dir, "class"))
+		device := readPCIID(filepath.Join(dir, "device"))
+		if !l.isFPGA(vendor, class, device) {
+			continue
+		}
+
+		// functions of a card share the address without function, eg. 0000:3b:00.0 and 0000:3b:00.1
+		address := entry.Name()
+		if i := strings.LastIndex(address, "."); i >= 0 {
+			address = address[:i]
+		}
+		card, ok := cards[address]
+		if !ok {
+			card = &Card{Address: address}
+			cards[address] = card
+		}
+		if card.Shell == "" {
+			card.Shell = readShell(dir)
+		}
+	}
+
+	list := []Card{}
+	for _, card := range cards {
+		list = append(list, *card)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Address < list[j].Address })
+	return list, nil
+}
+
+// isFPGA returns true if the PCI device matches any of the FPGA devices
+func (l *Labeller) isFPGA(vendor, class, device string) bool {
+	for _, d := range l.PCIDevices {
+		if d.matches(vendor, class, device) {
+			return true
+		}
+	}
+	return false
+}
+
+// readPCIID reads a PCI ID from sysfs, eg. 0x10ee for vendor or 0x120000 for class.
+// The class is truncated to the class and subclass, as labeled by NFD, eg. 1200
+func readPCIID(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	id := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(string(data))), "0x")
+	if len(id) > 4 {
+		id = id[:4]
+	}
+	return id
+}
+
+// readShell reads the VBNV exposed by the rom subdevice of the XRT driver, eg. rom.u.1024/VBNV
+func readShell(dir string) string {
+	matches, _ := filepath.Glob(filepath.Join(dir, "rom.*", "VBNV"))
+	for _, match := range matches {
+		data, err := os.ReadFile(match)
+		if err == nil && strings.TrimSpace(string(data)) != "" {
+			return strings.TrimSpace(string(data))
+		}
+	}
+	return ""
+}
+
+// readXRTVersion reads the version of the XRT driver loaded, empty if no driver is loaded
+func (l *Labeller) readXRTVersion() string {
+	for _, module := range xrtModules {
+		data, err := os.ReadFile(filepath.Join(l.Root, modulesPath, module, "version"))
+		if err == nil {
+			return strings.TrimSpace(string(data))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,219 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package labeller detects the OS and the FPGA cards of a node from the host file system,
+// and publishes them as node labels, so FPGA-Operator works without Node Feature Discovery.
+// It also publishes the setup recorded by host-setup on the node as node annotations
+package labeller
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// LabelPrefix is the prefix of all the labels published by the labeller
+	LabelPrefix = "fpga.xilinx.com/"
+	// LabelOSReleaseID is the ID in os-release, eg. ubuntu
+	LabelOSReleaseID = LabelPrefix + "os-release.ID"
+	// LabelOSVersionID is the VERSION_ID in os-release, eg. 20.04
+	LabelOSVersionID = LabelPrefix + "os-release.VERSION_ID"
+	// LabelOSMajorVersion is the major version of VERSION_ID in os-release, eg. 20
+	LabelOSMajorVersion = LabelPrefix + "os-release.VERSION_ID.major"
+	// LabelFPGAPresent is set to true if there are FPGA cards on the node
+	LabelFPGAPresent = LabelPrefix + "fpga.present"
+	// LabelFPGACount is the number of FPGA cards on the node
+	LabelFPGACount = LabelPrefix + "fpga.count"
+	// LabelFPGAModel is the model of FPGA cards on the node, eg. u200
+	LabelFPGAModel = LabelPrefix + "fpga.model"
+	// LabelFPGAShell is the shell (VBNV) flashed on FPGA cards, eg. xilinx_u200_gen3x16_xdma_base_2
+	LabelFPGAShell = LabelPrefix + "fpga.shell"
+	// LabelXRTVersion is the version of XRT driver loaded on the node
+	LabelXRTVersion = LabelPrefix + "xrt.version"
+
+	// mixedValue is used if a label has several values too long to be joined
+	mixedValue = "mixed"
+	// maxLabelValueLength is the max length of a label value
+	maxLabelValueLength = 63
+)
+
+var invalidLabelValueChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// Labeller detects the labels of a node from the host file system mounted at Root
+type Labeller struct {
+	// Root is the root directory of the host file system, eg. /host
+	Root string
+	// PCIDevices identify the FPGA cards, a PCI device matching any of them is an FPGA
+	PCIDevices []PCIDevice
+}
+
+// New returns a labeller for the host file system mounted at root
+func New(root string, devices []PCIDevice) *Labeller {
+	return &Labeller{Root: root, PCIDevices: devices}
+}
+
+// Labels returns the labels of the node
+func (l *Labeller) Labels() (map[string]string, error) {
+	labels := map[string]string{}
+
+	osRelease, err := l.readOSRelease()
+	if err != nil {
+		return nil, err
+	}
+	if id := osRelease["ID"]; id != "" {
+		labels[LabelOSReleaseID] = labelValue(id)
+	}
+	if version := osRelease["VERSION_ID"]; version != "" {
+		labels[LabelOSVersionID] = labelValue(version)
+		labels[LabelOSMajorVersion] = labelValue(strings.SplitN(version, ".", 2)[0])
+	}
+
+	cards, err := l.scanCards()
+	if err != nil {
+		return nil, err
+	}
+	if len(cards) == 0 {
+		return labels, nil
+	}
+	labels[LabelFPGAPresent] = "true"
+	labels[LabelFPGACount] = strconv.Itoa(len(cards))
+
+	models := []string{}
+	shells := []string{}
+	for _, card := range cards {
+		if card.Shell == "" {
+			continue
+		}
+		shells = append(shells, card.Shell)
+		if model := card.Model(); model != "" {
+			models = append(models, model)
+		}
+	}
+	if value := joinLabelValues(models); value != "" {
+		labels[LabelFPGAModel] = value
+	}
+	if value := joinLabelValues(shells); value != "" {
+		labels[LabelFPGAShell] = value
+	}
+
+	if version := l.readXRTVersion(); version != "" {
+		labels[LabelXRTVersion] = labelValue(version)
+	}
+	return labels, nil
+}
+
+// labelValue converts s to a valid label value
+func labelValue(s string) string {
+	value := invalidLabelValueChars.ReplaceAllString(strings.TrimSpace(s), "_")
+	if len(value) > maxLabelValueLength {
+		value = value[:maxLabelValueLength]
+	}
+	return strings.Trim(value, "_.-")
+}
+
+// joinLabelValues joins the distinct values sorted, or returns "mixed" if the result is too long for a label value
+func joinLabelValues(values []string) string {
+	seen := map[string]bool{}
+	distinct := []string{}
+	for _, value := range values {
+		value = labelValue(value)
+		if value != "" && !seen[value] {
+			seen[value] = true
+			distinct = append(distinct, value)
+		}
+	}
+	sort.Strings(distinct)
+
+	joined := strings.Join(distinct, ".")
+	if len(joined) > maxLabelValueLength {
+		return mixedValue
+	}
+	return joined
+}
+
+// PCIDevice matches PCI devices by vendor, class and device IDs, the empty ones match any device
+type PCIDevice struct {
+	// Vendor is the PCI vendor ID, eg. 10ee
+	Vendor string
+	// Class is the PCI device class, eg. 1200
+	Class string
+	// Device is the PCI device ID, eg. 5000
+	Device string
+}
+
+// ParsePCIDevice parses a PCI device in the format of <vendor>[:<class>[:<device>]], eg. 10ee:1200
+func ParsePCIDevice(s string) (PCIDevice, error) {
+	fields := strings.Split(strings.ToLower(s), ":")
+	if len(fields) > 3 || fields[0] == "" {
+		return PCIDevice{}, fmt.Errorf("invalid PCI device %q, expected <vendor>[:<class>[:<device>]]", s)
+	}
+	for _, field := range fields {
+		if field == "" {
+			continue
+		}
+		if _, err := strconv.ParseUint(field, 16, 16); err != nil || len(field) != 4 {
+			return PCIDevice{}, fmt.Errorf("invalid PCI ID %q in %q, expected 4 hex digits", field, s)
+		}
+	}
+
+	device := PCIDevice{Vendor: fields[0]}
+	if len(fields) > 1 {
+		device.Class = fields[1]
+	}
+	if len(fields) > 2 {
+		device.Device = fields[2]
+	}
+	return device, nil
+}
+
+// PCIDevices is a flag accepting PCI devices separated by comma, or repeated
+type PCIDevices []PCIDevice
+
+// String returns the PCI devices separated by comma
+func (d *PCIDevices) String() string {
+	devices := []string{}
+	for _, device := range *d {
+		devices = append(devices, device.String())
+	}
+	return strings.Join(devices, ",")
+}
+
+// Set appends the PCI devices separated by comma
+func (d *PCIDevices) Set(value string) error {
+	for _, s := range strings.Split(value, ",") {
+		device, err := ParsePCIDevice(strings.TrimSpace(s))
+		if err != nil {
+			return err
+		}
+		*d = append(*d, device)
+	}
+	return nil
+}
+
+// String returns the PCI device in the format of <vendor>:<class>:<device>
+func (d PCIDevice) String() string {
+	return strings.TrimRight(d.Vendor+":"+d.Class+":"+d.Device, ":")
+}
+
+// matches returns true if the vendor, class and device IDs match the PCI device
+func (d PCIDevice) matches(vendor, class, device string) bool {
+	return d.Vendor == vendor &&
+		(d.Class == "" || d.Class == class) &&
+		(d.Device == "" || d.Device == device)
+}
@@ -0,0 +1,252 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package labeller
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var defaultPCIDevices = []PCIDevice{{Vendor: "10ee", Class: "1200"}}
+
+// fakeHost is a fake host file system for the labeller
+type fakeHost struct {
+	t    *testing.T
+	root string
+}
+
+func newFakeHost(t *testing.T) *fakeHost {
+	return &fakeHost{t: t, root: t.TempDir()}
+}
+
+func (h *fakeHost) writeFile(path string, content string) {
+	full := filepath.Join(h.root, path)
+	require.NoError(h.t, os.MkdirAll(filepath.Dir(full), 0755))
+	require.NoError(h.t, os.WriteFile(full, []byte(content+"\n"), 0644))
+}
+
+// addPCIDevice adds a PCI device to sysfs, with the VBNV of the XRT driver if shell is not empty
+func (h *fakeHost) addPCIDevice(address, vendor, class, device, shell string) {
+	dir := filepath.Join(pciDevicesPath, address)
+	h.writeFile(filepath.Join(dir, "vendor"), "0x"+vendor)
+	h.writeFile(filepath.Join(dir, "class"), "0x"+class)
+	h.writeFile(filepath.Join(dir, "device"), "0x"+device)
+	if shell != "" {
+		h.writeFile(filepath.Join(dir, "rom.u.1024", "VBNV"), shell)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	testCases := []struct {
+		description string
+		setup       func(h *fakeHost)
+		devices     []PCIDevice
+		expected    map[string]string
+	}{
+		{
+			"no FPGA",
+			func(h *fakeHost) {
+				h.writeFile("etc/os-release", "NAME=\"Ubuntu\"\nID=ubuntu\nVERSION_ID=\"20.04\"")
+				h.addPCIDevice("0000:00:00.0", "8086", "060000", "2020", "")
+			},
+			defaultPCIDevices,
+			map[string]string{
+				LabelOSReleaseID:    "ubuntu",
+				LabelOSVersionID:    "20.04",
+				LabelOSMajorVersion: "20",
+			},
+		},
+		{
+			"alveo cards with XRT",
+			func(h *fakeHost) {
+				h.writeFile("etc/os-release", "ID=\"centos\"\nVERSION_ID=\"7\"")
+				h.writeFile("sys/module/xocl/version", "2.15.225")
+				// management and user functions of the same card
+				h.addPCIDevice("0000:3b:00.0", "10ee", "120000", "5000", "xilinx_u200_gen3x16_xdma_base_2")
+				h.addPCIDevice("0000:3b:00.1", "10ee", "120000", "5001", "xilinx_u200_gen3x16_xdma_base_2")
+				h.addPCIDevice("0000:af:00.1", "10ee", "120000", "5021", "xilinx_u50_gen3x16_xdma_5_202210_1")
+				h.addPCIDevice("0000:00:00.0", "8086", "060000", "2020", "")
+			},
+			defaultPCIDevices,
+			map[string]string{
+				LabelOSReleaseID:    "centos",
+				LabelOSVersionID:    "7",
+				LabelOSMajorVersion: "7",
+				LabelFPGAPresent:    "true",
+				LabelFPGACount:      "2",
+				LabelFPGAModel:      "u200.u50",
+				LabelFPGAShell:      "mixed",
+				LabelXRTVersion:     "2.15.225",
+			},
+		},
+		{
+			"cards without XRT",
+			func(h *fakeHost) {
+				h.writeFile("usr/lib/os-release", "ID=ubuntu\nVERSION_ID=\"22.04\"")
+				require.NoError(t, os.MkdirAll(filepath.Join(h.root, "etc"), 0755))
+				require.NoError(t, os.Symlink("/usr/lib/os-release", filepath.Join(h.root, "etc/os-release")))
+				h.addPCIDevice("0000:3b:00.0", "10ee", "120000", "5000", "")
+				h.addPCIDevice("0000:5e:00.0", "10ee", "0b4000", "5048", "")
+			},
+			[]PCIDevice{{Vendor: "10ee", Class: "1200"}, {Vendor: "10ee", Class: "0b40"}},
+			map[string]string{
+				LabelOSReleaseID:    "ubuntu",
+				LabelOSVersionID:    "22.04",
+				LabelOSMajorVersion: "22",
+				LabelFPGAPresent:    "true",
+				LabelFPGACount:      "2",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			h := newFakeHost(t)
+			tc.setup(h)
+
+			labels, err := New(h.root, tc.devices).Labels()
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, labels)
+		})
+	}
+}
+
+func TestLabelsWithoutOSRelease(t *testing.T) {
+	h := newFakeHost(t)
+	h.addPCIDevice("0000:3b:00.0", "10ee", "120000", "5000", "")
+
+	_, err := New(h.root, defaultPCIDevices).Labels()
+	require.Error(t, err, "missing os-release is not reported")
+}
+
+func TestParsePCIDevice(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected PCIDevice
+		valid    bool
+	}{
+		{"10ee", PCIDevice{Vendor: "10ee"}, true},
+		{"10EE:1200", PCIDevice{Vendor: "10ee", Class: "1200"}, true},
+		{"1022:1200:5700", PCIDevice{Vendor: "1022", Class: "1200", Device: "5700"}, true},
+		{"10ee::5000", PCIDevice{Vendor: "10ee", Device: "5000"}, true},
+		{":1200", PCIDevice{}, false},
+		{"10ee:12", PCIDevice{}, false},
+		{"10ee:1200:5000:1", PCIDevice{}, false},
+		{"xilinx", PCIDevice{}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			device, err := ParsePCIDevice(tc.value)
+			if !tc.valid {
+				require.Error(t, err, "invalid PCI device is accepted")
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, device)
+		})
+	}
+}
+
+func TestUpdateNode(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "fpga-node",
+			Labels: map[string]string{
+				"kubernetes.io/hostname": "fpga-node",
+				LabelOSReleaseID:         "ubuntu",
+				LabelXRTVersion:          "2.14.354",
+			},
+		},
+	}
+	clientset := fake.NewSimpleClientset(node)
+	labels := map[string]string{
+		LabelOSReleaseID: "ubuntu",
+		LabelFPGACount:   "1",
+	}
+
+	updated, err := UpdateNode(context.TODO(), clientset, node.Name, labels)
+	require.NoError(t, err)
+	require.True(t, updated, "node is not updated")
+
+	found, err := clientset.CoreV1().Nodes().Get(context.TODO(), node.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"kubernetes.io/hostname": "fpga-node",
+		LabelOSReleaseID:         "ubuntu",
+		LabelFPGACount:           "1",
+	}, found.Labels, "stale labels are not removed")
+
+	updated, err = UpdateNode(context.TODO(), clientset, node.Name, labels)
+	require.NoError(t, err)
+	require.False(t, updated, "node is updated with the same labels")
+}
+
+func TestSetupAnnotations(t *testing.T) {
+	h := newFakeHost(t)
+	h.addPCIDevice("0000:3b:00.0", "10ee", "120000", "5000", "xilinx_u200_gen3x16_xdma_base_2")
+	h.addPCIDevice("0000:af:00.0", "10ee", "120000", "5020", "")
+
+	// nothing is recorded before the first setup
+	annotations, err := New(h.root, defaultPCIDevices).SetupAnnotations()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{AnnotationShells: "0000:3b:00=xilinx_u200_gen3x16_xdma_base_2"}, annotations)
+
+	h.writeFile(SetupStatusPath, "XRT_VERSION=2023.1\nXRM_INSTALLED=true\nRESULT=Succeeded\nTIME=2023-03-01T10:00:00Z")
+	annotations, err = New(h.root, defaultPCIDevices).SetupAnnotations()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		AnnotationXRTVersion:      "2023.1",
+		AnnotationXRMInstalled:    "true",
+		AnnotationLastSetupResult: SetupSucceeded,
+		AnnotationLastSetupTime:   "2023-03-01T10:00:00Z",
+		AnnotationShells:          "0000:3b:00=xilinx_u200_gen3x16_xdma_base_2",
+	}, annotations)
+	require.Equal(t, map[string]string{"0000:3b:00": "xilinx_u200_gen3x16_xdma_base_2"}, ParseShells(annotations[AnnotationShells]))
+}
+
+func TestUpdateNodeAnnotations(t *testing.T) {
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "fpga-node",
+			Annotations: map[string]string{
+				"node.alpha.kubernetes.io/ttl": "0",
+				AnnotationShells:               "0000:3b:00=xilinx_u200_gen3x16_xdma_base_2",
+			},
+		},
+	}
+	clientset := fake.NewSimpleClientset(node)
+	annotations := map[string]string{AnnotationXRTVersion: "2023.1"}
+
+	updated, err := UpdateNodeAnnotations(context.TODO(), clientset, node.Name, annotations)
+	require.NoError(t, err)
+	require.True(t, updated, "node is not updated")
+
+	found, err := clientset.CoreV1().Nodes().Get(context.TODO(), node.Name, metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"node.alpha.kubernetes.io/ttl": "0",
+		AnnotationXRTVersion:           "2023.1",
+	}, found.Annotations, "stale annotations are not removed")
+}
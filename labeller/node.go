@@ -0,0 +1,81 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package labeller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// UpdateNode replaces the labels with LabelPrefix of the node with the given labels.
+// It returns true if the node is patched, or false if the labels are up to date
+func UpdateNode(ctx context.Context, clientset kubernetes.Interface, nodeName string, labels map[string]string) (bool, error) {
+	return updateNodeMetadata(ctx, clientset, nodeName, "labels", LabelPrefix, labels)
+}
+
+// UpdateNodeAnnotations replaces the annotations with SetupAnnotationPrefix of the node with the given annotations.
+// It returns true if the node is patched, or false if the annotations are up to date
+func UpdateNodeAnnotations(ctx context.Context, clientset kubernetes.Interface, nodeName string, annotations map[string]string) (bool, error) {
+	return updateNodeMetadata(ctx, clientset, nodeName, "annotations", SetupAnnotationPrefix, annotations)
+}
+
+// updateNodeMetadata replaces the labels or annotations with the prefix of the node with the given values
+func updateNodeMetadata(ctx context.Context, clientset kubernetes.Interface, nodeName, field, prefix string,
+	values map[string]string) (bool, error) {
+	node, err := clientset.CoreV1().Nodes().Get(ctx, nodeName, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("unable to get node %s: %v", nodeName, err)
+	}
+	current := node.Labels
+	if field == "annotations" {
+		current = node.Annotations
+	}
+
+	// a nil value removes the label or annotation in a merge patch
+	changes := map[string]interface{}{}
+	for key := range current {
+		if _, ok := values[key]; !ok && strings.HasPrefix(key, prefix) {
+			changes[key] = nil
+		}
+	}
+	for key, value := range values {
+		if currentValue, ok := current[key]; !ok || currentValue != value {
+			changes[key] = value
+		}
+	}
+	if len(changes) == 0 {
+		return false, nil
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{field: changes},
+	})
+	if err != nil {
+		return false, err
+	}
+	_, err = clientset.CoreV1().Nodes().Patch(ctx, nodeName, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return false, fmt.Errorf("unable to patch %s of node %s: %v", field, nodeName, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright (C) 2023, Advanced Micro Devices, Inc. - All rights reserved
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package labeller
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// SetupAnnotationPrefix is the prefix of all the node annotations published by the host-setup reporter
+	SetupAnnotationPrefix = "host-setup.fpga.xilinx.com/"
+	// AnnotationXRTVersion is the version of XRT installed by host-setup, eg. 2023.1
+	AnnotationXRTVersion = SetupAnnotationPrefix + "xrt-version"
+	// AnnotationXRMInstalled is true if XRM is installed by host-setup
+	AnnotationXRMInstalled = SetupAnnotationPrefix + "xrm-installed"
+	// AnnotationShells are the shells flashed on FPGA cards, eg. 0000:3b:00=xilinx_u200_gen3x16_xdma_base_2,...
+	AnnotationShells = SetupAnnotationPrefix + "shells"
+	// AnnotationLastSetupTime is the time the last setup completed in RFC 3339
+	AnnotationLastSetupTime = SetupAnnotationPrefix + "last-setup-time"
+	// AnnotationLastSetupResult is the result of the last setup, Succeeded or Failed
+	AnnotationLastSetupResult = SetupAnnotationPrefix + "last-setup-result"
+
+	// SetupStatusPath is the file on the host recording the setup, in the format of os-release:
+	// XRT_VERSION and XRM_INSTALLED once XRT is installed, RESULT and TIME of the last setup
+	SetupStatusPath = "var/lib/fpga-operator/host-setup/status"
+	// SetupSucceeded is the result of a setup which installed XRT and flashed the cards
+	SetupSucceeded = "Succeeded"
+	// SetupFailed is the result of a setup which failed to install XRT or to flash the cards
+	SetupFailed = "Failed"
+)
+
+// setupStatusAnnotations maps the keys of the setup status file to the annotations
+var setupStatusAnnotations = map[string]string{
+	"XRT_VERSION":   AnnotationXRTVersion,
+	"XRM_INSTALLED": AnnotationXRMInstalled,
+	"RESULT":        AnnotationLastSetupResult,
+	"TIME":          AnnotationLastSetupTime,
+}
+
+// SetupAnnotations returns the annotations of the setup recorded by host-setup on the node,
+// and the shells flashed on the FPGA cards found in sysfs
+func (l *Labeller) SetupAnnotations() (map[string]string, error) {
+	annotations := map[string]string{}
+	data, err := os.ReadFile(filepath.Join(l.Root, SetupStatusPath))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("unable to read setup status: %v", err)
+	}
+	if err == nil {
+		status, err := parseKeyValues(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse setup status: %v", err)
+		}
+		for key, annotation := range setupStatusAnnotations {
+			if value := status[key]; value != "" {
+				annotations[annotation] = value
+			}
+		}
+	}
+
+	cards, err := l.scanCards()
+	if err != nil {
+		return nil, err
+	}
+	shells := []string{}
+	for _, card := range cards {
+		if card.Shell != "" {
+			shells = append(shells, card.Address+"="+card.Shell)
+		}
+	}
+	if len(shells) > 0 {
+		annotations[AnnotationShells] = strings.Join(shells, ",")
+	}
+	return annotations, nil
+}
+
+// ParseShells parses the shells annotation into the shells keyed by the PCI address of the card
+func ParseShells(value string) map[string]string {
+	shells := map[string]string{}
+	for _, card := range strings.Split(value, ",") {
+		kv := strings.SplitN(strings.TrimSpace(card), "=", 2)
+		if len(kv) == 2 && kv[0] != "" {
+			shells[kv[0]] = kv[1]
+		}
+	}
+	return shells
+}
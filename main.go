@@ -17,8 +17,10 @@ limitations under the License.
 package main
 
 import (
+	"embed"
 	"flag"
 	"fmt"
+	"io/fs"
 	"os"
 	"strings"
 
@@ -31,15 +33,25 @@ import (
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 
 	policyv1 "github.com/xilinx/fpga-operator/api/v1"
+	policyv2 "github.com/xilinx/fpga-operator/api/v2"
 	"github.com/xilinx/fpga-operator/controllers"
 	//+kubebuilder:scaffold:imports
 )
 
+//go:embed hostSetup/conf/spec.txt
+var hostSetupSpec []byte
+
+// assets are the asset files of the states deployed by the operator
+//
+//go:embed assets
+var assets embed.FS
+
 var (
 	scheme          = runtime.NewScheme()
 	timestampFormat = "2006-01-02 15:04:05"
@@ -81,6 +93,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(policyv1.AddToScheme(scheme))
+	utilruntime.Must(policyv2.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -88,11 +101,14 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var assetsDir string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&assetsDir, "assets-dir", "",
+		"The directory of the asset files overriding the embedded ones, in a subdirectory per state, eg. state-device-plugin.")
 	flag.Parse()
 
 	// create loggers
@@ -100,6 +116,18 @@ func main() {
 	setupLog := logger.WithName("setup")
 	ctrl.SetLogger(setupLog)
 
+	if assetsDir != "" {
+		if info, err := os.Stat(assetsDir); err != nil || !info.IsDir() {
+			setupLog.Error(err, "invalid assets directory", "dir", assetsDir)
+			os.Exit(1)
+		}
+	}
+	embeddedAssets, err := fs.Sub(assets, "assets")
+	if err != nil {
+		setupLog.Error(err, "unable to load embedded assets")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -124,14 +152,38 @@ func main() {
 		os.Exit(1)
 	}
 
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create Kubernetes client")
+		os.Exit(1)
+	}
+
 	if err = (&controllers.ClusterPolicyReconciler{
-		Client: mgr.GetClient(),
-		Log:    logger.WithName("controllers").WithName("ClusterPolicy"),
-		Scheme: mgr.GetScheme(),
+		Client:    mgr.GetClient(),
+		Log:       logger.WithName("controllers").WithName("ClusterPolicy"),
+		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorderFor("fpga-operator"),
+		Assets:    embeddedAssets,
+		AssetsDir: assetsDir,
+		Clientset: clientset,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ClusterPolicy")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		platforms, err := policyv1.ParseHostSetupPlatforms(hostSetupSpec)
+		if err != nil {
+			setupLog.Error(err, "unable to parse host setup platforms")
+			os.Exit(1)
+		}
+		if err = (&policyv1.ClusterPolicyValidator{
+			Client:    mgr.GetAPIReader(),
+			Platforms: platforms,
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "ClusterPolicy")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {